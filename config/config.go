@@ -0,0 +1,656 @@
+package config
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// Config application configuration
+type Config struct {
+	Server    ServerConfig    `yaml:"server" toml:"server"`
+	Database  DatabaseConfig  `yaml:"database" toml:"database"`
+	Auth      AuthConfig      `yaml:"auth" toml:"auth"`
+	Mail      MailConfig      `yaml:"mail" toml:"mail"`
+	RateLimit RateLimitConfig `yaml:"rate_limit" toml:"rate_limit"`
+	Cleanup   CleanupConfig   `yaml:"cleanup" toml:"cleanup"`
+}
+
+// ServerConfig HTTP server configuration
+type ServerConfig struct {
+	Address         string   `yaml:"address" toml:"address" env:"APP_SERVER_ADDRESS"`                            // Listen address, e.g. ":8080"
+	Mode            string   `yaml:"mode" toml:"mode" env:"APP_SERVER_MODE"`                                     // Gin mode: debug, release, test
+	ShutdownTimeout Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout" env:"APP_SERVER_SHUTDOWN_TIMEOUT"` // Time to finish in-flight requests on SIGINT or SIGTERM
+}
+
+// DatabaseConfig database configuration
+type DatabaseConfig struct {
+	Driver          string       `yaml:"driver" toml:"driver" env:"APP_DATABASE_DRIVER"`                                  // Database driver: sqlite, postgres, mysql
+	DSN             string       `yaml:"dsn" toml:"dsn" env:"APP_DATABASE_DSN"`                                           // Data source name
+	MaxOpenConns    int          `yaml:"max_open_conns" toml:"max_open_conns" env:"APP_DATABASE_MAX_OPEN_CONNS"`          // Maximum open connections, 0 means unlimited
+	MaxIdleConns    int          `yaml:"max_idle_conns" toml:"max_idle_conns" env:"APP_DATABASE_MAX_IDLE_CONNS"`          // Maximum idle connections
+	ConnMaxLifetime Duration     `yaml:"conn_max_lifetime" toml:"conn_max_lifetime" env:"APP_DATABASE_CONN_MAX_LIFETIME"` // Maximum connection lifetime, 0 means unlimited
+	AutoMigrate     bool         `yaml:"auto_migrate" toml:"auto_migrate" env:"APP_DATABASE_AUTO_MIGRATE"`                // Apply pending migrations on server startup
+	SQLite          SQLiteConfig `yaml:"sqlite" toml:"sqlite"`                                                            // SQLite specific options
+}
+
+// SQLiteConfig SQLite specific configuration, only applies to file-backed databases
+type SQLiteConfig struct {
+	JournalMode string   `yaml:"journal_mode" toml:"journal_mode" env:"APP_DATABASE_SQLITE_JOURNAL_MODE"` // Journal mode, e.g. WAL, DELETE
+	BusyTimeout Duration `yaml:"busy_timeout" toml:"busy_timeout" env:"APP_DATABASE_SQLITE_BUSY_TIMEOUT"` // How long to wait for a locked database
+	ForeignKeys bool     `yaml:"foreign_keys" toml:"foreign_keys" env:"APP_DATABASE_SQLITE_FOREIGN_KEYS"` // Enforce foreign key constraints
+}
+
+// AuthConfig authentication configuration
+type AuthConfig struct {
+	SessionTTL            Duration              `yaml:"session_ttl" toml:"session_ttl" env:"APP_AUTH_SESSION_TTL"`                                     // Session lifetime, extended on every use and also the lifetime of its refresh tokens
+	IdleTimeout           Duration              `yaml:"idle_timeout" toml:"idle_timeout" env:"APP_AUTH_IDLE_TIMEOUT"`                                  // Session ends after this long without use, 0 disables
+	MaxSessionLifetime    Duration              `yaml:"max_session_lifetime" toml:"max_session_lifetime" env:"APP_AUTH_MAX_SESSION_LIFETIME"`          // Absolute session lifetime, use never extends a session past it
+	AccessTokenTTL        Duration              `yaml:"access_token_ttl" toml:"access_token_ttl" env:"APP_AUTH_ACCESS_TOKEN_TTL"`                      // Access token lifetime
+	ResetTokenTTL         Duration              `yaml:"reset_token_ttl" toml:"reset_token_ttl" env:"APP_AUTH_RESET_TOKEN_TTL"`                         // Password reset token lifetime
+	TokenFormat           string                `yaml:"token_format" toml:"token_format" env:"APP_AUTH_TOKEN_FORMAT"`                                  // Access token format: opaque, jwt
+	VerificationTokenTTL  Duration              `yaml:"verification_token_ttl" toml:"verification_token_ttl" env:"APP_AUTH_VERIFICATION_TOKEN_TTL"`    // Email verification token lifetime
+	VerifyEmailURL        string                `yaml:"verify_email_url" toml:"verify_email_url" env:"APP_AUTH_VERIFY_EMAIL_URL"`                      // Frontend page receiving the verification token as "token" query parameter
+	AllowUnverifiedSignin bool                  `yaml:"allow_unverified_signin" toml:"allow_unverified_signin" env:"APP_AUTH_ALLOW_UNVERIFIED_SIGNIN"` // Whether users may sign in before verifying their email
+	ResetPasswordURL      string                `yaml:"reset_password_url" toml:"reset_password_url" env:"APP_AUTH_RESET_PASSWORD_URL"`                // Frontend page receiving the reset token as "token" query parameter
+	RecoveryCodeCount     int                   `yaml:"recovery_code_count" toml:"recovery_code_count" env:"APP_AUTH_RECOVERY_CODE_COUNT"`             // Number of account recovery codes generated per batch
+	RememberMe            RememberMeConfig      `yaml:"remember_me" toml:"remember_me"`                                                                // Longer session policy selected at signin
+	JWT                   JWTConfig             `yaml:"jwt" toml:"jwt"`                                                                                // JWT access token options
+	Lockout               LockoutConfig         `yaml:"lockout" toml:"lockout"`                                                                        // Brute-force protection of signin
+	PasswordPolicy        PasswordPolicyConfig  `yaml:"password_policy" toml:"password_policy"`                                                        // Rules applied to new passwords
+	PasswordHashing       PasswordHashingConfig `yaml:"password_hashing" toml:"password_hashing"`                                                      // Algorithm and cost of stored password hashes
+	MFA                   MFAConfig             `yaml:"mfa" toml:"mfa"`                                                                                // Two-factor authentication
+	WebAuthn              WebAuthnConfig        `yaml:"webauthn" toml:"webauthn"`                                                                      // Passkey login
+	LoginLink             LoginLinkConfig       `yaml:"login_link" toml:"login_link"`                                                                  // Passwordless login by email
+	OAuth                 OAuthConfig           `yaml:"oauth" toml:"oauth"`                                                                            // Social login with OAuth2 and OpenID Connect providers
+}
+
+// JWTConfig signed JWT access token configuration
+type JWTConfig struct {
+	Algorithm        string   `yaml:"algorithm" toml:"algorithm" env:"APP_AUTH_JWT_ALGORITHM"`                         // Signing algorithm: HS256, RS256, EdDSA
+	Secret           string   `yaml:"secret" toml:"secret" env:"APP_AUTH_JWT_SECRET"`                                  // HMAC secret for HS256
+	PrivateKeyFile   string   `yaml:"private_key_file" toml:"private_key_file" env:"APP_AUTH_JWT_PRIVATE_KEY_FILE"`    // PEM private key for RS256 and EdDSA
+	Issuer           string   `yaml:"issuer" toml:"issuer" env:"APP_AUTH_JWT_ISSUER"`                                  // Issuer (iss) claim
+	Audience         string   `yaml:"audience" toml:"audience" env:"APP_AUTH_JWT_AUDIENCE"`                            // Audience (aud) claim
+	RevocationCheck  bool     `yaml:"revocation_check" toml:"revocation_check" env:"APP_AUTH_JWT_REVOCATION_CHECK"`    // Check session status in database on every request
+	KeyDir           string   `yaml:"key_dir" toml:"key_dir" env:"APP_AUTH_JWT_KEY_DIR"`                               // Directory persisting rotated RS256/EdDSA keys
+	RotationInterval Duration `yaml:"rotation_interval" toml:"rotation_interval" env:"APP_AUTH_JWT_ROTATION_INTERVAL"` // How often to rotate signing key, 0 disables rotation
+	RetirementPeriod Duration `yaml:"retirement_period" toml:"retirement_period" env:"APP_AUTH_JWT_RETIREMENT_PERIOD"` // How long a replaced key is still accepted and published
+	AssertionTTL     Duration `yaml:"assertion_ttl" toml:"assertion_ttl" env:"APP_AUTH_JWT_ASSERTION_TTL"`             // Lifetime of signed identity assertions
+}
+
+// KeysConfigured checks if signing keys are configured
+func (c JWTConfig) KeysConfigured() bool {
+	return c.Secret != "" || c.PrivateKeyFile != "" || c.KeyDir != ""
+}
+
+// LockoutConfig failed login throttling and lockout configuration
+type LockoutConfig struct {
+	Enabled         bool     `yaml:"enabled" toml:"enabled" env:"APP_AUTH_LOCKOUT_ENABLED"`                               // Enable brute-force protection
+	FreeAttempts    int      `yaml:"free_attempts" toml:"free_attempts" env:"APP_AUTH_LOCKOUT_FREE_ATTEMPTS"`             // Failures allowed before delays start
+	BaseDelay       Duration `yaml:"base_delay" toml:"base_delay" env:"APP_AUTH_LOCKOUT_BASE_DELAY"`                      // First delay, doubled with every further failure
+	MaxDelay        Duration `yaml:"max_delay" toml:"max_delay" env:"APP_AUTH_LOCKOUT_MAX_DELAY"`                         // Upper bound of progressive delay
+	MaxUserAttempts int      `yaml:"max_user_attempts" toml:"max_user_attempts" env:"APP_AUTH_LOCKOUT_MAX_USER_ATTEMPTS"` // Failures per account before lockout
+	MaxIPAttempts   int      `yaml:"max_ip_attempts" toml:"max_ip_attempts" env:"APP_AUTH_LOCKOUT_MAX_IP_ATTEMPTS"`       // Failures per client IP before lockout
+	LockoutDuration Duration `yaml:"lockout_duration" toml:"lockout_duration" env:"APP_AUTH_LOCKOUT_DURATION"`            // How long a locked account or IP stays locked
+	ResetAfter      Duration `yaml:"reset_after" toml:"reset_after" env:"APP_AUTH_LOCKOUT_RESET_AFTER"`                   // Forget failures after this quiet period
+}
+
+// PasswordPolicyConfig rules applied whenever a password is set
+type PasswordPolicyConfig struct {
+	MinLength             int               `yaml:"min_length" toml:"min_length" env:"APP_AUTH_PASSWORD_MIN_LENGTH"`                                           // Minimum length in characters
+	MaxLength             int               `yaml:"max_length" toml:"max_length" env:"APP_AUTH_PASSWORD_MAX_LENGTH"`                                           // Maximum length in characters, 0 for no limit
+	RequireUppercase      bool              `yaml:"require_uppercase" toml:"require_uppercase" env:"APP_AUTH_PASSWORD_REQUIRE_UPPERCASE"`                      // Require an uppercase letter
+	RequireLowercase      bool              `yaml:"require_lowercase" toml:"require_lowercase" env:"APP_AUTH_PASSWORD_REQUIRE_LOWERCASE"`                      // Require a lowercase letter
+	RequireDigit          bool              `yaml:"require_digit" toml:"require_digit" env:"APP_AUTH_PASSWORD_REQUIRE_DIGIT"`                                  // Require a digit
+	RequireSymbol         bool              `yaml:"require_symbol" toml:"require_symbol" env:"APP_AUTH_PASSWORD_REQUIRE_SYMBOL"`                               // Require a symbol
+	MinCharacterClasses   int               `yaml:"min_character_classes" toml:"min_character_classes" env:"APP_AUTH_PASSWORD_MIN_CHARACTER_CLASSES"`          // Minimum number of distinct classes among uppercase, lowercase, digits, symbols
+	MaxUserInfoSimilarity float64           `yaml:"max_user_info_similarity" toml:"max_user_info_similarity" env:"APP_AUTH_PASSWORD_MAX_USER_INFO_SIMILARITY"` // Reject passwords more similar than this (0-1) to username or email, 0 disables
+	CheckDictionary       bool              `yaml:"check_dictionary" toml:"check_dictionary" env:"APP_AUTH_PASSWORD_CHECK_DICTIONARY"`                         // Reject common passwords
+	DictionaryFile        string            `yaml:"dictionary_file" toml:"dictionary_file" env:"APP_AUTH_PASSWORD_DICTIONARY_FILE"`                            // Extra common passwords, one per line
+	Breach                BreachCheckConfig `yaml:"breach" toml:"breach"`                                                                                      // Rejection of passwords found in data breaches
+}
+
+// BreachCheckConfig breached password checking configuration
+type BreachCheckConfig struct {
+	File       string   `yaml:"file" toml:"file" env:"APP_AUTH_PASSWORD_BREACH_FILE"`                      // Local SHA-1 hash file sorted by hash, in HASH:COUNT format
+	IndexFile  string   `yaml:"index_file" toml:"index_file" env:"APP_AUTH_PASSWORD_BREACH_INDEX_FILE"`    // Prefix index of hash file, defaults to file path with ".idx" appended
+	APIEnabled bool     `yaml:"api_enabled" toml:"api_enabled" env:"APP_AUTH_PASSWORD_BREACH_API_ENABLED"` // Query k-anonymity range API
+	APIURL     string   `yaml:"api_url" toml:"api_url" env:"APP_AUTH_PASSWORD_BREACH_API_URL"`             // Range API base URL, hash prefix is appended
+	APITimeout Duration `yaml:"api_timeout" toml:"api_timeout" env:"APP_AUTH_PASSWORD_BREACH_API_TIMEOUT"` // Range API request timeout
+	MinCount   int      `yaml:"min_count" toml:"min_count" env:"APP_AUTH_PASSWORD_BREACH_MIN_COUNT"`       // Reject passwords seen at least this many times
+	FailOpen   bool     `yaml:"fail_open" toml:"fail_open" env:"APP_AUTH_PASSWORD_BREACH_FAIL_OPEN"`       // Accept passwords when the check itself fails
+}
+
+// PasswordHashingConfig password hashing configuration. New passwords are hashed with Algorithm;
+// stored hashes of other algorithms or parameters are upgraded on the user's next successful signin.
+type PasswordHashingConfig struct {
+	Algorithm  string         `yaml:"algorithm" toml:"algorithm" env:"APP_AUTH_PASSWORD_HASHING_ALGORITHM"`       // Algorithm: argon2id, scrypt, bcrypt
+	BcryptCost int            `yaml:"bcrypt_cost" toml:"bcrypt_cost" env:"APP_AUTH_PASSWORD_HASHING_BCRYPT_COST"` // bcrypt cost factor
+	Scrypt     ScryptConfig   `yaml:"scrypt" toml:"scrypt"`                                                       // scrypt parameters
+	Argon2id   Argon2idConfig `yaml:"argon2id" toml:"argon2id"`                                                   // Argon2id parameters
+}
+
+// ScryptConfig scrypt parameters
+type ScryptConfig struct {
+	CostLog     int `yaml:"cost_log" toml:"cost_log" env:"APP_AUTH_PASSWORD_HASHING_SCRYPT_COST_LOG"`          // log2 of CPU/memory cost N
+	BlockSize   int `yaml:"block_size" toml:"block_size" env:"APP_AUTH_PASSWORD_HASHING_SCRYPT_BLOCK_SIZE"`    // Block size r
+	Parallelism int `yaml:"parallelism" toml:"parallelism" env:"APP_AUTH_PASSWORD_HASHING_SCRYPT_PARALLELISM"` // Parallelism p
+}
+
+// Argon2idConfig Argon2id parameters
+type Argon2idConfig struct {
+	Memory      uint32 `yaml:"memory" toml:"memory" env:"APP_AUTH_PASSWORD_HASHING_ARGON2ID_MEMORY"`                // Memory in KiB
+	Iterations  uint32 `yaml:"iterations" toml:"iterations" env:"APP_AUTH_PASSWORD_HASHING_ARGON2ID_ITERATIONS"`    // Number of passes
+	Parallelism uint8  `yaml:"parallelism" toml:"parallelism" env:"APP_AUTH_PASSWORD_HASHING_ARGON2ID_PARALLELISM"` // Number of lanes
+}
+
+// MFAConfig two-factor authentication configuration
+type MFAConfig struct {
+	Issuer       string   `yaml:"issuer" toml:"issuer" env:"APP_AUTH_MFA_ISSUER"`                      // Issuer shown by authenticator apps
+	Skew         int      `yaml:"skew" toml:"skew" env:"APP_AUTH_MFA_SKEW"`                            // TOTP time steps accepted before and after the current one
+	ChallengeTTL Duration `yaml:"challenge_ttl" toml:"challenge_ttl" env:"APP_AUTH_MFA_CHALLENGE_TTL"` // Time to submit the second factor after password signin
+	MaxAttempts  int      `yaml:"max_attempts" toml:"max_attempts" env:"APP_AUTH_MFA_MAX_ATTEMPTS"`    // Wrong codes before a challenge is invalidated
+}
+
+// WebAuthnConfig WebAuthn passkey configuration
+type WebAuthnConfig struct {
+	Enabled       bool     `yaml:"enabled" toml:"enabled" env:"APP_AUTH_WEBAUTHN_ENABLED"`                         // Enable passkey registration and login
+	RPID          string   `yaml:"rp_id" toml:"rp_id" env:"APP_AUTH_WEBAUTHN_RP_ID"`                               // Relying party ID, the site's domain without scheme and port
+	RPDisplayName string   `yaml:"rp_display_name" toml:"rp_display_name" env:"APP_AUTH_WEBAUTHN_RP_DISPLAY_NAME"` // Relying party name shown by authenticators
+	RPOrigins     []string `yaml:"rp_origins" toml:"rp_origins" env:"APP_AUTH_WEBAUTHN_RP_ORIGINS"`                // Allowed origins of the frontend, comma separated in env
+	CeremonyTTL   Duration `yaml:"ceremony_ttl" toml:"ceremony_ttl" env:"APP_AUTH_WEBAUTHN_CEREMONY_TTL"`          // Time to complete a registration or login after requesting options
+}
+
+// RememberMeConfig session policy of signins with the remember me flag
+type RememberMeConfig struct {
+	Enabled            bool     `yaml:"enabled" toml:"enabled" env:"APP_AUTH_REMEMBER_ME_ENABLED"`                                        // Honor the remember me flag of signin
+	SessionTTL         Duration `yaml:"session_ttl" toml:"session_ttl" env:"APP_AUTH_REMEMBER_ME_SESSION_TTL"`                            // Session lifetime, extended on every use
+	IdleTimeout        Duration `yaml:"idle_timeout" toml:"idle_timeout" env:"APP_AUTH_REMEMBER_ME_IDLE_TIMEOUT"`                         // Session ends after this long without use, 0 disables
+	MaxSessionLifetime Duration `yaml:"max_session_lifetime" toml:"max_session_lifetime" env:"APP_AUTH_REMEMBER_ME_MAX_SESSION_LIFETIME"` // Absolute session lifetime
+}
+
+// LoginLinkConfig passwordless email login configuration
+type LoginLinkConfig struct {
+	Enabled     bool     `yaml:"enabled" toml:"enabled" env:"APP_AUTH_LOGIN_LINK_ENABLED"`                // Enable login by emailed link or code
+	TTL         Duration `yaml:"ttl" toml:"ttl" env:"APP_AUTH_LOGIN_LINK_TTL"`                            // Lifetime of link and code
+	URL         string   `yaml:"url" toml:"url" env:"APP_AUTH_LOGIN_LINK_URL"`                            // Frontend page receiving the login token as "token" query parameter
+	MaxAttempts int      `yaml:"max_attempts" toml:"max_attempts" env:"APP_AUTH_LOGIN_LINK_MAX_ATTEMPTS"` // Wrong codes before a code is invalidated
+}
+
+// OAuthConfig social login configuration
+type OAuthConfig struct {
+	CallbackURL string                         `yaml:"callback_url" toml:"callback_url" env:"APP_AUTH_OAUTH_CALLBACK_URL"`    // Base URL of callback routes, "/<provider>/callback" is appended
+	StateTTL    Duration                       `yaml:"state_ttl" toml:"state_ttl" env:"APP_AUTH_OAUTH_STATE_TTL"`             // Time to complete login at the provider
+	Timeout     Duration                       `yaml:"timeout" toml:"timeout" env:"APP_AUTH_OAUTH_TIMEOUT"`                   // Timeout of requests to providers
+	LinkByEmail bool                           `yaml:"link_by_email" toml:"link_by_email" env:"APP_AUTH_OAUTH_LINK_BY_EMAIL"` // Link identities to existing users with the same verified email
+	Providers   map[string]OAuthProviderConfig `yaml:"providers" toml:"providers"`                                            // Providers by route name
+}
+
+// OAuthProviderConfig OAuth2 login provider configuration
+type OAuthProviderConfig struct {
+	Type         string   `yaml:"type" toml:"type"`                   // Provider type: google, github, oidc
+	ClientID     string   `yaml:"client_id" toml:"client_id"`         // OAuth2 client ID
+	ClientSecret string   `yaml:"client_secret" toml:"client_secret"` // OAuth2 client secret
+	IssuerURL    string   `yaml:"issuer_url" toml:"issuer_url"`       // OpenID Connect issuer, discovered on startup; required for oidc
+	AuthURL      string   `yaml:"auth_url" toml:"auth_url"`           // Authorization endpoint override, e.g. for GitHub Enterprise
+	TokenURL     string   `yaml:"token_url" toml:"token_url"`         // Token endpoint override
+	APIURL       string   `yaml:"api_url" toml:"api_url"`             // GitHub API base URL override
+	Scopes       []string `yaml:"scopes" toml:"scopes"`               // Requested scopes, defaults depend on type
+}
+
+// RateLimitConfig HTTP rate limiting configuration
+type RateLimitConfig struct {
+	Enabled  bool                       `yaml:"enabled" toml:"enabled" env:"APP_RATE_LIMIT_ENABLED"` // Enable rate limiting
+	Store    string                     `yaml:"store" toml:"store" env:"APP_RATE_LIMIT_STORE"`       // State store: memory, redis
+	Redis    RedisConfig                `yaml:"redis" toml:"redis"`                                  // Redis store options
+	Policies map[string]RateLimitPolicy `yaml:"policies" toml:"policies"`                            // Policies by route name
+}
+
+// RateLimitPolicy rate limit of one route
+type RateLimitPolicy struct {
+	Algorithm string   `yaml:"algorithm" toml:"algorithm"` // Algorithm: token_bucket, sliding_window
+	Limit     int      `yaml:"limit" toml:"limit"`         // Requests per window, or bucket capacity
+	Window    Duration `yaml:"window" toml:"window"`       // Window length, or time to refill an empty bucket
+	KeyBy     string   `yaml:"key_by" toml:"key_by"`       // Client identity: ip, user, api_key
+}
+
+// RedisConfig Redis server configuration
+type RedisConfig struct {
+	Addr     string   `yaml:"addr" toml:"addr" env:"APP_REDIS_ADDR"`             // Server address, host:port
+	Password string   `yaml:"password" toml:"password" env:"APP_REDIS_PASSWORD"` // Password, empty disables AUTH
+	DB       int      `yaml:"db" toml:"db" env:"APP_REDIS_DB"`                   // Database number
+	Timeout  Duration `yaml:"timeout" toml:"timeout" env:"APP_REDIS_TIMEOUT"`    // Dial and command timeout
+}
+
+// CleanupConfig background expiry and purging of stale rows
+type CleanupConfig struct {
+	Enabled   bool     `yaml:"enabled" toml:"enabled" env:"APP_CLEANUP_ENABLED"`          // Run cleanup job in the server process
+	Interval  Duration `yaml:"interval" toml:"interval" env:"APP_CLEANUP_INTERVAL"`       // Time between runs
+	Retention Duration `yaml:"retention" toml:"retention" env:"APP_CLEANUP_RETENTION"`    // How long ended sessions and expired tokens are kept
+	BatchSize int      `yaml:"batch_size" toml:"batch_size" env:"APP_CLEANUP_BATCH_SIZE"` // Rows deleted per statement
+}
+
+// MailConfig email delivery configuration
+type MailConfig struct {
+	Driver  string     `yaml:"driver" toml:"driver" env:"APP_MAIL_DRIVER"`       // Mail driver: smtp, file, log
+	From    string     `yaml:"from" toml:"from" env:"APP_MAIL_FROM"`             // Sender address
+	FileDir string     `yaml:"file_dir" toml:"file_dir" env:"APP_MAIL_FILE_DIR"` // Output directory of the file driver
+	SMTP    SMTPConfig `yaml:"smtp" toml:"smtp"`                                 // SMTP driver options
+}
+
+// SMTPConfig SMTP server configuration
+type SMTPConfig struct {
+	Host     string `yaml:"host" toml:"host" env:"APP_MAIL_SMTP_HOST"`             // Server host
+	Port     int    `yaml:"port" toml:"port" env:"APP_MAIL_SMTP_PORT"`             // Server port
+	Username string `yaml:"username" toml:"username" env:"APP_MAIL_SMTP_USERNAME"` // Username, empty disables authentication
+	Password string `yaml:"password" toml:"password" env:"APP_MAIL_SMTP_PASSWORD"` // Password
+}
+
+// Default returns configuration with default values
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Address:         ":8080",
+			Mode:            "debug",
+			ShutdownTimeout: Duration(10 * time.Second),
+		},
+		Database: DatabaseConfig{
+			Driver:       "sqlite",
+			DSN:          ":memory:",
+			MaxIdleConns: 2,
+			AutoMigrate:  true,
+			SQLite: SQLiteConfig{
+				JournalMode: "WAL",
+				BusyTimeout: Duration(5 * time.Second),
+				ForeignKeys: true,
+			},
+		},
+		Auth: AuthConfig{
+			SessionTTL:           Duration(24 * 7 * time.Hour),  // 7 days
+			MaxSessionLifetime:   Duration(24 * 30 * time.Hour), // 30 days
+			AccessTokenTTL:       Duration(15 * time.Minute),
+			ResetTokenTTL:        Duration(1 * time.Hour),
+			TokenFormat:          "opaque",
+			ResetPasswordURL:     "http://localhost:8080/reset-password",
+			VerificationTokenTTL: Duration(48 * time.Hour),
+			VerifyEmailURL:       "http://localhost:8080/verify-email",
+			RecoveryCodeCount:    10,
+			JWT: JWTConfig{
+				Algorithm:        "HS256",
+				Issuer:           "go-gin-api",
+				RevocationCheck:  true,
+				RetirementPeriod: Duration(24 * time.Hour),
+				AssertionTTL:     Duration(5 * time.Minute),
+			},
+			Lockout: LockoutConfig{
+				Enabled:         true,
+				FreeAttempts:    3,
+				BaseDelay:       Duration(1 * time.Second),
+				MaxDelay:        Duration(1 * time.Minute),
+				MaxUserAttempts: 10,
+				MaxIPAttempts:   100,
+				LockoutDuration: Duration(15 * time.Minute),
+				ResetAfter:      Duration(1 * time.Hour),
+			},
+			PasswordPolicy: PasswordPolicyConfig{
+				MinLength:             8,
+				MaxLength:             72,
+				MaxUserInfoSimilarity: 0.7,
+				CheckDictionary:       true,
+				Breach: BreachCheckConfig{
+					APIURL:     "https://api.pwnedpasswords.com/range/",
+					APITimeout: Duration(3 * time.Second),
+					MinCount:   1,
+					FailOpen:   true,
+				},
+			},
+			MFA: MFAConfig{
+				Issuer:       "go-gin-api",
+				Skew:         1,
+				ChallengeTTL: Duration(5 * time.Minute),
+				MaxAttempts:  5,
+			},
+			WebAuthn: WebAuthnConfig{
+				Enabled:       true,
+				RPID:          "localhost",
+				RPDisplayName: "go-gin-api",
+				RPOrigins:     []string{"http://localhost:8080"},
+				CeremonyTTL:   Duration(5 * time.Minute),
+			},
+			RememberMe: RememberMeConfig{
+				Enabled:            true,
+				SessionTTL:         Duration(24 * 30 * time.Hour), // 30 days
+				MaxSessionLifetime: Duration(24 * 90 * time.Hour), // 90 days
+			},
+			LoginLink: LoginLinkConfig{
+				Enabled:     true,
+				TTL:         Duration(15 * time.Minute),
+				URL:         "http://localhost:8080/login-link",
+				MaxAttempts: 5,
+			},
+			OAuth: OAuthConfig{
+				CallbackURL: "http://localhost:8080/api/auth/oauth",
+				StateTTL:    Duration(10 * time.Minute),
+				Timeout:     Duration(10 * time.Second),
+				LinkByEmail: true,
+			},
+			PasswordHashing: PasswordHashingConfig{
+				Algorithm:  "argon2id",
+				BcryptCost: 10,
+				Scrypt: ScryptConfig{
+					CostLog:     15,
+					BlockSize:   8,
+					Parallelism: 1,
+				},
+				Argon2id: Argon2idConfig{
+					Memory:      19 * 1024,
+					Iterations:  2,
+					Parallelism: 1,
+				},
+			},
+		},
+		Cleanup: CleanupConfig{
+			Enabled:   true,
+			Interval:  Duration(10 * time.Minute),
+			Retention: Duration(7 * 24 * time.Hour),
+			BatchSize: 500,
+		},
+		RateLimit: RateLimitConfig{
+			Enabled: true,
+			Store:   "memory",
+			Redis: RedisConfig{
+				Addr:    "localhost:6379",
+				Timeout: Duration(1 * time.Second),
+			},
+			Policies: map[string]RateLimitPolicy{
+				"signup":              {Algorithm: "sliding_window", Limit: 10, Window: Duration(time.Hour), KeyBy: "ip"},
+				"signin":              {Algorithm: "token_bucket", Limit: 20, Window: Duration(time.Minute), KeyBy: "ip"},
+				"refresh":             {Algorithm: "token_bucket", Limit: 60, Window: Duration(time.Minute), KeyBy: "ip"},
+				"forgot_password":     {Algorithm: "sliding_window", Limit: 5, Window: Duration(time.Hour), KeyBy: "ip"},
+				"reset_password":      {Algorithm: "sliding_window", Limit: 10, Window: Duration(time.Hour), KeyBy: "ip"},
+				"verify_email":        {Algorithm: "sliding_window", Limit: 20, Window: Duration(time.Hour), KeyBy: "ip"},
+				"resend_verification": {Algorithm: "sliding_window", Limit: 5, Window: Duration(time.Hour), KeyBy: "ip"},
+				"validate":            {Algorithm: "token_bucket", Limit: 120, Window: Duration(time.Minute), KeyBy: "ip"},
+				"change_password":     {Algorithm: "sliding_window", Limit: 10, Window: Duration(time.Hour), KeyBy: "user"},
+				"signin_mfa":          {Algorithm: "token_bucket", Limit: 20, Window: Duration(time.Minute), KeyBy: "ip"},
+				"mfa":                 {Algorithm: "sliding_window", Limit: 20, Window: Duration(time.Hour), KeyBy: "user"},
+				"recover_account":     {Algorithm: "sliding_window", Limit: 10, Window: Duration(time.Hour), KeyBy: "ip"},
+				"login_link":          {Algorithm: "sliding_window", Limit: 5, Window: Duration(time.Hour), KeyBy: "ip"},
+				"login_link_exchange": {Algorithm: "token_bucket", Limit: 20, Window: Duration(time.Minute), KeyBy: "ip"},
+				"oauth":               {Algorithm: "token_bucket", Limit: 20, Window: Duration(time.Minute), KeyBy: "ip"},
+				"oauth_link":          {Algorithm: "sliding_window", Limit: 10, Window: Duration(time.Hour), KeyBy: "user"},
+				"recovery_codes":      {Algorithm: "sliding_window", Limit: 10, Window: Duration(time.Hour), KeyBy: "user"},
+				"webauthn_login":      {Algorithm: "token_bucket", Limit: 20, Window: Duration(time.Minute), KeyBy: "ip"},
+			},
+		},
+		Mail: MailConfig{
+			Driver:  "log",
+			From:    "no-reply@localhost",
+			FileDir: "mail",
+			SMTP: SMTPConfig{
+				Port: 587,
+			},
+		},
+	}
+}
+
+// Validate checks configuration values
+func (c *Config) Validate() error {
+	var problems []string
+
+	if c.Server.Address == "" {
+		problems = append(problems, "server.address is required")
+	}
+	switch c.Server.Mode {
+	case "debug", "release", "test":
+	default:
+		problems = append(problems, "server.mode must be one of debug, release, test")
+	}
+	if c.Server.ShutdownTimeout <= 0 {
+		problems = append(problems, "server.shutdown_timeout must be positive")
+	}
+
+	switch c.Database.Driver {
+	case "sqlite", "postgres", "mysql":
+	default:
+		problems = append(problems, "database.driver must be one of sqlite, postgres, mysql")
+	}
+	if c.Database.DSN == "" {
+		problems = append(problems, "database.dsn is required")
+	}
+	if c.Database.MaxOpenConns < 0 || c.Database.MaxIdleConns < 0 || c.Database.ConnMaxLifetime < 0 {
+		problems = append(problems, "database connection pool settings must not be negative")
+	}
+	if c.Database.SQLite.BusyTimeout < 0 {
+		problems = append(problems, "database.sqlite.busy_timeout must not be negative")
+	}
+
+	if c.Auth.SessionTTL <= 0 {
+		problems = append(problems, "auth.session_ttl must be positive")
+	}
+	if c.Auth.AccessTokenTTL <= 0 || c.Auth.AccessTokenTTL > c.Auth.SessionTTL {
+		problems = append(problems, "auth.access_token_ttl must be positive and not longer than auth.session_ttl")
+	}
+	if c.Auth.IdleTimeout < 0 {
+		problems = append(problems, "auth.idle_timeout must not be negative")
+	}
+	if c.Auth.MaxSessionLifetime < c.Auth.SessionTTL {
+		problems = append(problems, "auth.max_session_lifetime must not be shorter than auth.session_ttl")
+	}
+	if c.Auth.RememberMe.Enabled {
+		if c.Auth.RememberMe.SessionTTL < c.Auth.AccessTokenTTL {
+			problems = append(problems, "auth.remember_me.session_ttl must not be shorter than auth.access_token_ttl")
+		}
+		if c.Auth.RememberMe.IdleTimeout < 0 {
+			problems = append(problems, "auth.remember_me.idle_timeout must not be negative")
+		}
+		if c.Auth.RememberMe.MaxSessionLifetime < c.Auth.RememberMe.SessionTTL {
+			problems = append(problems, "auth.remember_me.max_session_lifetime must not be shorter than auth.remember_me.session_ttl")
+		}
+	}
+	if c.Auth.ResetTokenTTL <= 0 {
+		problems = append(problems, "auth.reset_token_ttl must be positive")
+	}
+	if c.Auth.ResetPasswordURL == "" {
+		problems = append(problems, "auth.reset_password_url is required")
+	}
+	if c.Auth.VerificationTokenTTL <= 0 {
+		problems = append(problems, "auth.verification_token_ttl must be positive")
+	}
+	if c.Auth.VerifyEmailURL == "" {
+		problems = append(problems, "auth.verify_email_url is required")
+	}
+	switch c.Auth.TokenFormat {
+	case "opaque", "jwt":
+	default:
+		problems = append(problems, "auth.token_format must be one of opaque, jwt")
+	}
+	if c.Auth.TokenFormat == "jwt" || c.Auth.JWT.KeysConfigured() {
+		switch c.Auth.JWT.Algorithm {
+		case "HS256":
+			if len(c.Auth.JWT.Secret) < 32 {
+				problems = append(problems, "auth.jwt.secret must be at least 32 bytes for HS256")
+			}
+			if c.Auth.JWT.RotationInterval != 0 {
+				problems = append(problems, "auth.jwt.rotation_interval is not supported for HS256")
+			}
+		case "RS256", "EdDSA":
+			if c.Auth.JWT.PrivateKeyFile == "" && c.Auth.JWT.KeyDir == "" {
+				problems = append(problems, "auth.jwt.private_key_file or auth.jwt.key_dir is required for "+c.Auth.JWT.Algorithm)
+			}
+		default:
+			problems = append(problems, "auth.jwt.algorithm must be one of HS256, RS256, EdDSA")
+		}
+		if c.Auth.JWT.RotationInterval < 0 {
+			problems = append(problems, "auth.jwt.rotation_interval must not be negative")
+		}
+		if c.Auth.JWT.RetirementPeriod < c.Auth.AccessTokenTTL || c.Auth.JWT.RetirementPeriod < c.Auth.JWT.AssertionTTL {
+			problems = append(problems, "auth.jwt.retirement_period must not be shorter than access token and assertion lifetimes")
+		}
+		if c.Auth.JWT.AssertionTTL <= 0 {
+			problems = append(problems, "auth.jwt.assertion_ttl must be positive")
+		}
+	}
+
+	if c.Auth.Lockout.Enabled {
+		if c.Auth.Lockout.FreeAttempts < 0 || c.Auth.Lockout.MaxUserAttempts < 0 || c.Auth.Lockout.MaxIPAttempts < 0 {
+			problems = append(problems, "auth.lockout attempt limits must not be negative")
+		}
+		if c.Auth.Lockout.BaseDelay < 0 || c.Auth.Lockout.MaxDelay < 0 || c.Auth.Lockout.ResetAfter < 0 {
+			problems = append(problems, "auth.lockout durations must not be negative")
+		}
+		if c.Auth.Lockout.LockoutDuration <= 0 {
+			problems = append(problems, "auth.lockout.lockout_duration must be positive")
+		}
+	}
+
+	policy := c.Auth.PasswordPolicy
+	if policy.MinLength < 1 {
+		problems = append(problems, "auth.password_policy.min_length must be positive")
+	}
+	if policy.MaxLength != 0 && policy.MaxLength < policy.MinLength {
+		problems = append(problems, "auth.password_policy.max_length must not be shorter than min_length")
+	}
+	if policy.MinCharacterClasses < 0 || policy.MinCharacterClasses > 4 {
+		problems = append(problems, "auth.password_policy.min_character_classes must be between 0 and 4")
+	}
+	if policy.MaxUserInfoSimilarity < 0 || policy.MaxUserInfoSimilarity > 1 {
+		problems = append(problems, "auth.password_policy.max_user_info_similarity must be between 0 and 1")
+	}
+	if policy.Breach.File != "" || policy.Breach.APIEnabled {
+		if policy.Breach.MinCount < 1 {
+			problems = append(problems, "auth.password_policy.breach.min_count must be positive")
+		}
+		if policy.Breach.APIEnabled && policy.Breach.APITimeout <= 0 {
+			problems = append(problems, "auth.password_policy.breach.api_timeout must be positive")
+		}
+	}
+
+	hashing := c.Auth.PasswordHashing
+	switch hashing.Algorithm {
+	case "argon2id", "scrypt", "bcrypt":
+	default:
+		problems = append(problems, "auth.password_hashing.algorithm must be one of argon2id, scrypt, bcrypt")
+	}
+	if hashing.BcryptCost < 4 || hashing.BcryptCost > 31 {
+		problems = append(problems, "auth.password_hashing.bcrypt_cost must be between 4 and 31")
+	}
+	if hashing.Scrypt.CostLog < 1 || hashing.Scrypt.CostLog > 30 || hashing.Scrypt.BlockSize < 1 || hashing.Scrypt.Parallelism < 1 {
+		problems = append(problems, "auth.password_hashing.scrypt parameters must be positive, cost_log at most 30")
+	}
+	if hashing.Argon2id.Iterations < 1 || hashing.Argon2id.Parallelism < 1 || hashing.Argon2id.Memory < 8*uint32(hashing.Argon2id.Parallelism) {
+		problems = append(problems, "auth.password_hashing.argon2id parameters must be positive, memory at least 8 KiB per lane")
+	}
+
+	if c.Auth.WebAuthn.Enabled {
+		if c.Auth.WebAuthn.RPID == "" || c.Auth.WebAuthn.RPDisplayName == "" || len(c.Auth.WebAuthn.RPOrigins) == 0 {
+			problems = append(problems, "auth.webauthn.rp_id, rp_display_name and rp_origins are required when webauthn is enabled")
+		}
+		if c.Auth.WebAuthn.CeremonyTTL <= 0 {
+			problems = append(problems, "auth.webauthn.ceremony_ttl must be positive")
+		}
+	}
+	if c.Auth.LoginLink.Enabled {
+		if c.Auth.LoginLink.TTL <= 0 || c.Auth.LoginLink.MaxAttempts < 1 {
+			problems = append(problems, "auth.login_link.ttl and auth.login_link.max_attempts must be positive")
+		}
+		if c.Auth.LoginLink.URL == "" {
+			problems = append(problems, "auth.login_link.url is required when login links are enabled")
+		}
+	}
+	if len(c.Auth.OAuth.Providers) > 0 {
+		if c.Auth.OAuth.CallbackURL == "" {
+			problems = append(problems, "auth.oauth.callback_url is required when providers are configured")
+		}
+		if c.Auth.OAuth.StateTTL <= 0 || c.Auth.OAuth.Timeout <= 0 {
+			problems = append(problems, "auth.oauth.state_ttl and auth.oauth.timeout must be positive")
+		}
+	}
+	for name, provider := range c.Auth.OAuth.Providers {
+		switch provider.Type {
+		case "google", "github":
+		case "oidc":
+			if provider.IssuerURL == "" {
+				problems = append(problems, "auth.oauth.providers."+name+".issuer_url is required for oidc")
+			}
+		default:
+			problems = append(problems, "auth.oauth.providers."+name+".type must be one of google, github, oidc")
+		}
+		if provider.ClientID == "" || provider.ClientSecret == "" {
+			problems = append(problems, "auth.oauth.providers."+name+".client_id and client_secret are required")
+		}
+	}
+	if c.Auth.RecoveryCodeCount < 1 || c.Auth.RecoveryCodeCount > 50 {
+		problems = append(problems, "auth.recovery_code_count must be between 1 and 50")
+	}
+	if c.Auth.MFA.Issuer == "" {
+		problems = append(problems, "auth.mfa.issuer is required")
+	}
+	if c.Auth.MFA.Skew < 0 || c.Auth.MFA.Skew > 10 {
+		problems = append(problems, "auth.mfa.skew must be between 0 and 10")
+	}
+	if c.Auth.MFA.ChallengeTTL <= 0 || c.Auth.MFA.MaxAttempts < 1 {
+		problems = append(problems, "auth.mfa.challenge_ttl and auth.mfa.max_attempts must be positive")
+	}
+
+	if c.Cleanup.Enabled {
+		if c.Cleanup.Interval < Duration(time.Second) || c.Cleanup.Retention < 0 {
+			problems = append(problems, "cleanup.interval must be at least 1s and cleanup.retention must not be negative")
+		}
+		if c.Cleanup.BatchSize < 1 {
+			problems = append(problems, "cleanup.batch_size must be positive")
+		}
+	}
+
+	switch c.Mail.Driver {
+	case "log":
+	case "file":
+		if c.Mail.FileDir == "" {
+			problems = append(problems, "mail.file_dir is required for file driver")
+		}
+	case "smtp":
+		if c.Mail.SMTP.Host == "" || c.Mail.SMTP.Port <= 0 {
+			problems = append(problems, "mail.smtp.host and mail.smtp.port are required for smtp driver")
+		}
+	default:
+		problems = append(problems, "mail.driver must be one of smtp, file, log")
+	}
+	// The log and file drivers keep reset, verification and login links readable outside the mailbox
+	if c.Server.Mode == "release" && (c.Mail.Driver == "log" || c.Mail.Driver == "file") {
+		problems = append(problems, "mail.driver must be smtp in release mode, log and file are for development and tests")
+	}
+	if c.Mail.From == "" {
+		problems = append(problems, "mail.from is required")
+	}
+
+	if c.RateLimit.Enabled {
+		switch c.RateLimit.Store {
+		case "memory":
+		case "redis":
+			if c.RateLimit.Redis.Addr == "" {
+				problems = append(problems, "rate_limit.redis.addr is required for redis store")
+			}
+		default:
+			problems = append(problems, "rate_limit.store must be one of memory, redis")
+		}
+		for name, policy := range c.RateLimit.Policies {
+			if policy.Algorithm != "token_bucket" && policy.Algorithm != "sliding_window" {
+				problems = append(problems, "rate_limit.policies."+name+".algorithm must be one of token_bucket, sliding_window")
+			}
+			if policy.Limit <= 0 || policy.Window < Duration(time.Millisecond) {
+				problems = append(problems, "rate_limit.policies."+name+" requires positive limit and window")
+			}
+			switch policy.KeyBy {
+			case "ip", "user", "api_key":
+			default:
+				problems = append(problems, "rate_limit.policies."+name+".key_by must be one of ip, user, api_key")
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return errors.New("invalid configuration: " + strings.Join(problems, "; "))
+	}
+	return nil
+}
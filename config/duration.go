@@ -0,0 +1,34 @@
+package config
+
+import (
+	"time"
+)
+
+// Duration wraps time.Duration so it can be decoded from strings like "15m" or "168h"
+// in YAML, TOML and environment variables
+type Duration time.Duration
+
+// UnmarshalText parses duration text
+func (d *Duration) UnmarshalText(text []byte) error {
+	parsed, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// MarshalText formats duration as text
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// Std returns the standard library duration
+func (d Duration) Std() time.Duration {
+	return time.Duration(d)
+}
+
+// String returns duration text
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
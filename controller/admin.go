@@ -0,0 +1,81 @@
+package controller
+
+import (
+	"expvar"
+	"net/http"
+	"strconv"
+
+	"github.com/damonleelcx/go-gin-api/middleware"
+	"github.com/damonleelcx/go-gin-api/service"
+	"github.com/gin-gonic/gin"
+)
+
+// AdminController administration controller
+type AdminController struct {
+	authService *service.AuthService
+}
+
+// NewAdminController creates a new administration controller instance
+func NewAdminController(authService *service.AuthService) *AdminController {
+	return &AdminController{
+		authService: authService,
+	}
+}
+
+// UnlockUser unlock user account
+// @Summary Unlock user
+// @Description Clear failed login attempts and lockout of a user account
+// @Tags admin
+// @Produce json
+// @Param Authorization header string true "Bearer Token"
+// @Param id path int true "User ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /admin/users/{id}/unlock [post]
+func (adc *AdminController) UnlockUser(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid user ID",
+		})
+		return
+	}
+
+	// Call service layer
+	if err := adc.authService.UnlockUser(uint(userID)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "User unlocked",
+	})
+}
+
+// Metrics runtime and job metrics
+// @Summary Metrics
+// @Description Expvar metrics of the process, including counters of the cleanup job
+// @Tags admin
+// @Produce json
+// @Param Authorization header string true "Bearer Token"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /admin/metrics [get]
+func (adc *AdminController) Metrics(c *gin.Context) {
+	expvar.Handler().ServeHTTP(c.Writer, c.Request)
+}
+
+// RegisterRoutes register routes
+// @Description Register administration routes to Gin router, all routes require admin role
+func (adc *AdminController) RegisterRoutes(router *gin.RouterGroup) {
+	admin := router.Group("/admin", middleware.Auth(adc.authService), middleware.RequireRole("admin"))
+	{
+		admin.POST("/users/:id/unlock", adc.UnlockUser)
+		admin.GET("/metrics", adc.Metrics)
+	}
+}
@@ -1,8 +1,14 @@
 package controller
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
+	"time"
 
+	"github.com/damonleelcx/go-gin-api/middleware"
+	"github.com/damonleelcx/go-gin-api/password"
+	"github.com/damonleelcx/go-gin-api/ratelimit"
 	"github.com/damonleelcx/go-gin-api/service"
 	"github.com/gin-gonic/gin"
 )
@@ -10,12 +16,14 @@ import (
 // AuthController authentication controller
 type AuthController struct {
 	authService *service.AuthService
+	limiter     *ratelimit.Limiter
 }
 
 // NewAuthController creates a new authentication controller instance
-func NewAuthController(authService *service.AuthService) *AuthController {
+func NewAuthController(authService *service.AuthService, limiter *ratelimit.Limiter) *AuthController {
 	return &AuthController{
 		authService: authService,
+		limiter:     limiter,
 	}
 }
 
@@ -45,6 +53,9 @@ func (ac *AuthController) Signup(c *gin.Context) {
 	// Call service layer
 	response, err := ac.authService.Signup(&req, ipAddress, userAgent)
 	if err != nil {
+		if respondPolicyViolations(c, err) {
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": err.Error(),
 		})
@@ -63,6 +74,8 @@ func (ac *AuthController) Signup(c *gin.Context) {
 // @Param request body service.SigninRequest true "Login information"
 // @Success 200 {object} service.SigninResponse
 // @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 429 {object} map[string]string
 // @Router /auth/signin [post]
 func (ac *AuthController) Signin(c *gin.Context) {
 	var req service.SigninRequest
@@ -79,6 +92,71 @@ func (ac *AuthController) Signin(c *gin.Context) {
 
 	// Call service layer
 	response, err := ac.authService.Signin(&req, ipAddress, userAgent)
+	if err != nil {
+		respondAuthError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// SigninMFA complete login with second factor
+// @Summary Two-factor login
+// @Description Exchange MFA token from signin and authenticator app code for session token
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body service.SigninMFARequest true "MFA token and code"
+// @Success 200 {object} service.SigninResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Router /auth/signin/mfa [post]
+func (ac *AuthController) SigninMFA(c *gin.Context) {
+	var req service.SigninMFARequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request parameters: " + err.Error(),
+		})
+		return
+	}
+
+	// Call service layer
+	response, err := ac.authService.SigninMFA(&req, c.ClientIP(), c.GetHeader("User-Agent"))
+	if err != nil {
+		respondAuthError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// Refresh refresh access token
+// @Summary Refresh token
+// @Description Exchange refresh token for a new access token and refresh token
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body service.RefreshRequest true "Refresh token"
+// @Success 200 {object} service.RefreshResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /auth/refresh [post]
+func (ac *AuthController) Refresh(c *gin.Context) {
+	var req service.RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request parameters: " + err.Error(),
+		})
+		return
+	}
+
+	// Get client IP and User-Agent
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	// Call service layer
+	response, err := ac.authService.Refresh(&req, ipAddress, userAgent)
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"error": err.Error(),
@@ -100,22 +178,11 @@ func (ac *AuthController) Signin(c *gin.Context) {
 // @Failure 401 {object} map[string]string
 // @Router /auth/logout [post]
 func (ac *AuthController) Logout(c *gin.Context) {
-	// Get token from request header
-	token := c.GetHeader("Authorization")
-	if token == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error": "Missing authentication token",
-		})
-		return
-	}
-
-	// Remove "Bearer " prefix if exists
-	if len(token) > 7 && token[:7] == "Bearer " {
-		token = token[7:]
-	}
+	// Get session authenticated by middleware
+	session, _ := middleware.CurrentSession(c)
 
 	// Call service layer
-	if err := ac.authService.Logout(token); err != nil {
+	if err := ac.authService.Logout(session.ID); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": err.Error(),
 		})
@@ -138,31 +205,76 @@ func (ac *AuthController) Logout(c *gin.Context) {
 // @Failure 401 {object} map[string]string
 // @Router /auth/logout-all [post]
 func (ac *AuthController) LogoutAll(c *gin.Context) {
-	// Get token from request header and validate
-	token := c.GetHeader("Authorization")
-	if token == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error": "Missing authentication token",
+	// Get user authenticated by middleware
+	user, _ := middleware.CurrentUser(c)
+
+	// Call service layer to logout all sessions
+	if err := ac.authService.LogoutAll(user.ID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "All sessions logged out",
+	})
+}
+
+// VerifyEmail verify email address
+// @Summary Verify email
+// @Description Verify email address using token from verification email
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body service.VerifyEmailRequest true "Verification token"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Router /auth/verify-email [post]
+func (ac *AuthController) VerifyEmail(c *gin.Context) {
+	var req service.VerifyEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request parameters: " + err.Error(),
 		})
 		return
 	}
 
-	// Remove "Bearer " prefix if exists
-	if len(token) > 7 && token[:7] == "Bearer " {
-		token = token[7:]
+	// Call service layer
+	if err := ac.authService.VerifyEmail(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
 	}
 
-	// Validate token and get user information
-	_, user, err := ac.authService.ValidateToken(token)
-	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error": "Invalid token: " + err.Error(),
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Email verified",
+	})
+}
+
+// ResendVerification resend verification email
+// @Summary Resend verification email
+// @Description Send a new verification link to an unverified email address
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body service.ResendVerificationRequest true "Email information"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Router /auth/resend-verification [post]
+func (ac *AuthController) ResendVerification(c *gin.Context) {
+	var req service.ResendVerificationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request parameters: " + err.Error(),
 		})
 		return
 	}
 
-	// Call service layer to logout all sessions
-	if err := ac.authService.LogoutAll(user.ID); err != nil {
+	// Call service layer
+	message, err := ac.authService.ResendVerification(&req)
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": err.Error(),
 		})
@@ -170,7 +282,7 @@ func (ac *AuthController) LogoutAll(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message": "All sessions logged out",
+		"message": message,
 	})
 }
 
@@ -228,6 +340,9 @@ func (ac *AuthController) ResetPassword(c *gin.Context) {
 
 	// Call service layer
 	if err := ac.authService.ResetPassword(&req); err != nil {
+		if respondPolicyViolations(c, err) {
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": err.Error(),
 		})
@@ -239,58 +354,323 @@ func (ac *AuthController) ResetPassword(c *gin.Context) {
 	})
 }
 
-// ValidateToken validate token
-// @Summary Validate token
-// @Description Validate user token validity and return user information
+// RequestLoginLink request passwordless login
+// @Summary Request login link
+// @Description Email single-use login link and 6-digit code to the user
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body service.RequestLoginLinkRequest true "Email information"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Router /auth/login-link [post]
+func (ac *AuthController) RequestLoginLink(c *gin.Context) {
+	var req service.RequestLoginLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request parameters: " + err.Error(),
+		})
+		return
+	}
+
+	// Call service layer
+	message, err := ac.authService.RequestLoginLink(&req, c.ClientIP(), c.GetHeader("User-Agent"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": message,
+	})
+}
+
+// ExchangeLoginLink complete passwordless login
+// @Summary Exchange login link
+// @Description Exchange login link token, or email and code, for session token
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body service.ExchangeLoginLinkRequest true "Login token, or email and code"
+// @Success 200 {object} service.SigninResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Router /auth/login-link/exchange [post]
+func (ac *AuthController) ExchangeLoginLink(c *gin.Context) {
+	var req service.ExchangeLoginLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request parameters: " + err.Error(),
+		})
+		return
+	}
+
+	// Call service layer
+	response, err := ac.authService.ExchangeLoginLink(&req, c.ClientIP(), c.GetHeader("User-Agent"))
+	if err != nil {
+		respondAuthError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// RecoverAccount recover account
+// @Summary Recover account
+// @Description Set new password using a one-time recovery code, logging out all sessions
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body service.RecoverAccountRequest true "Username, recovery code and new password"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Router /auth/recover-account [post]
+func (ac *AuthController) RecoverAccount(c *gin.Context) {
+	var req service.RecoverAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request parameters: " + err.Error(),
+		})
+		return
+	}
+
+	// Call service layer
+	if err := ac.authService.RecoverAccount(&req, c.ClientIP(), c.GetHeader("User-Agent")); err != nil {
+		if respondPolicyViolations(c, err) {
+			return
+		}
+		if respondThrottled(c, err) {
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Account recovered, please sign in with your new password",
+	})
+}
+
+// RecoveryCodeStatus recovery code status
+// @Summary Recovery code status
+// @Description Get number of unused recovery codes of the current user
 // @Tags auth
 // @Produce json
 // @Param Authorization header string true "Bearer Token"
-// @Success 200 {object} map[string]interface{}
+// @Success 200 {object} service.RecoveryCodeStatusResponse
+// @Failure 400 {object} map[string]string
 // @Failure 401 {object} map[string]string
-// @Router /auth/validate [get]
-func (ac *AuthController) ValidateToken(c *gin.Context) {
-	// Get token from request header
-	token := c.GetHeader("Authorization")
-	if token == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error": "Missing authentication token",
+// @Router /auth/recovery-codes [get]
+func (ac *AuthController) RecoveryCodeStatus(c *gin.Context) {
+	// Get user authenticated by middleware
+	user, _ := middleware.CurrentUser(c)
+
+	// Call service layer
+	response, err := ac.authService.RecoveryCodeStatus(user.ID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// RegenerateRecoveryCodes regenerate recovery codes
+// @Summary Regenerate recovery codes
+// @Description Replace all recovery codes of the current user with a new batch, requires password
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer Token"
+// @Param request body service.RegenerateRecoveryCodesRequest true "Current password"
+// @Success 200 {object} service.RecoveryCodesResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /auth/recovery-codes/regenerate [post]
+func (ac *AuthController) RegenerateRecoveryCodes(c *gin.Context) {
+	var req service.RegenerateRecoveryCodesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request parameters: " + err.Error(),
 		})
 		return
 	}
 
-	// Remove "Bearer " prefix if exists
-	if len(token) > 7 && token[:7] == "Bearer " {
-		token = token[7:]
+	// Get user and session authenticated by middleware
+	user, _ := middleware.CurrentUser(c)
+	session, _ := middleware.CurrentSession(c)
+
+	// Call service layer
+	response, err := ac.authService.RegenerateRecoveryCodes(user.ID, session.ID, &req, c.ClientIP(), c.GetHeader("User-Agent"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
 	}
 
+	c.JSON(http.StatusOK, response)
+}
+
+// ChangePassword change password
+// @Summary Change password
+// @Description Change password of the current user, optionally logging out all other sessions
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer Token"
+// @Param request body service.ChangePasswordRequest true "Current and new password"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Router /auth/change-password [post]
+func (ac *AuthController) ChangePassword(c *gin.Context) {
+	var req service.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request parameters: " + err.Error(),
+		})
+		return
+	}
+
+	// Get user and session authenticated by middleware
+	user, _ := middleware.CurrentUser(c)
+	session, _ := middleware.CurrentSession(c)
+
 	// Call service layer
-	session, user, err := ac.authService.ValidateToken(token)
+	err := ac.authService.ChangePassword(user.ID, session.ID, &req, c.ClientIP(), c.GetHeader("User-Agent"))
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error": "Invalid token: " + err.Error(),
+		if respondPolicyViolations(c, err) {
+			return
+		}
+		if respondThrottled(c, err) {
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
 		})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
+		"message": "Password changed successfully",
+	})
+}
+
+// ValidateToken validate token
+// @Summary Validate token
+// @Description Validate user token validity and return user information and a signed identity assertion
+// @Tags auth
+// @Produce json
+// @Param Authorization header string true "Bearer Token"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]string
+// @Router /auth/validate [get]
+func (ac *AuthController) ValidateToken(c *gin.Context) {
+	// Get user and session authenticated by middleware
+	user, _ := middleware.CurrentUser(c)
+	session, _ := middleware.CurrentSession(c)
+
+	response := gin.H{
 		"user":    user,
 		"session": session,
 		"valid":   true,
-	})
+	}
+
+	// Attach signed identity assertion when signing keys are configured
+	if ac.authService.CanSignAssertions() {
+		assertion, err := ac.authService.IdentityAssertion(session, user)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		response["assertion"] = assertion
+	}
+
+	c.JSON(http.StatusOK, response)
 }
 
 // RegisterRoutes register routes
-// @Description Register authentication-related routes to Gin router
+// @Description Register authentication-related routes to Gin router, rate limit policies are looked up by route name
 func (ac *AuthController) RegisterRoutes(router *gin.RouterGroup) {
+	requireAuth := middleware.Auth(ac.authService)
+
 	auth := router.Group("/auth")
 	{
-		auth.POST("/signup", ac.Signup)
-		auth.POST("/signin", ac.Signin)
-		auth.POST("/logout", ac.Logout)
-		auth.POST("/logout-all", ac.LogoutAll)
-		auth.POST("/forgot-password", ac.ForgotPassword)
-		auth.POST("/reset-password", ac.ResetPassword)
-		auth.GET("/validate", ac.ValidateToken)
+		auth.POST("/signup", ac.limiter.Middleware("signup"), ac.Signup)
+		auth.POST("/signin", ac.limiter.Middleware("signin"), ac.Signin)
+		auth.POST("/signin/mfa", ac.limiter.Middleware("signin_mfa"), ac.SigninMFA)
+		auth.POST("/refresh", ac.limiter.Middleware("refresh"), ac.Refresh)
+		auth.POST("/logout", requireAuth, ac.Logout)
+		auth.POST("/logout-all", requireAuth, ac.LogoutAll)
+		auth.POST("/verify-email", ac.limiter.Middleware("verify_email"), ac.VerifyEmail)
+		auth.POST("/resend-verification", ac.limiter.Middleware("resend_verification"), ac.ResendVerification)
+		auth.POST("/forgot-password", ac.limiter.Middleware("forgot_password"), ac.ForgotPassword)
+		auth.POST("/reset-password", ac.limiter.Middleware("reset_password"), ac.ResetPassword)
+		auth.POST("/change-password", requireAuth, ac.limiter.Middleware("change_password"), ac.ChangePassword)
+		auth.POST("/recover-account", ac.limiter.Middleware("recover_account"), ac.RecoverAccount)
+		auth.GET("/recovery-codes", requireAuth, ac.RecoveryCodeStatus)
+		auth.POST("/recovery-codes/regenerate", requireAuth, ac.limiter.Middleware("recovery_codes"), ac.RegenerateRecoveryCodes)
+		auth.GET("/validate", ac.limiter.Middleware("validate"), requireAuth, ac.ValidateToken)
+		if ac.authService.LoginLinksEnabled() {
+			auth.POST("/login-link", ac.limiter.Middleware("login_link"), ac.RequestLoginLink)
+			auth.POST("/login-link/exchange", ac.limiter.Middleware("login_link_exchange"), ac.ExchangeLoginLink)
+		}
+	}
+}
+
+// retryAfterSeconds formats duration as Retry-After header value, rounded up to whole seconds
+func retryAfterSeconds(d time.Duration) string {
+	seconds := int64((d + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	return strconv.FormatInt(seconds, 10)
+}
+
+// respondThrottled writes 429 with Retry-After if err is a too many attempts error
+func respondThrottled(c *gin.Context, err error) bool {
+	var throttled *service.TooManyAttemptsError
+	if !errors.As(err, &throttled) {
+		return false
+	}
+	c.Header("Retry-After", retryAfterSeconds(throttled.RetryAfter))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error": err.Error(),
+	})
+	return true
+}
+
+// respondAuthError writes 429 with Retry-After if err is a too many attempts error, 401 otherwise
+func respondAuthError(c *gin.Context, err error) {
+	if respondThrottled(c, err) {
+		return
 	}
+	c.JSON(http.StatusUnauthorized, gin.H{
+		"error": err.Error(),
+	})
 }
 
+// respondPolicyViolations writes 400 with per-rule violations if err is a password policy error
+func respondPolicyViolations(c *gin.Context, err error) bool {
+	var policyErr *password.PolicyError
+	if !errors.As(err, &policyErr) {
+		return false
+	}
+	c.JSON(http.StatusBadRequest, gin.H{
+		"error":      "Password does not meet requirements",
+		"violations": policyErr.Violations,
+	})
+	return true
+}
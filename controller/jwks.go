@@ -0,0 +1,40 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/damonleelcx/go-gin-api/token"
+	"github.com/gin-gonic/gin"
+)
+
+// JWKSController publishes public signing keys
+type JWKSController struct {
+	keys *token.KeyManager
+}
+
+// NewJWKSController creates a new JWKS controller instance
+func NewJWKSController(keys *token.KeyManager) *JWKSController {
+	return &JWKSController{
+		keys: keys,
+	}
+}
+
+// JWKS public signing keys
+// @Summary JSON web key set
+// @Description Public keys for verifying access tokens and identity assertions, including the next and retiring keys
+// @Tags auth
+// @Produce json
+// @Success 200 {object} token.JWKS
+// @Router /.well-known/jwks.json [get]
+func (jc *JWKSController) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age="+strconv.Itoa(int(token.JWKSMaxAge/time.Second)))
+	c.JSON(http.StatusOK, jc.keys.JWKS())
+}
+
+// RegisterRoutes register routes
+// @Description Register well-known routes to Gin router
+func (jc *JWKSController) RegisterRoutes(router *gin.RouterGroup) {
+	router.GET("/.well-known/jwks.json", jc.JWKS)
+}
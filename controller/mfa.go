@@ -0,0 +1,137 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/damonleelcx/go-gin-api/middleware"
+	"github.com/damonleelcx/go-gin-api/ratelimit"
+	"github.com/damonleelcx/go-gin-api/service"
+	"github.com/gin-gonic/gin"
+)
+
+// MFAController two-factor authentication controller
+type MFAController struct {
+	authService *service.AuthService
+	limiter     *ratelimit.Limiter
+}
+
+// NewMFAController creates a new two-factor authentication controller instance
+func NewMFAController(authService *service.AuthService, limiter *ratelimit.Limiter) *MFAController {
+	return &MFAController{
+		authService: authService,
+		limiter:     limiter,
+	}
+}
+
+// EnrollTOTP start TOTP enrollment
+// @Summary Enroll TOTP
+// @Description Generate TOTP secret, otpauth URI and QR code for the current user
+// @Tags mfa
+// @Produce json
+// @Param Authorization header string true "Bearer Token"
+// @Success 200 {object} service.TOTPEnrollResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /auth/mfa/totp/enroll [post]
+func (mc *MFAController) EnrollTOTP(c *gin.Context) {
+	// Get user authenticated by middleware
+	user, _ := middleware.CurrentUser(c)
+
+	// Call service layer
+	response, err := mc.authService.EnrollTOTP(user.ID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// ConfirmTOTP confirm TOTP enrollment
+// @Summary Confirm TOTP
+// @Description Enable two-factor authentication with a code from the authenticator app
+// @Tags mfa
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer Token"
+// @Param request body service.ConfirmTOTPRequest true "Authentication code"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /auth/mfa/totp/confirm [post]
+func (mc *MFAController) ConfirmTOTP(c *gin.Context) {
+	var req service.ConfirmTOTPRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request parameters: " + err.Error(),
+		})
+		return
+	}
+
+	// Get user and session authenticated by middleware
+	user, _ := middleware.CurrentUser(c)
+	session, _ := middleware.CurrentSession(c)
+
+	// Call service layer
+	if err := mc.authService.ConfirmTOTP(user.ID, session.ID, &req, c.ClientIP(), c.GetHeader("User-Agent")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Two-factor authentication enabled",
+	})
+}
+
+// DisableTOTP disable TOTP
+// @Summary Disable TOTP
+// @Description Disable two-factor authentication, requires password and a current code
+// @Tags mfa
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer Token"
+// @Param request body service.DisableTOTPRequest true "Password and authentication code"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /auth/mfa/totp/disable [post]
+func (mc *MFAController) DisableTOTP(c *gin.Context) {
+	var req service.DisableTOTPRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request parameters: " + err.Error(),
+		})
+		return
+	}
+
+	// Get user and session authenticated by middleware
+	user, _ := middleware.CurrentUser(c)
+	session, _ := middleware.CurrentSession(c)
+
+	// Call service layer
+	if err := mc.authService.DisableTOTP(user.ID, session.ID, &req, c.ClientIP(), c.GetHeader("User-Agent")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Two-factor authentication disabled",
+	})
+}
+
+// RegisterRoutes register routes
+// @Description Register two-factor authentication routes to Gin router, all routes require authentication
+func (mc *MFAController) RegisterRoutes(router *gin.RouterGroup) {
+	totp := router.Group("/auth/mfa/totp", middleware.Auth(mc.authService), mc.limiter.Middleware("mfa"))
+	{
+		totp.POST("/enroll", mc.EnrollTOTP)
+		totp.POST("/confirm", mc.ConfirmTOTP)
+		totp.POST("/disable", mc.DisableTOTP)
+	}
+}
@@ -0,0 +1,240 @@
+package controller
+
+import (
+	"net/http"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/damonleelcx/go-gin-api/middleware"
+	"github.com/damonleelcx/go-gin-api/ratelimit"
+	"github.com/damonleelcx/go-gin-api/service"
+	"github.com/gin-gonic/gin"
+)
+
+// oauthBindingCookie cookie binding OAuth state to the browser that started the flow
+const oauthBindingCookie = "oauth_binding"
+
+// OAuthController social login controller
+type OAuthController struct {
+	authService  *service.AuthService
+	oauthService *service.OAuthService
+	limiter      *ratelimit.Limiter
+}
+
+// NewOAuthController creates a new social login controller instance
+func NewOAuthController(authService *service.AuthService, oauthService *service.OAuthService, limiter *ratelimit.Limiter) *OAuthController {
+	return &OAuthController{
+		authService:  authService,
+		oauthService: oauthService,
+		limiter:      limiter,
+	}
+}
+
+// Providers list social login providers
+// @Summary List OAuth providers
+// @Description List names of configured social login providers
+// @Tags oauth
+// @Produce json
+// @Success 200 {object} map[string][]string
+// @Router /auth/oauth/providers [get]
+func (oc *OAuthController) Providers(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"providers": oc.oauthService.Providers(),
+	})
+}
+
+// Login start social login
+// @Summary OAuth login
+// @Description Redirect to the login page of the provider
+// @Tags oauth
+// @Param provider path string true "Provider name"
+// @Success 302
+// @Failure 400 {object} map[string]string
+// @Router /auth/oauth/{provider}/login [get]
+func (oc *OAuthController) Login(c *gin.Context) {
+	// Call service layer
+	response, err := oc.oauthService.Authorize(c.Param("provider"), nil)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	oc.setBindingCookie(c, response.Binding)
+	c.Redirect(http.StatusFound, response.AuthorizationURL)
+}
+
+// Callback complete social login
+// @Summary OAuth callback
+// @Description Exchange authorization code returned by the provider for session token, or link the identity.
+// @Description Requires the binding cookie set when the flow started, linking also requires the bearer token of the same user.
+// @Tags oauth
+// @Produce json
+// @Param Authorization header string false "Bearer Token, required to link an identity"
+// @Param provider path string true "Provider name"
+// @Param code query string false "Authorization code"
+// @Param state query string true "State from the login redirect"
+// @Success 200 {object} service.OAuthCallbackResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Router /auth/oauth/{provider}/callback [get]
+func (oc *OAuthController) Callback(c *gin.Context) {
+	var req service.OAuthCallbackRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request parameters: " + err.Error(),
+		})
+		return
+	}
+
+	// The binding is single use, whatever the outcome
+	req.Binding, _ = c.Cookie(oauthBindingCookie)
+	oc.setBindingCookie(c, "")
+
+	// Get user authenticated by middleware, if any
+	var userID *uint
+	if user, ok := middleware.CurrentUser(c); ok {
+		userID = &user.ID
+	}
+
+	// Call service layer
+	response, err := oc.oauthService.Callback(c.Param("provider"), &req, userID, c.ClientIP(), c.GetHeader("User-Agent"))
+	if err != nil {
+		respondAuthError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// Link start linking of a provider identity
+// @Summary Link OAuth identity
+// @Description Get login page URL of the provider, the callback links the identity to the current user
+// @Tags oauth
+// @Produce json
+// @Param Authorization header string true "Bearer Token"
+// @Param provider path string true "Provider name"
+// @Success 200 {object} service.OAuthAuthorizeResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /auth/oauth/{provider}/link [post]
+func (oc *OAuthController) Link(c *gin.Context) {
+	// Get user authenticated by middleware
+	user, _ := middleware.CurrentUser(c)
+
+	// Call service layer
+	response, err := oc.oauthService.Authorize(c.Param("provider"), &user.ID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	oc.setBindingCookie(c, response.Binding)
+	c.JSON(http.StatusOK, response)
+}
+
+// ListIdentities list linked identities
+// @Summary List linked identities
+// @Description List provider identities linked to the current user
+// @Tags oauth
+// @Produce json
+// @Param Authorization header string true "Bearer Token"
+// @Success 200 {array} entity.UserIdentity
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /auth/oauth/identities [get]
+func (oc *OAuthController) ListIdentities(c *gin.Context) {
+	// Get user authenticated by middleware
+	user, _ := middleware.CurrentUser(c)
+
+	// Call service layer
+	identities, err := oc.oauthService.ListIdentities(user.ID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"identities": identities,
+	})
+}
+
+// UnlinkIdentity unlink identity
+// @Summary Unlink identity
+// @Description Remove provider identity of the current user
+// @Tags oauth
+// @Produce json
+// @Param Authorization header string true "Bearer Token"
+// @Param id path int true "Identity ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /auth/oauth/identities/{id} [delete]
+func (oc *OAuthController) UnlinkIdentity(c *gin.Context) {
+	identityID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid identity ID",
+		})
+		return
+	}
+
+	// Get user and session authenticated by middleware
+	user, _ := middleware.CurrentUser(c)
+	session, _ := middleware.CurrentSession(c)
+
+	// Call service layer
+	if err := oc.oauthService.UnlinkIdentity(user.ID, session.ID, uint(identityID), c.ClientIP(), c.GetHeader("User-Agent")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Identity unlinked",
+	})
+}
+
+// setBindingCookie stores binding of a started flow in the browser, limited to the routes of the provider.
+// Empty value removes the cookie.
+func (oc *OAuthController) setBindingCookie(c *gin.Context, value string) {
+	ttl, secure := oc.oauthService.BindingCookieOptions()
+	maxAge := int(ttl / time.Second)
+	if value == "" {
+		maxAge = -1
+	}
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     oauthBindingCookie,
+		Value:    value,
+		Path:     path.Dir(c.Request.URL.Path),
+		MaxAge:   maxAge,
+		Secure:   secure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// RegisterRoutes register routes
+// @Description Register social login routes to Gin router
+func (oc *OAuthController) RegisterRoutes(router *gin.RouterGroup) {
+	requireAuth := middleware.Auth(oc.authService)
+	optionalAuth := middleware.OptionalAuth(oc.authService)
+
+	oauth := router.Group("/auth/oauth")
+	{
+		oauth.GET("/providers", oc.Providers)
+		oauth.GET("/identities", requireAuth, oc.ListIdentities)
+		oauth.DELETE("/identities/:id", requireAuth, oc.UnlinkIdentity)
+		oauth.GET("/:provider/login", oc.limiter.Middleware("oauth"), oc.Login)
+		oauth.GET("/:provider/callback", optionalAuth, oc.limiter.Middleware("oauth"), oc.Callback)
+		oauth.POST("/:provider/link", requireAuth, oc.limiter.Middleware("oauth_link"), oc.Link)
+	}
+}
@@ -0,0 +1,128 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/damonleelcx/go-gin-api/middleware"
+	"github.com/damonleelcx/go-gin-api/service"
+	"github.com/gin-gonic/gin"
+)
+
+// SessionController session management controller
+type SessionController struct {
+	authService *service.AuthService
+}
+
+// NewSessionController creates a new session management controller instance
+func NewSessionController(authService *service.AuthService) *SessionController {
+	return &SessionController{
+		authService: authService,
+	}
+}
+
+// ListSessions list sessions
+// @Summary List sessions
+// @Description List active sessions of the current user, the session of the request is flagged as current
+// @Tags sessions
+// @Produce json
+// @Param Authorization header string true "Bearer Token"
+// @Success 200 {array} service.SessionInfo
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /auth/sessions [get]
+func (sc *SessionController) ListSessions(c *gin.Context) {
+	// Get user and session authenticated by middleware
+	user, _ := middleware.CurrentUser(c)
+	session, _ := middleware.CurrentSession(c)
+
+	// Call service layer
+	sessions, err := sc.authService.ListSessions(user.ID, session.ID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"sessions": sessions,
+	})
+}
+
+// RevokeSession revoke session
+// @Summary Revoke session
+// @Description Log out one session of the current user
+// @Tags sessions
+// @Produce json
+// @Param Authorization header string true "Bearer Token"
+// @Param id path int true "Session ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /auth/sessions/{id} [delete]
+func (sc *SessionController) RevokeSession(c *gin.Context) {
+	sessionID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid session ID",
+		})
+		return
+	}
+
+	// Get user and session authenticated by middleware
+	user, _ := middleware.CurrentUser(c)
+	session, _ := middleware.CurrentSession(c)
+
+	// Call service layer
+	if err := sc.authService.RevokeSession(user.ID, session.ID, uint(sessionID), c.ClientIP(), c.GetHeader("User-Agent")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Session revoked",
+	})
+}
+
+// RevokeOtherSessions revoke other sessions
+// @Summary Revoke other sessions
+// @Description Log out every session of the current user except the one making the request
+// @Tags sessions
+// @Produce json
+// @Param Authorization header string true "Bearer Token"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /auth/sessions/revoke-others [post]
+func (sc *SessionController) RevokeOtherSessions(c *gin.Context) {
+	// Get user and session authenticated by middleware
+	user, _ := middleware.CurrentUser(c)
+	session, _ := middleware.CurrentSession(c)
+
+	// Call service layer
+	if err := sc.authService.RevokeOtherSessions(user.ID, session.ID, c.ClientIP(), c.GetHeader("User-Agent")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Other sessions revoked",
+	})
+}
+
+// RegisterRoutes register routes
+// @Description Register session management routes to Gin router, all routes require authentication
+func (sc *SessionController) RegisterRoutes(router *gin.RouterGroup) {
+	sessions := router.Group("/auth/sessions", middleware.Auth(sc.authService))
+	{
+		sessions.GET("", sc.ListSessions)
+		sessions.DELETE("/:id", sc.RevokeSession)
+		sessions.POST("/revoke-others", sc.RevokeOtherSessions)
+	}
+}
@@ -0,0 +1,235 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/damonleelcx/go-gin-api/middleware"
+	"github.com/damonleelcx/go-gin-api/ratelimit"
+	"github.com/damonleelcx/go-gin-api/service"
+	"github.com/gin-gonic/gin"
+)
+
+// WebAuthnController passkey controller
+type WebAuthnController struct {
+	authService     *service.AuthService
+	webAuthnService *service.WebAuthnService
+	limiter         *ratelimit.Limiter
+}
+
+// NewWebAuthnController creates a new passkey controller instance
+func NewWebAuthnController(authService *service.AuthService, webAuthnService *service.WebAuthnService, limiter *ratelimit.Limiter) *WebAuthnController {
+	return &WebAuthnController{
+		authService:     authService,
+		webAuthnService: webAuthnService,
+		limiter:         limiter,
+	}
+}
+
+// RegisterOptions begin passkey registration
+// @Summary Passkey registration options
+// @Description Get options for navigator.credentials.create() to register a passkey for the current user
+// @Tags webauthn
+// @Produce json
+// @Param Authorization header string true "Bearer Token"
+// @Success 200 {object} service.WebAuthnOptionsResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /auth/webauthn/register/options [post]
+func (wc *WebAuthnController) RegisterOptions(c *gin.Context) {
+	// Get user authenticated by middleware
+	user, _ := middleware.CurrentUser(c)
+
+	// Call service layer
+	response, err := wc.webAuthnService.BeginRegistration(user.ID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// RegisterVerify finish passkey registration
+// @Summary Register passkey
+// @Description Verify authenticator response of navigator.credentials.create() and store the passkey
+// @Tags webauthn
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer Token"
+// @Param request body service.WebAuthnRegisterRequest true "Ceremony ID and credential"
+// @Success 200 {object} entity.WebAuthnCredential
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /auth/webauthn/register/verify [post]
+func (wc *WebAuthnController) RegisterVerify(c *gin.Context) {
+	var req service.WebAuthnRegisterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request parameters: " + err.Error(),
+		})
+		return
+	}
+
+	// Get user and session authenticated by middleware
+	user, _ := middleware.CurrentUser(c)
+	session, _ := middleware.CurrentSession(c)
+
+	// Call service layer
+	credential, err := wc.webAuthnService.FinishRegistration(user.ID, session.ID, &req, c.ClientIP(), c.GetHeader("User-Agent"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, credential)
+}
+
+// LoginOptions begin passkey login
+// @Summary Passkey login options
+// @Description Get options for navigator.credentials.get(), username is optional for discoverable passkeys
+// @Tags webauthn
+// @Accept json
+// @Produce json
+// @Param request body service.WebAuthnLoginOptionsRequest false "Optional username"
+// @Success 200 {object} service.WebAuthnOptionsResponse
+// @Failure 400 {object} map[string]string
+// @Router /auth/webauthn/login/options [post]
+func (wc *WebAuthnController) LoginOptions(c *gin.Context) {
+	var req service.WebAuthnLoginOptionsRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid request parameters: " + err.Error(),
+			})
+			return
+		}
+	}
+
+	// Call service layer
+	response, err := wc.webAuthnService.BeginLogin(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// LoginVerify finish passkey login
+// @Summary Passkey login
+// @Description Verify authenticator response of navigator.credentials.get() and get session token
+// @Tags webauthn
+// @Accept json
+// @Produce json
+// @Param request body service.WebAuthnLoginRequest true "Ceremony ID and credential"
+// @Success 200 {object} service.SigninResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Router /auth/webauthn/login/verify [post]
+func (wc *WebAuthnController) LoginVerify(c *gin.Context) {
+	var req service.WebAuthnLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request parameters: " + err.Error(),
+		})
+		return
+	}
+
+	// Call service layer
+	response, err := wc.webAuthnService.FinishLogin(&req, c.ClientIP(), c.GetHeader("User-Agent"))
+	if err != nil {
+		respondAuthError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// ListCredentials list passkeys
+// @Summary List passkeys
+// @Description List passkeys of the current user
+// @Tags webauthn
+// @Produce json
+// @Param Authorization header string true "Bearer Token"
+// @Success 200 {array} entity.WebAuthnCredential
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /auth/webauthn/credentials [get]
+func (wc *WebAuthnController) ListCredentials(c *gin.Context) {
+	// Get user authenticated by middleware
+	user, _ := middleware.CurrentUser(c)
+
+	// Call service layer
+	credentials, err := wc.webAuthnService.ListCredentials(user.ID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"credentials": credentials,
+	})
+}
+
+// DeleteCredential delete passkey
+// @Summary Delete passkey
+// @Description Remove a passkey of the current user
+// @Tags webauthn
+// @Produce json
+// @Param Authorization header string true "Bearer Token"
+// @Param id path int true "Credential ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /auth/webauthn/credentials/{id} [delete]
+func (wc *WebAuthnController) DeleteCredential(c *gin.Context) {
+	credentialID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid credential ID",
+		})
+		return
+	}
+
+	// Get user and session authenticated by middleware
+	user, _ := middleware.CurrentUser(c)
+	session, _ := middleware.CurrentSession(c)
+
+	// Call service layer
+	if err := wc.webAuthnService.DeleteCredential(user.ID, session.ID, uint(credentialID), c.ClientIP(), c.GetHeader("User-Agent")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Passkey removed",
+	})
+}
+
+// RegisterRoutes register routes
+// @Description Register passkey routes to Gin router
+func (wc *WebAuthnController) RegisterRoutes(router *gin.RouterGroup) {
+	requireAuth := middleware.Auth(wc.authService)
+
+	webAuthn := router.Group("/auth/webauthn")
+	{
+		webAuthn.POST("/register/options", requireAuth, wc.limiter.Middleware("mfa"), wc.RegisterOptions)
+		webAuthn.POST("/register/verify", requireAuth, wc.limiter.Middleware("mfa"), wc.RegisterVerify)
+		webAuthn.POST("/login/options", wc.limiter.Middleware("webauthn_login"), wc.LoginOptions)
+		webAuthn.POST("/login/verify", wc.limiter.Middleware("webauthn_login"), wc.LoginVerify)
+		webAuthn.GET("/credentials", requireAuth, wc.ListCredentials)
+		webAuthn.DELETE("/credentials/:id", requireAuth, wc.DeleteCredential)
+	}
+}
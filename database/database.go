@@ -0,0 +1,82 @@
+package database
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/damonleelcx/go-gin-api/config"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// Open opens a database connection using the configured driver and applies connection pool settings
+func Open(cfg config.DatabaseConfig) (*gorm.DB, error) {
+	var dialector gorm.Dialector
+	switch cfg.Driver {
+	case "sqlite":
+		dialector = sqlite.Open(sqliteDSN(cfg))
+	case "postgres":
+		dialector = postgres.Open(cfg.DSN)
+	case "mysql":
+		dialector = mysql.Open(cfg.DSN)
+	default:
+		return nil, errors.New("unsupported database driver: " + cfg.Driver)
+	}
+
+	db, err := gorm.Open(dialector, &gorm.Config{})
+	if err != nil {
+		return nil, err
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+
+	maxOpenConns := cfg.MaxOpenConns
+	if cfg.Driver == "sqlite" && isSQLiteMemory(cfg.DSN) {
+		// Every connection to an in-memory database gets its own empty database,
+		// so the pool must be pinned to a single connection
+		maxOpenConns = 1
+	}
+	sqlDB.SetMaxOpenConns(maxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime.Std())
+
+	return db, nil
+}
+
+// sqliteDSN appends journal mode, busy timeout and foreign key parameters to file-backed SQLite DSN
+func sqliteDSN(cfg config.DatabaseConfig) string {
+	if isSQLiteMemory(cfg.DSN) {
+		return cfg.DSN
+	}
+
+	var params []string
+	if cfg.SQLite.JournalMode != "" && !strings.Contains(cfg.DSN, "_journal_mode=") {
+		params = append(params, "_journal_mode="+cfg.SQLite.JournalMode)
+	}
+	if cfg.SQLite.BusyTimeout > 0 && !strings.Contains(cfg.DSN, "_busy_timeout=") {
+		params = append(params, "_busy_timeout="+strconv.FormatInt(cfg.SQLite.BusyTimeout.Std().Milliseconds(), 10))
+	}
+	if cfg.SQLite.ForeignKeys && !strings.Contains(cfg.DSN, "_foreign_keys=") {
+		params = append(params, "_foreign_keys=on")
+	}
+	if len(params) == 0 {
+		return cfg.DSN
+	}
+
+	separator := "?"
+	if strings.Contains(cfg.DSN, "?") {
+		separator = "&"
+	}
+	return cfg.DSN + separator + strings.Join(params, "&")
+}
+
+// isSQLiteMemory checks if DSN points to an in-memory SQLite database
+func isSQLiteMemory(dsn string) bool {
+	return strings.HasPrefix(dsn, ":memory:") || strings.Contains(dsn, "mode=memory")
+}
@@ -0,0 +1,22 @@
+package entity
+
+import (
+	"time"
+)
+
+// AuditEvent security relevant account event
+type AuditEvent struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`                   // Primary key ID
+	UserID    uint      `json:"user_id" gorm:"index;not null"`          // User ID
+	SessionID *uint     `json:"session_id"`                             // Session that performed the action, nil if none
+	Event     string    `json:"event" gorm:"not null;type:varchar(50)"` // Event type, e.g. password_changed
+	IPAddress string    `json:"ip_address" gorm:"type:varchar(45)"`     // Client IP address
+	UserAgent string    `json:"user_agent" gorm:"type:text"`            // User agent
+	Details   string    `json:"details" gorm:"type:text"`               // Free-form event details
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime;index"` // Created at
+}
+
+// TableName specifies table name
+func (AuditEvent) TableName() string {
+	return "audit_events"
+}
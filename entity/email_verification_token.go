@@ -0,0 +1,32 @@
+package entity
+
+import (
+	"time"
+)
+
+// EmailVerificationToken email verification token entity
+type EmailVerificationToken struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`                           // Primary key ID
+	UserID    uint      `json:"user_id" gorm:"not null;index"`                  // User ID, foreign key to User table
+	Email     string    `json:"email" gorm:"not null"`                          // Email address being verified
+	Token     string    `json:"-" gorm:"-"`                                     // Plain verification token, only set when issued and never stored
+	TokenHash string    `json:"-" gorm:"uniqueIndex;not null;type:varchar(64)"` // SHA-256 digest of verification token, unique index
+	ExpiresAt time.Time `json:"expires_at" gorm:"not null;index"`               // Expiration time, indexed
+	Used      bool      `json:"used" gorm:"default:false"`                      // Whether it has been used
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`               // Created at
+}
+
+// TableName specifies table name
+func (EmailVerificationToken) TableName() string {
+	return "email_verification_tokens"
+}
+
+// IsExpired checks if token has expired
+func (e *EmailVerificationToken) IsExpired() bool {
+	return time.Now().After(e.ExpiresAt)
+}
+
+// IsValid checks if token is valid (not used and not expired)
+func (e *EmailVerificationToken) IsValid() bool {
+	return !e.Used && !e.IsExpired()
+}
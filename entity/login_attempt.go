@@ -0,0 +1,26 @@
+package entity
+
+import (
+	"time"
+)
+
+// LoginAttempt failed login tracking entity, keyed by user ("user:<id>") or client IP ("ip:<address>")
+type LoginAttempt struct {
+	ID           uint       `json:"id" gorm:"primaryKey"`                                       // Primary key ID
+	TrackingKey  string     `json:"tracking_key" gorm:"uniqueIndex;not null;type:varchar(100)"` // Tracking key, unique index
+	Failures     int        `json:"failures" gorm:"not null;default:0"`                         // Consecutive failed attempts
+	LastFailedAt time.Time  `json:"last_failed_at" gorm:"not null"`                             // Time of last failed attempt
+	LockedUntil  *time.Time `json:"locked_until"`                                               // Lockout end time, nil if not locked
+	CreatedAt    time.Time  `json:"created_at" gorm:"autoCreateTime"`                           // Created at
+	UpdatedAt    time.Time  `json:"updated_at" gorm:"autoUpdateTime"`                           // Updated at
+}
+
+// TableName specifies table name
+func (LoginAttempt) TableName() string {
+	return "login_attempts"
+}
+
+// IsLocked checks if key is currently locked out
+func (l *LoginAttempt) IsLocked() bool {
+	return l.LockedUntil != nil && time.Now().Before(*l.LockedUntil)
+}
@@ -0,0 +1,32 @@
+package entity
+
+import (
+	"time"
+)
+
+// LoginToken passwordless login token entity, emailed as a link and a short code
+type LoginToken struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`                           // Primary key ID
+	UserID    uint      `json:"user_id" gorm:"not null;index"`                  // User ID, foreign key to User table
+	Email     string    `json:"email" gorm:"not null"`                          // Address the token was sent to
+	Token     string    `json:"-" gorm:"-"`                                     // Plain link token, only set when issued and never stored
+	TokenHash string    `json:"-" gorm:"uniqueIndex;not null;type:varchar(64)"` // SHA-256 digest of link token, unique index
+	Code      string    `json:"-" gorm:"-"`                                     // Plain 6-digit code, only set when issued and never stored
+	CodeHash  string    `json:"-" gorm:"not null;type:varchar(64)"`             // SHA-256 digest of code salted with the token digest
+	IPAddress string    `json:"ip_address" gorm:"type:varchar(45)"`             // Client IP that requested the token
+	UserAgent string    `json:"user_agent" gorm:"type:text"`                    // User agent that requested the token
+	Attempts  int       `json:"attempts" gorm:"default:0"`                      // Wrong codes submitted
+	ExpiresAt time.Time `json:"expires_at" gorm:"not null;index"`               // Expiration time, indexed
+	Used      bool      `json:"used" gorm:"default:false"`                      // Whether it has been used
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`               // Created at
+}
+
+// TableName specifies table name
+func (LoginToken) TableName() string {
+	return "login_tokens"
+}
+
+// IsExpired checks if token has expired
+func (l *LoginToken) IsExpired() bool {
+	return time.Now().After(l.ExpiresAt)
+}
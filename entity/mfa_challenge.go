@@ -0,0 +1,30 @@
+package entity
+
+import (
+	"time"
+)
+
+// MFAChallenge pending second factor of a signin whose password was verified
+type MFAChallenge struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`                           // Primary key ID
+	UserID     uint      `json:"user_id" gorm:"not null;index"`                  // User ID
+	Token      string    `json:"-" gorm:"-"`                                     // Plain challenge token returned by signin, never stored
+	TokenHash  string    `json:"-" gorm:"uniqueIndex;not null;type:varchar(64)"` // SHA-256 digest of challenge token, unique index
+	IPAddress  string    `json:"ip_address" gorm:"type:varchar(45)"`             // Client IP address of the signin
+	UserAgent  string    `json:"user_agent" gorm:"type:text"`                    // User agent of the signin
+	Attempts   int       `json:"attempts" gorm:"not null;default:0"`             // Failed code submissions
+	RememberMe bool      `json:"remember_me" gorm:"default:false"`               // Remember me flag of the signin, applied to the session
+	ExpiresAt  time.Time `json:"expires_at" gorm:"not null;index"`               // Expiration time
+	Used       bool      `json:"used" gorm:"default:false"`                      // Whether challenge has been completed
+	CreatedAt  time.Time `json:"created_at" gorm:"autoCreateTime"`               // Created at
+}
+
+// TableName specifies table name
+func (MFAChallenge) TableName() string {
+	return "mfa_challenges"
+}
+
+// IsExpired checks if challenge has expired
+func (m *MFAChallenge) IsExpired() bool {
+	return time.Now().After(m.ExpiresAt)
+}
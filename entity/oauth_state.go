@@ -0,0 +1,29 @@
+package entity
+
+import (
+	"time"
+)
+
+// OAuthState pending social login, created when the user is sent to the provider
+type OAuthState struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`                           // Primary key ID
+	State        string    `json:"-" gorm:"-"`                                     // Plain state parameter echoed by the provider, never stored
+	StateHash    string    `json:"-" gorm:"uniqueIndex;not null;type:varchar(64)"` // SHA-256 digest of state parameter, unique index
+	Provider     string    `json:"provider" gorm:"not null;type:varchar(50)"`      // Provider name
+	Nonce        string    `json:"-" gorm:"not null;type:varchar(255)"`            // Nonce expected in the ID token
+	CodeVerifier string    `json:"-" gorm:"not null;type:varchar(255)"`            // PKCE code verifier
+	BindingHash  string    `json:"-" gorm:"type:varchar(64)"`                      // SHA-256 digest of the binding cookie of the browser that started the flow
+	UserID       *uint     `json:"user_id" gorm:"index"`                           // Signed-in user linking the identity, nil for login
+	ExpiresAt    time.Time `json:"expires_at" gorm:"not null;index"`               // Expiration time, indexed
+	CreatedAt    time.Time `json:"created_at" gorm:"autoCreateTime"`               // Created at
+}
+
+// TableName specifies table name
+func (OAuthState) TableName() string {
+	return "oauth_states"
+}
+
+// IsExpired checks if state has expired
+func (o *OAuthState) IsExpired() bool {
+	return time.Now().After(o.ExpiresAt)
+}
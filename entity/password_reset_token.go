@@ -6,12 +6,13 @@ import (
 
 // PasswordResetToken password reset token entity
 type PasswordResetToken struct {
-	ID        uint      `json:"id" gorm:"primaryKey"`                                    // Primary key ID
-	UserID    uint      `json:"user_id" gorm:"not null;index"`                         // User ID, foreign key to User table
-	Token     string    `json:"token" gorm:"uniqueIndex;not null;type:varchar(255)"`    // Reset token, unique index
-	ExpiresAt time.Time `json:"expires_at" gorm:"not null;index"`                       // Expiration time, indexed
-	Used      bool      `json:"used" gorm:"default:false"`                              // Whether it has been used
-	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`                       // Created at
+	ID        uint      `json:"id" gorm:"primaryKey"`                           // Primary key ID
+	UserID    uint      `json:"user_id" gorm:"not null;index"`                  // User ID, foreign key to User table
+	Token     string    `json:"-" gorm:"-"`                                     // Plain reset token, only set when issued and never stored
+	TokenHash string    `json:"-" gorm:"uniqueIndex;not null;type:varchar(64)"` // SHA-256 digest of reset token, unique index
+	ExpiresAt time.Time `json:"expires_at" gorm:"not null;index"`               // Expiration time, indexed
+	Used      bool      `json:"used" gorm:"default:false"`                      // Whether it has been used
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`               // Created at
 }
 
 // TableName specifies table name
@@ -28,4 +29,3 @@ func (p *PasswordResetToken) IsExpired() bool {
 func (p *PasswordResetToken) IsValid() bool {
 	return !p.Used && !p.IsExpired()
 }
-
@@ -0,0 +1,19 @@
+package entity
+
+import (
+	"time"
+)
+
+// RecoveryCode single-use account recovery code, only its SHA-256 digest is stored
+type RecoveryCode struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`                     // Primary key ID
+	UserID    uint       `json:"user_id" gorm:"not null;index"`            // User ID
+	CodeHash  string     `json:"-" gorm:"not null;type:varchar(64);index"` // Hex SHA-256 digest of normalized code
+	UsedAt    *time.Time `json:"used_at"`                                  // Time code was redeemed, nil if unused
+	CreatedAt time.Time  `json:"created_at" gorm:"autoCreateTime"`         // Created at
+}
+
+// TableName specifies table name
+func (RecoveryCode) TableName() string {
+	return "recovery_codes"
+}
@@ -0,0 +1,33 @@
+package entity
+
+import (
+	"time"
+)
+
+// RefreshToken refresh token entity, all refresh tokens of a session form one token family
+type RefreshToken struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`                           // Primary key ID
+	SessionID uint       `json:"session_id" gorm:"not null;index"`               // Session ID, foreign key to Session table (token family)
+	UserID    uint       `json:"user_id" gorm:"not null;index"`                  // User ID, foreign key to User table
+	Token     string     `json:"-" gorm:"-"`                                     // Plain refresh token, only set when issued and never stored
+	TokenHash string     `json:"-" gorm:"uniqueIndex;not null;type:varchar(64)"` // SHA-256 digest of refresh token, unique index
+	ExpiresAt time.Time  `json:"expires_at" gorm:"not null;index"`               // Expiration time, indexed
+	UsedAt    *time.Time `json:"used_at"`                                        // Time the token was rotated, nil if unused
+	Revoked   bool       `json:"revoked" gorm:"default:false"`                   // Whether the token family has been revoked
+	CreatedAt time.Time  `json:"created_at" gorm:"autoCreateTime"`               // Created at
+}
+
+// TableName specifies table name
+func (RefreshToken) TableName() string {
+	return "refresh_tokens"
+}
+
+// IsExpired checks if token has expired
+func (r *RefreshToken) IsExpired() bool {
+	return time.Now().After(r.ExpiresAt)
+}
+
+// IsValid checks if token is valid (not used, not revoked and not expired)
+func (r *RefreshToken) IsValid() bool {
+	return r.UsedAt == nil && !r.Revoked && !r.IsExpired()
+}
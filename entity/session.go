@@ -6,18 +6,24 @@ import (
 
 // Session represents user session entity
 type Session struct {
-	ID        uint      `json:"id" gorm:"primaryKey"`                                  // Session ID
-	UserID    uint      `json:"user_id" gorm:"not null;index"`                        // User ID, foreign key to User table
-	Token     string    `json:"token" gorm:"uniqueIndex;not null;type:varchar(255)"`   // Session token, unique index
-	IPAddress string    `json:"ip_address" gorm:"type:varchar(45)"`                    // IP address (supports IPv6)
-	UserAgent string    `json:"user_agent" gorm:"type:varchar(500)"`                   // User agent information
-	Device    string    `json:"device" gorm:"type:varchar(50)"`                        // Device type: web, mobile, tablet
-	Platform  string    `json:"platform" gorm:"type:varchar(50)"`                      // Platform: windows, macos, linux, ios, android
-	Status    string    `json:"status" gorm:"type:varchar(20);default:'active'"`       // Status: active, expired, revoked
-	ExpiresAt time.Time `json:"expires_at" gorm:"not null;index"`                      // Expiration time, indexed
-	LastUsedAt *time.Time `json:"last_used_at" gorm:"index"`                          // Last used time, indexed
-	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`                      // Created at
-	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`                      // Updated at
+	ID              uint       `json:"id" gorm:"primaryKey"`                            // Session ID
+	UserID          uint       `json:"user_id" gorm:"not null;index"`                   // User ID, foreign key to User table
+	Token           string     `json:"-" gorm:"-"`                                      // Plain session token, only set when issued and never stored
+	TokenHash       string     `json:"-" gorm:"uniqueIndex;not null;type:varchar(64)"`  // SHA-256 digest of session token, unique index
+	JTIHash         string     `json:"-" gorm:"type:varchar(64)"`                       // SHA-256 digest of the ID claim of the current JWT access token
+	IPAddress       string     `json:"ip_address" gorm:"type:varchar(45)"`              // IP address (supports IPv6)
+	UserAgent       string     `json:"user_agent" gorm:"type:varchar(500)"`             // User agent information
+	Device          string     `json:"device" gorm:"type:varchar(50)"`                  // Device type: web, mobile, tablet, bot, other
+	Platform        string     `json:"platform" gorm:"type:varchar(50)"`                // Platform: windows, macos, linux, ios, android, chromeos
+	Browser         string     `json:"browser" gorm:"type:varchar(50)"`                 // Browser or client name parsed from user agent
+	BrowserVersion  string     `json:"browser_version" gorm:"type:varchar(20)"`         // Major and minor browser version
+	Status          string     `json:"status" gorm:"type:varchar(20);default:'active'"` // Status: active, expired, revoked
+	RememberMe      bool       `json:"remember_me" gorm:"default:false"`                // Whether session follows the longer remember me policy
+	ExpiresAt       time.Time  `json:"expires_at" gorm:"not null;index"`                // Expiration time, indexed
+	AccessExpiresAt time.Time  `json:"access_expires_at"`                               // Access token expiration time
+	LastUsedAt      *time.Time `json:"last_used_at" gorm:"index"`                       // Last used time, indexed
+	CreatedAt       time.Time  `json:"created_at" gorm:"autoCreateTime"`                // Created at
+	UpdatedAt       time.Time  `json:"updated_at" gorm:"autoUpdateTime"`                // Updated at
 }
 
 // TableName specifies table name
@@ -35,10 +41,14 @@ func (s *Session) IsActive() bool {
 	return s.Status == "active" && !s.IsExpired()
 }
 
+// IsAccessTokenExpired checks if current access token has expired
+func (s *Session) IsAccessTokenExpired() bool {
+	return time.Now().After(s.AccessExpiresAt)
+}
+
 // User associated user (if using GORM association feature)
 // func (s *Session) User() User {
 // 	var user User
 // 	// Can query through GORM association here
 // 	return user
 // }
-
@@ -6,19 +6,23 @@ import (
 
 // User represents user entity
 type User struct {
-	ID        uint      `json:"id" gorm:"primaryKey"`                    // User ID
-	Username  string    `json:"username" gorm:"uniqueIndex;not null"`    // Username, unique index
-	Email     string    `json:"email" gorm:"uniqueIndex;not null"`       // Email, unique index
-	Password  string    `json:"-" gorm:"not null"`                       // Password (not serialized to JSON)
-	FirstName string    `json:"first_name" gorm:"type:varchar(100)"`      // First name
-	LastName  string    `json:"last_name" gorm:"type:varchar(100)"`      // Last name
-	Phone     string    `json:"phone" gorm:"type:varchar(20)"`           // Phone number
-	Avatar    string    `json:"avatar" gorm:"type:varchar(255)"`         // Avatar URL
-	Status    string    `json:"status" gorm:"type:varchar(20);default:'active'"` // Status: active, inactive, banned
-	Role      string    `json:"role" gorm:"type:varchar(20);default:'user'"`     // Role: user, admin, moderator
-	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`        // Created at
-	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`        // Updated at
-	DeletedAt *time.Time `json:"deleted_at,omitempty" gorm:"index"`     // Soft delete time
+	ID              uint       `json:"id" gorm:"primaryKey"`                            // User ID
+	Username        string     `json:"username" gorm:"uniqueIndex;not null"`            // Username, unique index
+	Email           string     `json:"email" gorm:"uniqueIndex;not null"`               // Email, unique index
+	Password        string     `json:"-" gorm:"not null"`                               // Password (not serialized to JSON)
+	FirstName       string     `json:"first_name" gorm:"type:varchar(100)"`             // First name
+	LastName        string     `json:"last_name" gorm:"type:varchar(100)"`              // Last name
+	Phone           string     `json:"phone" gorm:"type:varchar(20)"`                   // Phone number
+	Avatar          string     `json:"avatar" gorm:"type:varchar(255)"`                 // Avatar URL
+	Status          string     `json:"status" gorm:"type:varchar(20);default:'active'"` // Status: active, pending_verification, inactive, banned
+	Role            string     `json:"role" gorm:"type:varchar(20);default:'user'"`     // Role: user, admin, moderator
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`                               // Email verification time, nil if unverified
+	TOTPSecret      string     `json:"-" gorm:"type:varchar(64)"`                       // Base32 TOTP secret, set on enrollment (not serialized to JSON)
+	TOTPEnabled     bool       `json:"totp_enabled" gorm:"default:false"`               // Whether TOTP two-factor authentication is confirmed and required
+	TOTPLastCounter int64      `json:"-" gorm:"default:0"`                              // Time step of last accepted code, earlier or equal codes are rejected as replays
+	CreatedAt       time.Time  `json:"created_at" gorm:"autoCreateTime"`                // Created at
+	UpdatedAt       time.Time  `json:"updated_at" gorm:"autoUpdateTime"`                // Updated at
+	DeletedAt       *time.Time `json:"deleted_at,omitempty" gorm:"index"`               // Soft delete time
 }
 
 // TableName specifies table name
@@ -31,4 +35,3 @@ func (User) TableName() string {
 // 	// Can add pre-creation logic here, such as password encryption, etc.
 // 	return nil
 // }
-
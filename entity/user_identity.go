@@ -0,0 +1,21 @@
+package entity
+
+import (
+	"time"
+)
+
+// UserIdentity account at an OAuth2 or OpenID Connect provider linked to a user
+type UserIdentity struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`                                                              // Primary key ID
+	UserID      uint       `json:"user_id" gorm:"not null;index"`                                                     // User ID, foreign key to User table
+	Provider    string     `json:"provider" gorm:"not null;type:varchar(50);uniqueIndex:idx_user_identities_subject"` // Provider name
+	Subject     string     `json:"subject" gorm:"not null;type:varchar(255);uniqueIndex:idx_user_identities_subject"` // User ID at the provider, unique per provider
+	Email       string     `json:"email" gorm:"type:varchar(255)"`                                                    // Email reported by the provider on last login
+	LastLoginAt *time.Time `json:"last_login_at"`                                                                     // Last login with this identity
+	CreatedAt   time.Time  `json:"created_at" gorm:"autoCreateTime"`                                                  // Created at
+}
+
+// TableName specifies table name
+func (UserIdentity) TableName() string {
+	return "user_identities"
+}
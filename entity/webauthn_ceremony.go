@@ -0,0 +1,27 @@
+package entity
+
+import (
+	"time"
+)
+
+// WebAuthnCeremony server side state of a pending passkey registration or login
+type WebAuthnCeremony struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`                           // Primary key ID
+	Token     string    `json:"-" gorm:"-"`                                     // Plain ceremony ID returned with the options, never stored
+	TokenHash string    `json:"-" gorm:"uniqueIndex;not null;type:varchar(64)"` // SHA-256 digest of ceremony ID, unique index
+	UserID    *uint     `json:"user_id" gorm:"index"`                           // User ID, nil for discoverable login
+	Kind      string    `json:"kind" gorm:"not null;type:varchar(20)"`          // Kind: registration, login
+	Data      string    `json:"-" gorm:"type:text;not null"`                    // JSON encoded WebAuthn session data including the challenge
+	ExpiresAt time.Time `json:"expires_at" gorm:"not null;index"`               // Expiration time
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`               // Created at
+}
+
+// TableName specifies table name
+func (WebAuthnCeremony) TableName() string {
+	return "webauthn_ceremonies"
+}
+
+// IsExpired checks if ceremony has expired
+func (w *WebAuthnCeremony) IsExpired() bool {
+	return time.Now().After(w.ExpiresAt)
+}
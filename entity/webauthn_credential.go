@@ -0,0 +1,30 @@
+package entity
+
+import (
+	"time"
+)
+
+// WebAuthnCredential passkey registered by a user
+type WebAuthnCredential struct {
+	ID              uint       `json:"id" gorm:"primaryKey"`                               // Primary key ID
+	UserID          uint       `json:"user_id" gorm:"not null;index"`                      // User ID
+	Name            string     `json:"name" gorm:"type:varchar(100)"`                      // User chosen label
+	CredentialID    []byte     `json:"credential_id" gorm:"uniqueIndex;not null;size:255"` // Credential ID assigned by the authenticator
+	PublicKey       []byte     `json:"-" gorm:"not null"`                                  // COSE encoded public key
+	AttestationType string     `json:"attestation_type" gorm:"type:varchar(32)"`           // Attestation format of registration
+	Transports      string     `json:"transports" gorm:"type:varchar(100)"`                // Comma separated transports, e.g. internal,hybrid
+	AAGUID          []byte     `json:"aaguid"`                                             // Authenticator model identifier
+	SignCount       uint32     `json:"sign_count" gorm:"not null;default:0"`               // Last signature counter reported by the authenticator
+	CloneWarning    bool       `json:"clone_warning" gorm:"default:false"`                 // Counter went backwards, the credential may be cloned
+	UserPresent     bool       `json:"-" gorm:"default:false"`                             // Authenticator flags of last ceremony
+	UserVerified    bool       `json:"-" gorm:"default:false"`
+	BackupEligible  bool       `json:"backup_eligible" gorm:"default:false"` // Credential can be synced between devices
+	BackupState     bool       `json:"backup_state" gorm:"default:false"`    // Credential is currently synced
+	LastUsedAt      *time.Time `json:"last_used_at"`                         // Last login with this credential
+	CreatedAt       time.Time  `json:"created_at" gorm:"autoCreateTime"`     // Created at
+}
+
+// TableName specifies table name
+func (WebAuthnCredential) TableName() string {
+	return "webauthn_credentials"
+}
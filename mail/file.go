@@ -0,0 +1,62 @@
+package mail
+
+import (
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// FileMailer writes every message as .eml file into a directory, for local development and tests
+type FileMailer struct {
+	dir     string
+	from    string
+	counter uint64
+}
+
+// NewFileMailer creates a new file mailer instance
+func NewFileMailer(dir, from string) (*FileMailer, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, errors.New("failed to create mail directory: " + err.Error())
+	}
+	return &FileMailer{
+		dir:  dir,
+		from: from,
+	}, nil
+}
+
+// Send deliver message
+func (m *FileMailer) Send(msg *Message) error {
+	data, err := encode(m.from, msg)
+	if err != nil {
+		return err
+	}
+
+	n := atomic.AddUint64(&m.counter, 1)
+	name := time.Now().Format("20060102-150405.000000") + "-" + strconv.FormatUint(n, 10) + ".eml"
+	if err := os.WriteFile(filepath.Join(m.dir, name), data, 0o600); err != nil {
+		return errors.New("failed to write email: " + err.Error())
+	}
+	return nil
+}
+
+// LogMailer writes messages to the application log, for local development
+type LogMailer struct {
+	from string
+}
+
+// NewLogMailer creates a new log mailer instance
+func NewLogMailer(from string) *LogMailer {
+	return &LogMailer{
+		from: from,
+	}
+}
+
+// Send deliver message
+func (m *LogMailer) Send(msg *Message) error {
+	log.Printf("email from %s to %s\nSubject: %s\n\n%s", m.from, msg.To, msg.Subject, msg.Text)
+	return nil
+}
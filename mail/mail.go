@@ -0,0 +1,35 @@
+package mail
+
+import (
+	"errors"
+
+	"github.com/damonleelcx/go-gin-api/config"
+)
+
+// Message email message
+type Message struct {
+	To      string // Recipient address
+	Subject string // Subject line
+	Text    string // Plain text body
+	HTML    string // HTML body, optional
+}
+
+// Mailer delivers email messages
+type Mailer interface {
+	// Send deliver message
+	Send(msg *Message) error
+}
+
+// New creates mailer selected by configuration
+func New(cfg config.MailConfig) (Mailer, error) {
+	switch cfg.Driver {
+	case "smtp":
+		return NewSMTPMailer(cfg.SMTP, cfg.From), nil
+	case "file":
+		return NewFileMailer(cfg.FileDir, cfg.From)
+	case "log":
+		return NewLogMailer(cfg.From), nil
+	default:
+		return nil, errors.New("unsupported mail driver: " + cfg.Driver)
+	}
+}
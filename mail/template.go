@@ -0,0 +1,78 @@
+package mail
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	htmltemplate "html/template"
+	"io/fs"
+	"path"
+	"strings"
+	texttemplate "text/template"
+)
+
+// Template files, each email has <name>.txt.tmpl defining "subject" and "text" blocks and
+// an optional <name>.html.tmpl with the HTML body
+//
+//go:embed templates/*.tmpl
+var templateFiles embed.FS
+
+// Templates are parsed one file at a time, since every text template defines the same block names
+var (
+	textTemplates = parseTextTemplates()
+	htmlTemplates = parseHTMLTemplates()
+)
+
+// parseTextTemplates parses subject and text templates by email name
+func parseTextTemplates() map[string]*texttemplate.Template {
+	files, _ := fs.Glob(templateFiles, "templates/*.txt.tmpl")
+	templates := make(map[string]*texttemplate.Template, len(files))
+	for _, file := range files {
+		name := strings.TrimSuffix(path.Base(file), ".txt.tmpl")
+		templates[name] = texttemplate.Must(texttemplate.ParseFS(templateFiles, file))
+	}
+	return templates
+}
+
+// parseHTMLTemplates parses HTML body templates by email name
+func parseHTMLTemplates() map[string]*htmltemplate.Template {
+	files, _ := fs.Glob(templateFiles, "templates/*.html.tmpl")
+	templates := make(map[string]*htmltemplate.Template, len(files))
+	for _, file := range files {
+		name := strings.TrimSuffix(path.Base(file), ".html.tmpl")
+		templates[name] = htmltemplate.Must(htmltemplate.ParseFS(templateFiles, file))
+	}
+	return templates
+}
+
+// Render renders named email template for recipient
+func Render(name, to string, data interface{}) (*Message, error) {
+	textTemplate, ok := textTemplates[name]
+	if !ok {
+		return nil, errors.New("unknown email template: " + name)
+	}
+
+	var subject, text bytes.Buffer
+	if err := textTemplate.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return nil, errors.New("failed to render email subject: " + err.Error())
+	}
+	if err := textTemplate.ExecuteTemplate(&text, "text", data); err != nil {
+		return nil, errors.New("failed to render email text: " + err.Error())
+	}
+
+	msg := &Message{
+		To:      to,
+		Subject: strings.TrimSpace(subject.String()),
+		Text:    strings.TrimSpace(text.String()) + "\n",
+	}
+
+	if htmlTemplate, ok := htmlTemplates[name]; ok {
+		var html bytes.Buffer
+		if err := htmlTemplate.Execute(&html, data); err != nil {
+			return nil, errors.New("failed to render email HTML: " + err.Error())
+		}
+		msg.HTML = html.String()
+	}
+
+	return msg, nil
+}
@@ -0,0 +1,92 @@
+package mail
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	// Values with markup and query separators show whether the HTML body escapes them
+	const (
+		username = "<b>ann</b>"
+		link     = "https://example.com/reset?token=abc123&next=%2Fhome"
+		code     = "042917"
+	)
+
+	tests := []struct {
+		name        string
+		subject     string
+		wantCode    bool
+		wantInText  []string
+		wantInHTML  []string
+		wantEscaped []string // Raw values that must not appear in the HTML body
+	}{
+		{
+			name:        "email_verification",
+			subject:     "Verify your email address",
+			wantInText:  []string{"Hello " + username, link, "ann@example.com", "24h0m0s"},
+			wantInHTML:  []string{"Hello &lt;b&gt;ann&lt;/b&gt;", `href="https://example.com/reset?token=abc123&amp;next=%2Fhome"`, "24h0m0s"},
+			wantEscaped: []string{username, link},
+		},
+		{
+			name:        "password_reset",
+			subject:     "Reset your password",
+			wantInText:  []string{"Hello " + username, link, "24h0m0s"},
+			wantInHTML:  []string{"Hello &lt;b&gt;ann&lt;/b&gt;", `href="https://example.com/reset?token=abc123&amp;next=%2Fhome"`, "24h0m0s"},
+			wantEscaped: []string{username, link},
+		},
+		{
+			name:        "login_link",
+			subject:     "Your sign-in link",
+			wantInText:  []string{"Hello " + username, link, "sign-in page: " + code, "24h0m0s"},
+			wantInHTML:  []string{"Hello &lt;b&gt;ann&lt;/b&gt;", `href="https://example.com/reset?token=abc123&amp;next=%2Fhome"`, "<strong>" + code + "</strong>", "24h0m0s"},
+			wantEscaped: []string{username, link},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := Render(tt.name, "ann@example.com", map[string]interface{}{
+				"Username":  username,
+				"Email":     "ann@example.com",
+				"Link":      link,
+				"Code":      code,
+				"ExpiresIn": "24h0m0s",
+			})
+			if err != nil {
+				t.Fatalf("Render() error: %v", err)
+			}
+			if msg.To != "ann@example.com" || msg.Subject != tt.subject {
+				t.Errorf("Render() to %q with subject %q, want ann@example.com and %q", msg.To, msg.Subject, tt.subject)
+			}
+			if !strings.HasSuffix(msg.Text, "\n") || strings.HasPrefix(msg.Text, "\n") {
+				t.Errorf("text body %q is not trimmed to a single trailing newline", msg.Text)
+			}
+			for _, want := range tt.wantInText {
+				if !strings.Contains(msg.Text, want) {
+					t.Errorf("text body does not contain %q:\n%s", want, msg.Text)
+				}
+			}
+			for _, want := range tt.wantInHTML {
+				if !strings.Contains(msg.HTML, want) {
+					t.Errorf("HTML body does not contain %q:\n%s", want, msg.HTML)
+				}
+			}
+			for _, raw := range tt.wantEscaped {
+				if strings.Contains(msg.HTML, raw) {
+					t.Errorf("HTML body contains unescaped %q:\n%s", raw, msg.HTML)
+				}
+			}
+		})
+	}
+
+	// Every template is covered above and has an HTML body
+	if len(textTemplates) != len(tests) || len(htmlTemplates) != len(tests) {
+		t.Errorf("parsed %d text and %d HTML templates, want %d of each", len(textTemplates), len(htmlTemplates), len(tests))
+	}
+}
+
+func TestRenderUnknownTemplate(t *testing.T) {
+	if _, err := Render("welcome", "ann@example.com", nil); err == nil || err.Error() != "unknown email template: welcome" {
+		t.Errorf("Render() error = %v, want unknown email template", err)
+	}
+}
@@ -0,0 +1,71 @@
+package migration
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Snapshots of the entities at the time of this migration. Migrations must not use
+// the entity package directly, since entities keep changing after the migration is written.
+
+type user0001 struct {
+	ID        uint       `gorm:"primaryKey"`
+	Username  string     `gorm:"uniqueIndex;not null"`
+	Email     string     `gorm:"uniqueIndex;not null"`
+	Password  string     `gorm:"not null"`
+	FirstName string     `gorm:"type:varchar(100)"`
+	LastName  string     `gorm:"type:varchar(100)"`
+	Phone     string     `gorm:"type:varchar(20)"`
+	Avatar    string     `gorm:"type:varchar(255)"`
+	Status    string     `gorm:"type:varchar(20);default:'active'"`
+	Role      string     `gorm:"type:varchar(20);default:'user'"`
+	CreatedAt time.Time  `gorm:"autoCreateTime"`
+	UpdatedAt time.Time  `gorm:"autoUpdateTime"`
+	DeletedAt *time.Time `gorm:"index"`
+}
+
+func (user0001) TableName() string { return "users" }
+
+type session0001 struct {
+	ID         uint       `gorm:"primaryKey"`
+	UserID     uint       `gorm:"not null;index"`
+	Token      string     `gorm:"uniqueIndex;not null;type:varchar(255)"`
+	IPAddress  string     `gorm:"type:varchar(45)"`
+	UserAgent  string     `gorm:"type:varchar(500)"`
+	Device     string     `gorm:"type:varchar(50)"`
+	Platform   string     `gorm:"type:varchar(50)"`
+	Status     string     `gorm:"type:varchar(20);default:'active'"`
+	ExpiresAt  time.Time  `gorm:"not null;index"`
+	LastUsedAt *time.Time `gorm:"index"`
+	CreatedAt  time.Time  `gorm:"autoCreateTime"`
+	UpdatedAt  time.Time  `gorm:"autoUpdateTime"`
+}
+
+func (session0001) TableName() string { return "sessions" }
+
+type passwordResetToken0001 struct {
+	ID        uint      `gorm:"primaryKey"`
+	UserID    uint      `gorm:"not null;index"`
+	Token     string    `gorm:"uniqueIndex;not null;type:varchar(255)"`
+	ExpiresAt time.Time `gorm:"not null;index"`
+	Used      bool      `gorm:"default:false"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+func (passwordResetToken0001) TableName() string { return "password_reset_tokens" }
+
+func init() {
+	register(Migration{
+		Version: 1,
+		Name:    "create_auth_tables",
+		Up: func(tx *gorm.DB) error {
+			// AutoMigrate instead of CreateTable so databases created by the former
+			// AutoMigrate startup code are adopted without errors
+			return tx.AutoMigrate(&user0001{}, &session0001{}, &passwordResetToken0001{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&passwordResetToken0001{}, &session0001{}, &user0001{})
+		},
+	})
+}
@@ -0,0 +1,49 @@
+package migration
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type session0002 struct {
+	AccessExpiresAt time.Time
+}
+
+func (session0002) TableName() string { return "sessions" }
+
+type refreshToken0002 struct {
+	ID        uint      `gorm:"primaryKey"`
+	SessionID uint      `gorm:"not null;index"`
+	UserID    uint      `gorm:"not null;index"`
+	Token     string    `gorm:"uniqueIndex;not null;type:varchar(255)"`
+	ExpiresAt time.Time `gorm:"not null;index"`
+	UsedAt    *time.Time
+	Revoked   bool      `gorm:"default:false"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+func (refreshToken0002) TableName() string { return "refresh_tokens" }
+
+func init() {
+	register(Migration{
+		Version: 2,
+		Name:    "add_refresh_tokens",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Migrator().AddColumn(&session0002{}, "AccessExpiresAt"); err != nil {
+				return err
+			}
+			// Existing sessions keep working until their original expiry
+			if err := tx.Exec("UPDATE sessions SET access_expires_at = expires_at").Error; err != nil {
+				return err
+			}
+			return tx.Migrator().CreateTable(&refreshToken0002{})
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropTable(&refreshToken0002{}); err != nil {
+				return err
+			}
+			return dropColumn(tx, &session0002{}, "AccessExpiresAt")
+		},
+	})
+}
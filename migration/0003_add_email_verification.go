@@ -0,0 +1,48 @@
+package migration
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type user0003 struct {
+	EmailVerifiedAt *time.Time
+}
+
+func (user0003) TableName() string { return "users" }
+
+type emailVerificationToken0003 struct {
+	ID        uint      `gorm:"primaryKey"`
+	UserID    uint      `gorm:"not null;index"`
+	Email     string    `gorm:"not null"`
+	Token     string    `gorm:"uniqueIndex;not null;type:varchar(255)"`
+	ExpiresAt time.Time `gorm:"not null;index"`
+	Used      bool      `gorm:"default:false"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+func (emailVerificationToken0003) TableName() string { return "email_verification_tokens" }
+
+func init() {
+	register(Migration{
+		Version: 3,
+		Name:    "add_email_verification",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Migrator().AddColumn(&user0003{}, "EmailVerifiedAt"); err != nil {
+				return err
+			}
+			// Accounts created before verification existed are treated as verified
+			if err := tx.Exec("UPDATE users SET email_verified_at = created_at").Error; err != nil {
+				return err
+			}
+			return tx.Migrator().CreateTable(&emailVerificationToken0003{})
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropTable(&emailVerificationToken0003{}); err != nil {
+				return err
+			}
+			return dropColumn(tx, &user0003{}, "EmailVerifiedAt")
+		},
+	})
+}
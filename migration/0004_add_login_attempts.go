@@ -0,0 +1,32 @@
+package migration
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type loginAttempt0004 struct {
+	ID           uint      `gorm:"primaryKey"`
+	TrackingKey  string    `gorm:"uniqueIndex;not null;type:varchar(100)"`
+	Failures     int       `gorm:"not null;default:0"`
+	LastFailedAt time.Time `gorm:"not null"`
+	LockedUntil  *time.Time
+	CreatedAt    time.Time `gorm:"autoCreateTime"`
+	UpdatedAt    time.Time `gorm:"autoUpdateTime"`
+}
+
+func (loginAttempt0004) TableName() string { return "login_attempts" }
+
+func init() {
+	register(Migration{
+		Version: 4,
+		Name:    "add_login_attempts",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&loginAttempt0004{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&loginAttempt0004{})
+		},
+	})
+}
@@ -0,0 +1,33 @@
+package migration
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type auditEvent0005 struct {
+	ID        uint `gorm:"primaryKey"`
+	UserID    uint `gorm:"index;not null"`
+	SessionID *uint
+	Event     string    `gorm:"not null;type:varchar(50)"`
+	IPAddress string    `gorm:"type:varchar(45)"`
+	UserAgent string    `gorm:"type:text"`
+	Details   string    `gorm:"type:text"`
+	CreatedAt time.Time `gorm:"autoCreateTime;index"`
+}
+
+func (auditEvent0005) TableName() string { return "audit_events" }
+
+func init() {
+	register(Migration{
+		Version: 5,
+		Name:    "add_audit_events",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&auditEvent0005{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&auditEvent0005{})
+		},
+	})
+}
@@ -0,0 +1,58 @@
+package migration
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type user0006 struct {
+	TOTPSecret      string `gorm:"type:varchar(64)"`
+	TOTPEnabled     bool   `gorm:"default:false"`
+	TOTPLastCounter int64  `gorm:"default:0"`
+}
+
+func (user0006) TableName() string { return "users" }
+
+type mfaChallenge0006 struct {
+	ID        uint      `gorm:"primaryKey"`
+	UserID    uint      `gorm:"not null;index"`
+	Token     string    `gorm:"uniqueIndex;not null;type:varchar(255)"`
+	IPAddress string    `gorm:"type:varchar(45)"`
+	UserAgent string    `gorm:"type:text"`
+	Attempts  int       `gorm:"not null;default:0"`
+	ExpiresAt time.Time `gorm:"not null;index"`
+	Used      bool      `gorm:"default:false"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+func (mfaChallenge0006) TableName() string { return "mfa_challenges" }
+
+// user0006Columns columns added to users
+var user0006Columns = []string{"TOTPSecret", "TOTPEnabled", "TOTPLastCounter"}
+
+func init() {
+	register(Migration{
+		Version: 6,
+		Name:    "add_totp",
+		Up: func(tx *gorm.DB) error {
+			for _, column := range user0006Columns {
+				if err := tx.Migrator().AddColumn(&user0006{}, column); err != nil {
+					return err
+				}
+			}
+			return tx.Migrator().CreateTable(&mfaChallenge0006{})
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropTable(&mfaChallenge0006{}); err != nil {
+				return err
+			}
+			for _, column := range user0006Columns {
+				if err := dropColumn(tx, &user0006{}, column); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+}
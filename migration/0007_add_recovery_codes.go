@@ -0,0 +1,30 @@
+package migration
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type recoveryCode0007 struct {
+	ID        uint   `gorm:"primaryKey"`
+	UserID    uint   `gorm:"not null;index"`
+	CodeHash  string `gorm:"not null;type:varchar(64);index"`
+	UsedAt    *time.Time
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+func (recoveryCode0007) TableName() string { return "recovery_codes" }
+
+func init() {
+	register(Migration{
+		Version: 7,
+		Name:    "add_recovery_codes",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&recoveryCode0007{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&recoveryCode0007{})
+		},
+	})
+}
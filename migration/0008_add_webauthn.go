@@ -0,0 +1,59 @@
+package migration
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type webAuthnCredential0008 struct {
+	ID              uint   `gorm:"primaryKey"`
+	UserID          uint   `gorm:"not null;index"`
+	Name            string `gorm:"type:varchar(100)"`
+	CredentialID    []byte `gorm:"uniqueIndex;not null;size:255"`
+	PublicKey       []byte `gorm:"not null"`
+	AttestationType string `gorm:"type:varchar(32)"`
+	Transports      string `gorm:"type:varchar(100)"`
+	AAGUID          []byte
+	SignCount       uint32 `gorm:"not null;default:0"`
+	CloneWarning    bool   `gorm:"default:false"`
+	UserPresent     bool   `gorm:"default:false"`
+	UserVerified    bool   `gorm:"default:false"`
+	BackupEligible  bool   `gorm:"default:false"`
+	BackupState     bool   `gorm:"default:false"`
+	LastUsedAt      *time.Time
+	CreatedAt       time.Time `gorm:"autoCreateTime"`
+}
+
+func (webAuthnCredential0008) TableName() string { return "webauthn_credentials" }
+
+type webAuthnCeremony0008 struct {
+	ID        uint      `gorm:"primaryKey"`
+	Token     string    `gorm:"uniqueIndex;not null;type:varchar(255)"`
+	UserID    *uint     `gorm:"index"`
+	Kind      string    `gorm:"not null;type:varchar(20)"`
+	Data      string    `gorm:"type:text;not null"`
+	ExpiresAt time.Time `gorm:"not null;index"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+func (webAuthnCeremony0008) TableName() string { return "webauthn_ceremonies" }
+
+func init() {
+	register(Migration{
+		Version: 8,
+		Name:    "add_webauthn",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Migrator().CreateTable(&webAuthnCredential0008{}); err != nil {
+				return err
+			}
+			return tx.Migrator().CreateTable(&webAuthnCeremony0008{})
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropTable(&webAuthnCeremony0008{}); err != nil {
+				return err
+			}
+			return tx.Migrator().DropTable(&webAuthnCredential0008{})
+		},
+	})
+}
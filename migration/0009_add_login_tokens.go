@@ -0,0 +1,36 @@
+package migration
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type loginToken0009 struct {
+	ID        uint      `gorm:"primaryKey"`
+	UserID    uint      `gorm:"not null;index"`
+	Email     string    `gorm:"not null"`
+	Token     string    `gorm:"uniqueIndex;not null;type:varchar(255)"`
+	Code      string    `gorm:"not null;type:varchar(6)"`
+	IPAddress string    `gorm:"type:varchar(45)"`
+	UserAgent string    `gorm:"type:text"`
+	Attempts  int       `gorm:"default:0"`
+	ExpiresAt time.Time `gorm:"not null;index"`
+	Used      bool      `gorm:"default:false"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+func (loginToken0009) TableName() string { return "login_tokens" }
+
+func init() {
+	register(Migration{
+		Version: 9,
+		Name:    "add_login_tokens",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&loginToken0009{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&loginToken0009{})
+		},
+	})
+}
@@ -0,0 +1,51 @@
+package migration
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type oauthState0010 struct {
+	ID           uint      `gorm:"primaryKey"`
+	State        string    `gorm:"uniqueIndex;not null;type:varchar(255)"`
+	Provider     string    `gorm:"not null;type:varchar(50)"`
+	Nonce        string    `gorm:"not null;type:varchar(255)"`
+	CodeVerifier string    `gorm:"not null;type:varchar(255)"`
+	UserID       *uint     `gorm:"index"`
+	ExpiresAt    time.Time `gorm:"not null;index"`
+	CreatedAt    time.Time `gorm:"autoCreateTime"`
+}
+
+func (oauthState0010) TableName() string { return "oauth_states" }
+
+type userIdentity0010 struct {
+	ID          uint   `gorm:"primaryKey"`
+	UserID      uint   `gorm:"not null;index"`
+	Provider    string `gorm:"not null;type:varchar(50);uniqueIndex:idx_user_identities_subject"`
+	Subject     string `gorm:"not null;type:varchar(255);uniqueIndex:idx_user_identities_subject"`
+	Email       string `gorm:"type:varchar(255)"`
+	LastLoginAt *time.Time
+	CreatedAt   time.Time `gorm:"autoCreateTime"`
+}
+
+func (userIdentity0010) TableName() string { return "user_identities" }
+
+func init() {
+	register(Migration{
+		Version: 10,
+		Name:    "add_oauth",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Migrator().CreateTable(&oauthState0010{}); err != nil {
+				return err
+			}
+			return tx.Migrator().CreateTable(&userIdentity0010{})
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropTable(&userIdentity0010{}); err != nil {
+				return err
+			}
+			return tx.Migrator().DropTable(&oauthState0010{})
+		},
+	})
+}
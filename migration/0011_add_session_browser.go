@@ -0,0 +1,38 @@
+package migration
+
+import (
+	"gorm.io/gorm"
+)
+
+type session0011 struct {
+	Browser        string `gorm:"type:varchar(50)"`
+	BrowserVersion string `gorm:"type:varchar(20)"`
+}
+
+func (session0011) TableName() string { return "sessions" }
+
+// session0011Columns columns added to sessions
+var session0011Columns = []string{"Browser", "BrowserVersion"}
+
+func init() {
+	register(Migration{
+		Version: 11,
+		Name:    "add_session_browser",
+		Up: func(tx *gorm.DB) error {
+			for _, column := range session0011Columns {
+				if err := tx.Migrator().AddColumn(&session0011{}, column); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			for _, column := range session0011Columns {
+				if err := dropColumn(tx, &session0011{}, column); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+}
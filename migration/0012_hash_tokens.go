@@ -0,0 +1,101 @@
+package migration
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+
+	"gorm.io/gorm"
+)
+
+// Token columns before and after the migration, applied to each of tokenTables0012. Columns are added
+// nullable, filled and then made NOT NULL, since a NOT NULL column without default cannot be added to
+// a table with rows.
+
+type plainToken0012 struct {
+	Token string `gorm:"type:varchar(255)"`
+}
+
+type requiredPlainToken0012 struct {
+	Token string `gorm:"uniqueIndex;not null;type:varchar(255)"`
+}
+
+type tokenHash0012 struct {
+	TokenHash string `gorm:"type:varchar(64)"`
+}
+
+type requiredTokenHash0012 struct {
+	TokenHash string `gorm:"uniqueIndex;not null;type:varchar(64)"`
+}
+
+// tokenTables0012 tables whose plain tokens are replaced by digests
+var tokenTables0012 = []string{"sessions", "password_reset_tokens"}
+
+// tokenRow0012 row read while hashing existing tokens
+type tokenRow0012 struct {
+	ID    uint
+	Token string
+}
+
+// hashToken0012 SHA-256 digest of token, must match token.Hash so existing tokens stay valid
+func hashToken0012(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(sum[:])
+}
+
+func init() {
+	register(Migration{
+		Version: 12,
+		Name:    "hash_tokens",
+		Up: func(tx *gorm.DB) error {
+			for _, table := range tokenTables0012 {
+				if err := tx.Table(table).Migrator().AddColumn(&tokenHash0012{}, "TokenHash"); err != nil {
+					return err
+				}
+
+				// Replace existing tokens with their digests, so issued sessions and reset links keep working
+				var rows []tokenRow0012
+				if err := tx.Table(table).Select("id", "token").Find(&rows).Error; err != nil {
+					return err
+				}
+				for _, row := range rows {
+					if err := tx.Table(table).Where("id = ?", row.ID).Update("token_hash", hashToken0012(row.Token)).Error; err != nil {
+						return err
+					}
+				}
+
+				if err := dropColumn(tx.Table(table), &plainToken0012{}, "Token"); err != nil {
+					return err
+				}
+				if err := alterColumn(tx.Table(table), &requiredTokenHash0012{}, "TokenHash"); err != nil {
+					return err
+				}
+				if err := tx.Table(table).Migrator().CreateIndex(&requiredTokenHash0012{}, "TokenHash"); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		// Plain tokens cannot be recovered from digests, so reverting signs out all sessions and
+		// invalidates pending reset links. Digests are kept as token values to satisfy the unique index.
+		Down: func(tx *gorm.DB) error {
+			for _, table := range tokenTables0012 {
+				if err := tx.Table(table).Migrator().AddColumn(&plainToken0012{}, "Token"); err != nil {
+					return err
+				}
+				if err := tx.Table(table).Where("1 = 1").Update("token", gorm.Expr("token_hash")).Error; err != nil {
+					return err
+				}
+				if err := dropColumn(tx.Table(table), &tokenHash0012{}, "TokenHash"); err != nil {
+					return err
+				}
+				if err := alterColumn(tx.Table(table), &requiredPlainToken0012{}, "Token"); err != nil {
+					return err
+				}
+				if err := tx.Table(table).Migrator().CreateIndex(&requiredPlainToken0012{}, "Token"); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+}
@@ -0,0 +1,36 @@
+package migration
+
+import (
+	"gorm.io/gorm"
+)
+
+type session0013 struct {
+	RememberMe bool `gorm:"default:false"`
+}
+
+func (session0013) TableName() string { return "sessions" }
+
+type mfaChallenge0013 struct {
+	RememberMe bool `gorm:"default:false"`
+}
+
+func (mfaChallenge0013) TableName() string { return "mfa_challenges" }
+
+func init() {
+	register(Migration{
+		Version: 13,
+		Name:    "add_remember_me",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Migrator().AddColumn(&session0013{}, "RememberMe"); err != nil {
+				return err
+			}
+			return tx.Migrator().AddColumn(&mfaChallenge0013{}, "RememberMe")
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := dropColumn(tx, &mfaChallenge0013{}, "RememberMe"); err != nil {
+				return err
+			}
+			return dropColumn(tx, &session0013{}, "RememberMe")
+		},
+	})
+}
@@ -0,0 +1,28 @@
+package migration
+
+import (
+	"gorm.io/gorm"
+)
+
+type session0014 struct {
+	JTIHash string `gorm:"type:varchar(64)"`
+}
+
+func (session0014) TableName() string { return "sessions" }
+
+func init() {
+	register(Migration{
+		Version: 14,
+		Name:    "add_session_jti",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Migrator().AddColumn(&session0014{}, "JTIHash"); err != nil {
+				return err
+			}
+			// JWTs issued so far carry the session token as ID claim, its digest keeps them valid until refreshed
+			return tx.Model(&session0014{}).Where("1 = 1").Update("jti_hash", gorm.Expr("token_hash")).Error
+		},
+		Down: func(tx *gorm.DB) error {
+			return dropColumn(tx, &session0014{}, "JTIHash")
+		},
+	})
+}
@@ -0,0 +1,158 @@
+package migration
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+
+	"gorm.io/gorm"
+)
+
+// Token and state columns before and after the migration, applied to each of hashedColumns0015.
+// Columns are added nullable, filled and then made NOT NULL like in migration 0012.
+
+type plainToken0015 struct {
+	Token string `gorm:"type:varchar(255)"`
+}
+
+type requiredPlainToken0015 struct {
+	Token string `gorm:"uniqueIndex;not null;type:varchar(255)"`
+}
+
+type tokenHash0015 struct {
+	TokenHash string `gorm:"type:varchar(64)"`
+}
+
+type requiredTokenHash0015 struct {
+	TokenHash string `gorm:"uniqueIndex;not null;type:varchar(64)"`
+}
+
+type plainState0015 struct {
+	State string `gorm:"type:varchar(255)"`
+}
+
+type requiredPlainState0015 struct {
+	State string `gorm:"uniqueIndex;not null;type:varchar(255)"`
+}
+
+type stateHash0015 struct {
+	StateHash string `gorm:"type:varchar(64)"`
+}
+
+type requiredStateHash0015 struct {
+	StateHash string `gorm:"uniqueIndex;not null;type:varchar(64)"`
+}
+
+// hashedColumn0015 column of table whose plain values are replaced by digests
+type hashedColumn0015 struct {
+	table         string
+	plainField    string
+	hashField     string
+	plainColumn   string
+	hashColumn    string
+	plain         interface{}
+	requiredPlain interface{}
+	hash          interface{}
+	requiredHash  interface{}
+}
+
+var tokenColumn0015 = hashedColumn0015{
+	plainField:    "Token",
+	hashField:     "TokenHash",
+	plainColumn:   "token",
+	hashColumn:    "token_hash",
+	plain:         &plainToken0015{},
+	requiredPlain: &requiredPlainToken0015{},
+	hash:          &tokenHash0015{},
+	requiredHash:  &requiredTokenHash0015{},
+}
+
+// hashedColumns0015 returns columns hashed by the migration
+func hashedColumns0015() []hashedColumn0015 {
+	var columns []hashedColumn0015
+	for _, table := range []string{"refresh_tokens", "email_verification_tokens", "login_tokens", "mfa_challenges", "webauthn_ceremonies"} {
+		column := tokenColumn0015
+		column.table = table
+		columns = append(columns, column)
+	}
+	return append(columns, hashedColumn0015{
+		table:         "oauth_states",
+		plainField:    "State",
+		hashField:     "StateHash",
+		plainColumn:   "state",
+		hashColumn:    "state_hash",
+		plain:         &plainState0015{},
+		requiredPlain: &requiredPlainState0015{},
+		hash:          &stateHash0015{},
+		requiredHash:  &requiredStateHash0015{},
+	})
+}
+
+// hashedRow0015 row read while hashing existing values
+type hashedRow0015 struct {
+	ID    uint
+	Value string
+}
+
+// hashToken0015 SHA-256 digest of token, must match token.Hash so existing tokens stay valid
+func hashToken0015(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(sum[:])
+}
+
+func init() {
+	register(Migration{
+		Version: 15,
+		Name:    "hash_remaining_tokens",
+		Up: func(tx *gorm.DB) error {
+			for _, column := range hashedColumns0015() {
+				if err := tx.Table(column.table).Migrator().AddColumn(column.hash, column.hashField); err != nil {
+					return err
+				}
+
+				// Replace existing values with their digests, so issued tokens keep working
+				var rows []hashedRow0015
+				if err := tx.Table(column.table).Select("id", column.plainColumn+" AS value").Find(&rows).Error; err != nil {
+					return err
+				}
+				for _, row := range rows {
+					if err := tx.Table(column.table).Where("id = ?", row.ID).Update(column.hashColumn, hashToken0015(row.Value)).Error; err != nil {
+						return err
+					}
+				}
+
+				if err := dropColumn(tx.Table(column.table), column.plain, column.plainField); err != nil {
+					return err
+				}
+				if err := alterColumn(tx.Table(column.table), column.requiredHash, column.hashField); err != nil {
+					return err
+				}
+				if err := tx.Table(column.table).Migrator().CreateIndex(column.requiredHash, column.hashField); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		// Plain values cannot be recovered from digests, so reverting invalidates refresh tokens and
+		// pending links, challenges and ceremonies. Digests are kept as values to satisfy the unique index.
+		Down: func(tx *gorm.DB) error {
+			for _, column := range hashedColumns0015() {
+				if err := tx.Table(column.table).Migrator().AddColumn(column.plain, column.plainField); err != nil {
+					return err
+				}
+				if err := tx.Table(column.table).Where("1 = 1").Update(column.plainColumn, gorm.Expr(column.hashColumn)).Error; err != nil {
+					return err
+				}
+				if err := dropColumn(tx.Table(column.table), column.hash, column.hashField); err != nil {
+					return err
+				}
+				if err := alterColumn(tx.Table(column.table), column.requiredPlain, column.plainField); err != nil {
+					return err
+				}
+				if err := tx.Table(column.table).Migrator().CreateIndex(column.requiredPlain, column.plainField); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+}
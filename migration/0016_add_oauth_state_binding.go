@@ -0,0 +1,25 @@
+package migration
+
+import (
+	"gorm.io/gorm"
+)
+
+type oauthState0016 struct {
+	BindingHash string `gorm:"type:varchar(64)"`
+}
+
+func (oauthState0016) TableName() string { return "oauth_states" }
+
+func init() {
+	register(Migration{
+		Version: 16,
+		Name:    "add_oauth_state_binding",
+		// Pending states have no binding and fail their callback, users simply start the login again
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().AddColumn(&oauthState0016{}, "BindingHash")
+		},
+		Down: func(tx *gorm.DB) error {
+			return dropColumn(tx, &oauthState0016{}, "BindingHash")
+		},
+	})
+}
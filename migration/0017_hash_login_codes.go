@@ -0,0 +1,86 @@
+package migration
+
+import (
+	"gorm.io/gorm"
+)
+
+// Code columns of login_tokens before and after the migration, added nullable, filled and then made
+// NOT NULL like in migration 0015
+
+type plainCode0017 struct {
+	Code string `gorm:"type:varchar(6)"`
+}
+
+func (plainCode0017) TableName() string { return "login_tokens" }
+
+type requiredPlainCode0017 struct {
+	Code string `gorm:"not null;type:varchar(6)"`
+}
+
+func (requiredPlainCode0017) TableName() string { return "login_tokens" }
+
+type codeHash0017 struct {
+	CodeHash string `gorm:"type:varchar(64)"`
+}
+
+func (codeHash0017) TableName() string { return "login_tokens" }
+
+type requiredCodeHash0017 struct {
+	CodeHash string `gorm:"not null;type:varchar(64)"`
+}
+
+func (requiredCodeHash0017) TableName() string { return "login_tokens" }
+
+// loginCodeRow0017 row read while hashing existing codes
+type loginCodeRow0017 struct {
+	ID        uint
+	TokenHash string
+	Code      string
+}
+
+// hashLoginCode0017 digest of code salted with the token digest, must match the login link service
+// so pending codes stay valid
+func hashLoginCode0017(tokenHash, code string) string {
+	return hashToken0015(tokenHash + ":" + code)
+}
+
+func init() {
+	register(Migration{
+		Version: 17,
+		Name:    "hash_login_codes",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Migrator().AddColumn(&codeHash0017{}, "CodeHash"); err != nil {
+				return err
+			}
+
+			var rows []loginCodeRow0017
+			if err := tx.Table("login_tokens").Select("id", "token_hash", "code").Find(&rows).Error; err != nil {
+				return err
+			}
+			for _, row := range rows {
+				if err := tx.Table("login_tokens").Where("id = ?", row.ID).
+					Update("code_hash", hashLoginCode0017(row.TokenHash, row.Code)).Error; err != nil {
+					return err
+				}
+			}
+
+			if err := dropColumn(tx, &plainCode0017{}, "Code"); err != nil {
+				return err
+			}
+			return alterColumn(tx, &requiredCodeHash0017{}, "CodeHash")
+		},
+		// Plain codes cannot be recovered from digests, so pending codes stop working; their links still do
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Migrator().AddColumn(&plainCode0017{}, "Code"); err != nil {
+				return err
+			}
+			if err := tx.Table("login_tokens").Where("1 = 1").Update("code", "").Error; err != nil {
+				return err
+			}
+			if err := dropColumn(tx, &codeHash0017{}, "CodeHash"); err != nil {
+				return err
+			}
+			return alterColumn(tx, &requiredPlainCode0017{}, "Code")
+		},
+	})
+}
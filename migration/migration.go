@@ -0,0 +1,92 @@
+package migration
+
+import (
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Migration versioned schema change
+type Migration struct {
+	Version uint                    // Unique, increasing version number
+	Name    string                  // Human readable name
+	Up      func(tx *gorm.DB) error // Apply schema change
+	Down    func(tx *gorm.DB) error // Revert schema change
+}
+
+// registry holds all known migrations, filled by init functions of migration files
+var registry []Migration
+
+// register adds migration to registry
+func register(m Migration) {
+	registry = append(registry, m)
+}
+
+// All returns all registered migrations ordered by version
+func All() []Migration {
+	migrations := make([]Migration, len(registry))
+	copy(migrations, registry)
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations
+}
+
+// dropColumn drops column of the table of model, keeping its other indexes on SQLite
+func dropColumn(tx *gorm.DB, model interface{}, column string) error {
+	return preserveIndexes(tx, model, column, func() error {
+		return tx.Migrator().DropColumn(model, column)
+	})
+}
+
+// alterColumn changes column of the table of model to its definition in model, keeping the
+// indexes of the table on SQLite
+func alterColumn(tx *gorm.DB, model interface{}, column string) error {
+	return preserveIndexes(tx, model, "", func() error {
+		return tx.Migrator().AlterColumn(model, column)
+	})
+}
+
+// preserveIndexes runs change to the table of model. SQLite drops and alters columns by rebuilding
+// the table, which loses its indexes, so indexes missing afterwards are recreated from their original
+// definitions, except those covering droppedColumn.
+func preserveIndexes(tx *gorm.DB, model interface{}, droppedColumn string, change func() error) error {
+	if tx.Dialector.Name() != "sqlite" {
+		return change()
+	}
+
+	stmt := &gorm.Statement{DB: tx}
+	if err := stmt.ParseWithSpecialTableName(model, tx.Statement.Table); err != nil {
+		return err
+	}
+	if field := stmt.Schema.LookUpField(droppedColumn); field != nil {
+		droppedColumn = field.DBName
+	}
+
+	var indexes []struct {
+		Name string
+		SQL  string
+	}
+	if err := tx.Raw("SELECT name, sql FROM sqlite_master WHERE type = 'index' AND tbl_name = ? AND sql IS NOT NULL", stmt.Table).
+		Scan(&indexes).Error; err != nil {
+		return err
+	}
+
+	if err := change(); err != nil {
+		return err
+	}
+
+	for _, index := range indexes {
+		if droppedColumn != "" && (strings.Contains(index.SQL, "`"+droppedColumn+"`") || strings.Contains(index.SQL, `"`+droppedColumn+`"`)) {
+			continue
+		}
+		if tx.Migrator().HasIndex(stmt.Table, index.Name) {
+			continue
+		}
+		if err := tx.Exec(index.SQL).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
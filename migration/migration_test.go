@@ -0,0 +1,204 @@
+package migration
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/damonleelcx/go-gin-api/config"
+	"github.com/damonleelcx/go-gin-api/database"
+	"gorm.io/gorm"
+)
+
+// openTestDB opens empty file-backed SQLite database in a temporary directory
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	cfg := config.Default().Database
+	cfg.DSN = filepath.Join(t.TempDir(), "test.db")
+	db, err := database.Open(cfg)
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+// schema returns columns and indexes of every application table, one line each
+func schema(t *testing.T, db *gorm.DB) []string {
+	t.Helper()
+
+	var tables []string
+	if err := db.Raw("SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT IN ('sqlite_sequence', 'schema_migrations')").
+		Scan(&tables).Error; err != nil {
+		t.Fatalf("list tables: %v", err)
+	}
+
+	var lines []string
+	for _, table := range tables {
+		var columns []struct {
+			Name    string
+			Type    string
+			NotNull bool
+			PK      int
+		}
+		if err := db.Raw("SELECT name, type, \"notnull\" AS not_null, pk FROM pragma_table_info(?)", table).Scan(&columns).Error; err != nil {
+			t.Fatalf("list columns of %s: %v", table, err)
+		}
+		for _, column := range columns {
+			lines = append(lines, fmt.Sprintf("%s.%s %s notnull=%v pk=%d", table, column.Name, column.Type, column.NotNull, column.PK))
+		}
+
+		var indexes []struct {
+			Name   string
+			Unique bool
+		}
+		if err := db.Raw("SELECT name, \"unique\" FROM pragma_index_list(?) WHERE origin = 'c'", table).Scan(&indexes).Error; err != nil {
+			t.Fatalf("list indexes of %s: %v", table, err)
+		}
+		for _, index := range indexes {
+			lines = append(lines, fmt.Sprintf("%s index %s unique=%v", table, index.Name, index.Unique))
+		}
+	}
+
+	sort.Strings(lines)
+	return lines
+}
+
+// schemaDiff returns lines only in got prefixed by +, and lines only in want prefixed by -
+func schemaDiff(got, want []string) string {
+	seen := map[string]int{}
+	for _, line := range want {
+		seen[line]++
+	}
+	var diff []string
+	for _, line := range got {
+		if seen[line] > 0 {
+			seen[line]--
+			continue
+		}
+		diff = append(diff, "+ "+line)
+	}
+	for _, line := range want {
+		if seen[line] > 0 {
+			seen[line]--
+			diff = append(diff, "- "+line)
+		}
+	}
+	return strings.Join(diff, "\n")
+}
+
+// TestDownUpChain reverts migrations one at a time and in every longer run, checking each time
+// that the schema matches a database migrated straight up to the same version
+func TestDownUpChain(t *testing.T) {
+	migrations := All()
+
+	// Reference schema of every version, built by applying the first n migrations to an empty database
+	want := make([][]string, len(migrations)+1)
+	for n := range want {
+		db := openTestDB(t)
+		migrator := &Migrator{db: db, migrations: migrations[:n]}
+		if _, err := migrator.Up(); err != nil {
+			t.Fatalf("up to version %d: %v", n, err)
+		}
+		want[n] = schema(t, db)
+	}
+
+	db := openTestDB(t)
+	migrator := NewMigrator(db)
+	if _, err := migrator.Up(); err != nil {
+		t.Fatalf("up: %v", err)
+	}
+
+	// Step down one migration at a time
+	for n := len(migrations) - 1; n >= 0; n-- {
+		if _, err := migrator.Down(1); err != nil {
+			t.Fatalf("down to version %d: %v", n, err)
+		}
+		if diff := schemaDiff(schema(t, db), want[n]); diff != "" {
+			t.Fatalf("schema after down to version %d differs:\n%s", n, diff)
+		}
+	}
+
+	// Revert every suffix of migrations and apply it again
+	for steps := 1; steps <= len(migrations); steps++ {
+		if _, err := migrator.Up(); err != nil {
+			t.Fatalf("up after reverting %d migrations: %v", steps, err)
+		}
+		if diff := schemaDiff(schema(t, db), want[len(migrations)]); diff != "" {
+			t.Fatalf("schema after reverting and reapplying %d migrations differs:\n%s", steps, diff)
+		}
+		if _, err := migrator.Down(steps); err != nil {
+			t.Fatalf("down %d migrations: %v", steps, err)
+		}
+		if diff := schemaDiff(schema(t, db), want[len(migrations)-steps]); diff != "" {
+			t.Fatalf("schema after down %d migrations differs:\n%s", steps, diff)
+		}
+	}
+}
+
+// TestHashTokensKeepsIssuedTokens checks that tokens stored in plain before migrations 0012, 0015 and 0017
+// are replaced by digests of the same value
+func TestHashTokensKeepsIssuedTokens(t *testing.T) {
+	migrations := All()
+	db := openTestDB(t)
+	if _, err := (&Migrator{db: db, migrations: migrations[:11]}).Up(); err != nil {
+		t.Fatalf("up to version 11: %v", err)
+	}
+
+	now := time.Now()
+	inserts := []struct {
+		table  string
+		column string
+		values map[string]interface{}
+	}{
+		{"sessions", "token", map[string]interface{}{"user_id": 1, "expires_at": now}},
+		{"password_reset_tokens", "token", map[string]interface{}{"user_id": 1, "expires_at": now}},
+		{"refresh_tokens", "token", map[string]interface{}{"session_id": 1, "user_id": 1, "expires_at": now}},
+		{"email_verification_tokens", "token", map[string]interface{}{"user_id": 1, "email": "leo@example.com", "expires_at": now}},
+		{"login_tokens", "token", map[string]interface{}{"user_id": 1, "email": "leo@example.com", "code": "123456", "expires_at": now}},
+		{"mfa_challenges", "token", map[string]interface{}{"user_id": 1, "expires_at": now}},
+		{"webauthn_ceremonies", "token", map[string]interface{}{"kind": "login", "data": "{}", "expires_at": now}},
+		{"oauth_states", "state", map[string]interface{}{"provider": "test", "nonce": "nonce", "code_verifier": "verifier", "expires_at": now}},
+	}
+	for _, insert := range inserts {
+		insert.values[insert.column] = "plain-" + insert.table
+		if err := db.Table(insert.table).Create(insert.values).Error; err != nil {
+			t.Fatalf("insert into %s: %v", insert.table, err)
+		}
+	}
+
+	if _, err := NewMigrator(db).Up(); err != nil {
+		t.Fatalf("up: %v", err)
+	}
+	for _, insert := range inserts {
+		var count int64
+		if err := db.Table(insert.table).Where(insert.column+"_hash = ?", hashToken0015("plain-"+insert.table)).Count(&count).Error; err != nil {
+			t.Fatalf("count %s: %v", insert.table, err)
+		}
+		if count != 1 {
+			t.Errorf("%s holds %d rows with the digest of the plain value, want 1", insert.table, count)
+		}
+		if db.Migrator().HasColumn(insert.table, insert.column) {
+			t.Errorf("%s still has plain column %s", insert.table, insert.column)
+		}
+	}
+
+	// Login codes are salted with the digest of their link token
+	var count int64
+	codeHash := hashLoginCode0017(hashToken0015("plain-login_tokens"), "123456")
+	if err := db.Table("login_tokens").Where("code_hash = ?", codeHash).Count(&count).Error; err != nil {
+		t.Fatalf("count login codes: %v", err)
+	}
+	if count != 1 || db.Migrator().HasColumn("login_tokens", "code") {
+		t.Errorf("login_tokens holds %d rows with the code digest and plain column %v, want 1 and no plain column",
+			count, db.Migrator().HasColumn("login_tokens", "code"))
+	}
+}
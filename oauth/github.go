@@ -0,0 +1,124 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/damonleelcx/go-gin-api/config"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/github"
+)
+
+// DefaultGitHubAPIURL GitHub REST API
+const DefaultGitHubAPIURL = "https://api.github.com"
+
+// GitHubProvider GitHub OAuth app provider, GitHub issues no ID token so the identity is read from its API
+type GitHubProvider struct {
+	oauth2 oauth2.Config
+	apiURL string
+	client *http.Client
+}
+
+// NewGitHubProvider creates GitHub provider, endpoints can be overridden for GitHub Enterprise
+func NewGitHubProvider(cfg config.OAuthProviderConfig, redirectURL string, client *http.Client) *GitHubProvider {
+	endpoint := github.Endpoint
+	if cfg.AuthURL != "" {
+		endpoint.AuthURL = cfg.AuthURL
+	}
+	if cfg.TokenURL != "" {
+		endpoint.TokenURL = cfg.TokenURL
+	}
+	apiURL := DefaultGitHubAPIURL
+	if cfg.APIURL != "" {
+		apiURL = strings.TrimSuffix(cfg.APIURL, "/")
+	}
+
+	return &GitHubProvider{
+		oauth2: oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			Endpoint:     endpoint,
+			RedirectURL:  redirectURL,
+			Scopes:       scopesOr(cfg.Scopes, "read:user", "user:email"),
+		},
+		apiURL: apiURL,
+		client: client,
+	}
+}
+
+// AuthCodeURL implements Provider, GitHub has no nonce parameter
+func (p *GitHubProvider) AuthCodeURL(state, nonce, verifier string) string {
+	return p.oauth2.AuthCodeURL(state, oauth2.S256ChallengeOption(verifier))
+}
+
+// Exchange implements Provider
+func (p *GitHubProvider) Exchange(ctx context.Context, code, verifier, nonce string) (*Identity, error) {
+	ctx = withClient(ctx, p.client)
+	token, err := p.oauth2.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return nil, errors.New("failed to exchange authorization code: " + err.Error())
+	}
+	client := p.oauth2.Client(ctx, token)
+
+	var user struct {
+		ID    int64  `json:"id"`
+		Login string `json:"login"`
+		Name  string `json:"name"`
+	}
+	if err := p.get(client, "/user", &user); err != nil {
+		return nil, err
+	}
+	if user.ID == 0 {
+		return nil, errors.New("GitHub returned no user ID")
+	}
+
+	// The profile email may be unverified or hidden, use the verified primary address instead
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	if err := p.get(client, "/user/emails", &emails); err != nil {
+		return nil, err
+	}
+
+	identity := &Identity{
+		Subject:  strconv.FormatInt(user.ID, 10),
+		Name:     user.Name,
+		Username: user.Login,
+	}
+	for _, email := range emails {
+		if email.Primary {
+			identity.Email = email.Email
+			identity.EmailVerified = email.Verified
+			break
+		}
+	}
+	return identity, nil
+}
+
+// get decodes JSON response of GitHub API path
+func (p *GitHubProvider) get(client *http.Client, path string, v interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, p.apiURL+path, nil)
+	if err != nil {
+		return errors.New("failed to create GitHub API request: " + err.Error())
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return errors.New("GitHub API request failed: " + err.Error())
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errors.New("GitHub API returned " + resp.Status + " for " + path)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return errors.New("invalid GitHub API response: " + err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/damonleelcx/go-gin-api/config"
+	"golang.org/x/oauth2"
+)
+
+// GoogleIssuerURL issuer of Google ID tokens
+const GoogleIssuerURL = "https://accounts.google.com"
+
+// OIDCProvider OpenID Connect provider, identities are taken from the verified ID token
+type OIDCProvider struct {
+	oauth2   oauth2.Config
+	verifier *oidc.IDTokenVerifier
+	client   *http.Client
+}
+
+// NewOIDCProvider discovers endpoints and signing keys of issuer and creates provider
+func NewOIDCProvider(ctx context.Context, cfg config.OAuthProviderConfig, redirectURL string, client *http.Client) (*OIDCProvider, error) {
+	provider, err := oidc.NewProvider(withClient(ctx, client), cfg.IssuerURL)
+	if err != nil {
+		return nil, errors.New("OpenID Connect discovery failed: " + err.Error())
+	}
+
+	endpoint := provider.Endpoint()
+	if cfg.AuthURL != "" {
+		endpoint.AuthURL = cfg.AuthURL
+	}
+	if cfg.TokenURL != "" {
+		endpoint.TokenURL = cfg.TokenURL
+	}
+
+	return &OIDCProvider{
+		oauth2: oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			Endpoint:     endpoint,
+			RedirectURL:  redirectURL,
+			Scopes:       scopesOr(cfg.Scopes, oidc.ScopeOpenID, "email", "profile"),
+		},
+		// Keys are fetched lazily and refreshed on unknown key IDs with the same client
+		verifier: provider.VerifierContext(withClient(context.Background(), client), &oidc.Config{ClientID: cfg.ClientID}),
+		client:   client,
+	}, nil
+}
+
+// AuthCodeURL implements Provider
+func (p *OIDCProvider) AuthCodeURL(state, nonce, verifier string) string {
+	return p.oauth2.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier))
+}
+
+// Exchange implements Provider, the ID token must be signed by the issuer for this client and carry nonce
+func (p *OIDCProvider) Exchange(ctx context.Context, code, verifier, nonce string) (*Identity, error) {
+	token, err := p.oauth2.Exchange(withClient(ctx, p.client), code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return nil, errors.New("failed to exchange authorization code: " + err.Error())
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		return nil, errors.New("provider returned no ID token")
+	}
+	idToken, err := p.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, errors.New("invalid ID token: " + err.Error())
+	}
+	if idToken.Nonce != nonce {
+		return nil, errors.New("invalid ID token: nonce mismatch")
+	}
+
+	var claims struct {
+		Email             string `json:"email"`
+		EmailVerified     bool   `json:"email_verified"`
+		Name              string `json:"name"`
+		PreferredUsername string `json:"preferred_username"`
+	}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, errors.New("invalid ID token claims: " + err.Error())
+	}
+
+	return &Identity{
+		Subject:       idToken.Subject,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified,
+		Name:          claims.Name,
+		Username:      claims.PreferredUsername,
+	}, nil
+}
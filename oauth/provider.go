@@ -0,0 +1,77 @@
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/damonleelcx/go-gin-api/config"
+	"golang.org/x/oauth2"
+)
+
+// Identity user identity verified by a provider
+type Identity struct {
+	Subject       string // Stable user ID at the provider
+	Email         string // Email address, may be empty
+	EmailVerified bool   // Whether the provider verified the email address
+	Name          string // Display name
+	Username      string // Preferred username, may be empty
+}
+
+// Provider OAuth2 login provider using the authorization code flow with PKCE
+type Provider interface {
+	// AuthCodeURL returns URL of the provider's login page, state and nonce are echoed back,
+	// verifier is sent as S256 code challenge
+	AuthCodeURL(state, nonce, verifier string) string
+	// Exchange redeems authorization code and returns the identity it was issued for
+	Exchange(ctx context.Context, code, verifier, nonce string) (*Identity, error)
+}
+
+// NewProvider creates provider of configured type, redirectURL is the callback route registered at the provider.
+// OpenID Connect providers are discovered from their issuer, so this makes a request for google and oidc.
+func NewProvider(ctx context.Context, cfg config.OAuthProviderConfig, redirectURL string, timeout time.Duration) (Provider, error) {
+	client := &http.Client{Timeout: timeout}
+	switch cfg.Type {
+	case "google":
+		if cfg.IssuerURL == "" {
+			cfg.IssuerURL = GoogleIssuerURL
+		}
+		return NewOIDCProvider(ctx, cfg, redirectURL, client)
+	case "oidc":
+		return NewOIDCProvider(ctx, cfg, redirectURL, client)
+	case "github":
+		return NewGitHubProvider(cfg, redirectURL, client), nil
+	default:
+		return nil, errors.New("unknown OAuth provider type: " + cfg.Type)
+	}
+}
+
+// GenerateVerifier returns random PKCE code verifier
+func GenerateVerifier() string {
+	return oauth2.GenerateVerifier()
+}
+
+// GenerateNonce returns random nonce binding an ID token to the login that requested it
+func GenerateNonce() (string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(bytes), nil
+}
+
+// withClient returns context making oauth2 requests with client
+func withClient(ctx context.Context, client *http.Client) context.Context {
+	return context.WithValue(ctx, oauth2.HTTPClient, client)
+}
+
+// scopesOr returns configured scopes or defaults
+func scopesOr(scopes []string, defaults ...string) []string {
+	if len(scopes) > 0 {
+		return scopes
+	}
+	return defaults
+}
@@ -0,0 +1,80 @@
+package password
+
+import (
+	"crypto/subtle"
+	"errors"
+	"strconv"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Argon2idHasher Argon2id password hasher, encoded as "$argon2id$v=19$m=19456,t=2,p=1$salt$hash"
+type Argon2idHasher struct {
+	Memory      uint32 // Memory in KiB
+	Iterations  uint32
+	Parallelism uint8
+}
+
+// Argon2id salt and derived key lengths in bytes
+const (
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+)
+
+// Hash implements Hasher
+func (a *Argon2idHasher) Hash(password string) (string, error) {
+	salt, err := randomSalt(argon2SaltLength)
+	if err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, a.Iterations, a.Memory, a.Parallelism, argon2KeyLength)
+	params := []string{
+		"m=" + strconv.FormatUint(uint64(a.Memory), 10),
+		"t=" + strconv.FormatUint(uint64(a.Iterations), 10),
+		"p=" + strconv.FormatUint(uint64(a.Parallelism), 10),
+	}
+	return encodePHC("argon2id", strconv.Itoa(argon2.Version), params, salt, key), nil
+}
+
+// Verify implements Hasher
+func (a *Argon2idHasher) Verify(password, encoded string) (bool, error) {
+	parsed, memory, iterations, parallelism, err := decodeArgon2id(encoded)
+	if err != nil {
+		return false, err
+	}
+	key := argon2.IDKey([]byte(password), parsed.salt, iterations, memory, parallelism, uint32(len(parsed.hash)))
+	return subtle.ConstantTimeCompare(key, parsed.hash) == 1, nil
+}
+
+// NeedsRehash implements Hasher
+func (a *Argon2idHasher) NeedsRehash(encoded string) bool {
+	_, memory, iterations, parallelism, err := decodeArgon2id(encoded)
+	return err != nil || memory != a.Memory || iterations != a.Iterations || parallelism != a.Parallelism
+}
+
+// decodeArgon2id parses Argon2id PHC string and its parameters
+func decodeArgon2id(encoded string) (parsed *phcHash, memory, iterations uint32, parallelism uint8, err error) {
+	if parsed, err = decodePHC(encoded, "argon2id"); err != nil {
+		return
+	}
+	if parsed.version != strconv.Itoa(argon2.Version) {
+		err = errors.New("unsupported argon2id version " + parsed.version)
+		return
+	}
+	m, err := strconv.ParseUint(parsed.params["m"], 10, 32)
+	if err != nil {
+		err = errors.New("invalid argon2id hash parameter m")
+		return
+	}
+	t, err := strconv.ParseUint(parsed.params["t"], 10, 32)
+	if err != nil || t == 0 {
+		err = errors.New("invalid argon2id hash parameter t")
+		return
+	}
+	p, err := strconv.ParseUint(parsed.params["p"], 10, 8)
+	if err != nil || p == 0 {
+		err = errors.New("invalid argon2id hash parameter p")
+		return
+	}
+	return parsed, uint32(m), uint32(t), uint8(p), nil
+}
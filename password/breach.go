@@ -0,0 +1,61 @@
+package password
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/damonleelcx/go-gin-api/config"
+)
+
+// BreachChecker reports how often a password appears in known breach corpora
+type BreachChecker interface {
+	// Breached returns the number of times password was seen, 0 if never
+	Breached(password string) (int, error)
+}
+
+// NewBreachChecker creates checkers enabled by configuration, nil when breach checking is off
+func NewBreachChecker(cfg config.BreachCheckConfig) (BreachChecker, error) {
+	var checkers multiChecker
+
+	if cfg.File != "" {
+		checker, err := OpenFileChecker(cfg.File, cfg.IndexFile)
+		if err != nil {
+			return nil, err
+		}
+		checkers = append(checkers, checker)
+	}
+	if cfg.APIEnabled {
+		checkers = append(checkers, NewRangeClient(cfg.APIURL, time.Duration(cfg.APITimeout)))
+	}
+
+	switch len(checkers) {
+	case 0:
+		return nil, nil
+	case 1:
+		return checkers[0], nil
+	default:
+		return checkers, nil
+	}
+}
+
+// multiChecker consults checkers in order, returning the first positive count
+type multiChecker []BreachChecker
+
+// Breached implements BreachChecker
+func (m multiChecker) Breached(password string) (int, error) {
+	for _, checker := range m {
+		count, err := checker.Breached(password)
+		if err != nil || count > 0 {
+			return count, err
+		}
+	}
+	return 0, nil
+}
+
+// hashPassword returns the upper-case hex SHA-1 digest used by breach corpora
+func hashPassword(password string) string {
+	sum := sha1.Sum([]byte(password))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
@@ -0,0 +1,68 @@
+package password
+
+import (
+	"bufio"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultRangeAPIURL Have I Been Pwned range API, the 5 character hash prefix is appended
+const DefaultRangeAPIURL = "https://api.pwnedpasswords.com/range/"
+
+// RangeClient checks passwords with a k-anonymity range API: only the first 5 hex digits of the
+// password's SHA-1 hash are sent, and the matching suffixes are compared locally
+type RangeClient struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewRangeClient creates a new range API client, baseURL defaults to DefaultRangeAPIURL
+func NewRangeClient(baseURL string, timeout time.Duration) *RangeClient {
+	if baseURL == "" {
+		baseURL = DefaultRangeAPIURL
+	}
+	return &RangeClient{
+		baseURL: baseURL,
+		client:  &http.Client{Timeout: timeout},
+	}
+}
+
+// Breached implements BreachChecker
+func (r *RangeClient) Breached(password string) (int, error) {
+	hash := hashPassword(password)
+	prefix, suffix := hash[:prefixLength], hash[prefixLength:]
+
+	req, err := http.NewRequest(http.MethodGet, r.baseURL+prefix, nil)
+	if err != nil {
+		return 0, errors.New("failed to create range API request: " + err.Error())
+	}
+	// Padding hides the real number of matches from network observers
+	req.Header.Set("Add-Padding", "true")
+	req.Header.Set("User-Agent", "go-gin-api")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return 0, errors.New("range API request failed: " + err.Error())
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, errors.New("range API returned " + resp.Status)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		lineSuffix, count, found := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if !found || !strings.EqualFold(lineSuffix, suffix) {
+			continue
+		}
+		// Padding entries have count 0
+		return strconv.Atoi(count)
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, errors.New("failed to read range API response: " + err.Error())
+	}
+	return 0, nil
+}
@@ -0,0 +1,90 @@
+package password
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// paddedRange returns range API response with count for suffix between padding entries, which have
+// count 0, the way the API answers with Add-Padding
+func paddedRange(suffix string, count int) string {
+	lines := []string{
+		"0018A45C4D1DEF81644B54AB7F969B88D65:0",
+		"00D4F6E8FA6EECAD2A3AA415EEC418D38EC:2",
+		"011053FD0102E94D6AE2F8B83D76FAF94F6:0",
+	}
+	if suffix != "" {
+		lines = append(lines, fmt.Sprintf("%s:%d", suffix, count))
+	}
+	lines = append(lines, "FFFF1C1E5E15C16F8A9BB9D7E3A8D5D7E22:0", "FFFFF0D1D9C4E4F7B4B2AF0BA4E2D5EBC1C:1")
+	return strings.Join(lines, "\r\n") + "\r\n"
+}
+
+func TestRangeClient(t *testing.T) {
+	hash := hashPassword("password")
+	prefix, suffix := hash[:prefixLength], hash[prefixLength:]
+
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		want    int
+		wantErr bool
+	}{
+		{name: "breached", status: http.StatusOK, body: paddedRange(suffix, 9545824), want: 9545824},
+		{name: "lower-case suffix", status: http.StatusOK, body: paddedRange(strings.ToLower(suffix), 3), want: 3},
+		{name: "not breached", status: http.StatusOK, body: paddedRange("", 0), want: 0},
+		{name: "padding entry of the suffix", status: http.StatusOK, body: paddedRange(suffix, 0), want: 0},
+		{name: "LF line endings", status: http.StatusOK, body: strings.ReplaceAll(paddedRange(suffix, 7), "\r\n", "\n"), want: 7},
+		{name: "empty response", status: http.StatusOK, body: "", want: 0},
+		{name: "invalid count", status: http.StatusOK, body: paddedRange(suffix, 1) + suffix + ":many\r\n", want: 1},
+		{name: "malformed count of the suffix", status: http.StatusOK, body: suffix + ":many\r\n", wantErr: true},
+		{name: "rate limited", status: http.StatusTooManyRequests, wantErr: true},
+		{name: "server error", status: http.StatusServiceUnavailable, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				// Only the hash prefix leaves the process, and padding is requested
+				if r.URL.Path != "/range/"+prefix {
+					t.Errorf("request path %s, want /range/%s", r.URL.Path, prefix)
+				}
+				if r.Header.Get("Add-Padding") != "true" {
+					t.Error("request does not ask for padding")
+				}
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, tt.body)
+			}))
+			defer server.Close()
+
+			got, err := NewRangeClient(server.URL+"/range/", time.Second).Breached("password")
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Breached() = %d, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Breached() error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Breached() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRangeClientTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	if _, err := NewRangeClient(server.URL+"/range/", 50*time.Millisecond).Breached("password"); err == nil {
+		t.Error("Breached() succeeded although the API did not answer in time")
+	}
+}
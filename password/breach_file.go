@@ -0,0 +1,215 @@
+package password
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// Breach hash file format: one "SHA1:COUNT" line per password, upper-case hex, sorted by hash,
+// as produced by the Have I Been Pwned downloader.
+//
+// The index maps every 5 hex digit hash prefix, the same prefix length as the range API, to the byte
+// offset of its first line, so a lookup reads one small block of the hash file instead of scanning it.
+// Layout: magic, hash file size and modification time (to detect a replaced file), then
+// prefixCount+1 big-endian offsets, the last one being the hash file size.
+const (
+	indexMagic   = "HIBPIDX1"
+	prefixLength = 5
+	prefixCount  = 1 << (4 * prefixLength)
+	indexHeader  = len(indexMagic) + 8 + 8
+	hashLength   = 40
+)
+
+// FileChecker looks up passwords in a local breach hash file
+type FileChecker struct {
+	hashes *os.File
+	index  *os.File
+}
+
+// OpenFileChecker opens hash file and its index, building the index if missing or stale.
+// indexPath defaults to path with ".idx" appended.
+func OpenFileChecker(path, indexPath string) (*FileChecker, error) {
+	if indexPath == "" {
+		indexPath = path + ".idx"
+	}
+
+	hashes, err := os.Open(path)
+	if err != nil {
+		return nil, errors.New("failed to open breach hash file: " + err.Error())
+	}
+	info, err := hashes.Stat()
+	if err != nil {
+		hashes.Close()
+		return nil, errors.New("failed to stat breach hash file: " + err.Error())
+	}
+
+	if !indexCurrent(indexPath, info) {
+		log.Printf("Building breach hash index %s", indexPath)
+		started := time.Now()
+		if err := buildIndex(hashes, info, indexPath); err != nil {
+			hashes.Close()
+			return nil, err
+		}
+		log.Printf("Built breach hash index in %s", time.Since(started).Round(time.Millisecond))
+	}
+
+	index, err := os.Open(indexPath)
+	if err != nil {
+		hashes.Close()
+		return nil, errors.New("failed to open breach hash index: " + err.Error())
+	}
+
+	return &FileChecker{hashes: hashes, index: index}, nil
+}
+
+// Breached implements BreachChecker
+func (f *FileChecker) Breached(password string) (int, error) {
+	return f.lookup(hashPassword(password))
+}
+
+// lookup returns count of upper-case hex SHA-1 hash, 0 if it is not in the file
+func (f *FileChecker) lookup(hash string) (int, error) {
+	prefix, err := strconv.ParseUint(hash[:prefixLength], 16, 32)
+	if err != nil {
+		return 0, err
+	}
+
+	// Read start and end offsets of the prefix block
+	var bounds [16]byte
+	if _, err := f.index.ReadAt(bounds[:], int64(indexHeader)+int64(prefix)*8); err != nil {
+		return 0, errors.New("failed to read breach hash index: " + err.Error())
+	}
+	start := int64(binary.BigEndian.Uint64(bounds[:8]))
+	end := int64(binary.BigEndian.Uint64(bounds[8:]))
+	if end <= start {
+		return 0, nil
+	}
+
+	block := make([]byte, end-start)
+	if _, err := f.hashes.ReadAt(block, start); err != nil && err != io.EOF {
+		return 0, errors.New("failed to read breach hash file: " + err.Error())
+	}
+
+	for len(block) > 0 {
+		line := block
+		if i := bytes.IndexByte(block, '\n'); i >= 0 {
+			line, block = block[:i], block[i+1:]
+		} else {
+			block = nil
+		}
+		line = bytes.TrimRight(line, "\r")
+		if len(line) < hashLength+2 || !bytes.EqualFold(line[:hashLength], []byte(hash)) {
+			continue
+		}
+		return strconv.Atoi(string(line[hashLength+1:]))
+	}
+	return 0, nil
+}
+
+// Close closes hash file and index
+func (f *FileChecker) Close() error {
+	f.index.Close()
+	return f.hashes.Close()
+}
+
+// indexCurrent checks if index exists and was built from the current hash file
+func indexCurrent(indexPath string, info os.FileInfo) bool {
+	file, err := os.Open(indexPath)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	header := make([]byte, indexHeader)
+	if _, err := io.ReadFull(file, header); err != nil {
+		return false
+	}
+	stat, err := file.Stat()
+	if err != nil || stat.Size() != int64(indexHeader)+int64(prefixCount+1)*8 {
+		return false
+	}
+	return string(header[:len(indexMagic)]) == indexMagic &&
+		binary.BigEndian.Uint64(header[8:16]) == uint64(info.Size()) &&
+		binary.BigEndian.Uint64(header[16:24]) == uint64(info.ModTime().UnixNano())
+}
+
+// buildIndex scans sorted hash file once and writes prefix offsets to indexPath
+func buildIndex(hashes *os.File, info os.FileInfo, indexPath string) error {
+	offsets := make([]uint64, prefixCount+1)
+	next := 0 // first prefix whose offset is not yet known
+	var offset uint64
+
+	reader := bufio.NewReaderSize(io.NewSectionReader(hashes, 0, info.Size()), 1<<20)
+	for lineNumber := 1; ; lineNumber++ {
+		line, err := reader.ReadSlice('\n')
+		if err == bufio.ErrBufferFull {
+			return errors.New("breach hash file line " + strconv.Itoa(lineNumber) + " is too long")
+		}
+		if len(line) > 0 {
+			trimmed := bytes.TrimRight(line, "\r\n")
+			if len(trimmed) > 0 {
+				if len(trimmed) < hashLength+2 || trimmed[hashLength] != ':' {
+					return errors.New("breach hash file line " + strconv.Itoa(lineNumber) + " is not in HASH:COUNT format")
+				}
+				prefix, perr := strconv.ParseUint(string(trimmed[:prefixLength]), 16, 32)
+				if perr != nil {
+					return errors.New("breach hash file line " + strconv.Itoa(lineNumber) + " has invalid hash")
+				}
+				if int(prefix) < next-1 {
+					return errors.New("breach hash file is not sorted by hash at line " + strconv.Itoa(lineNumber))
+				}
+				for ; next <= int(prefix); next++ {
+					offsets[next] = offset
+				}
+			}
+			offset += uint64(len(line))
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return errors.New("failed to read breach hash file: " + err.Error())
+		}
+	}
+	for ; next <= prefixCount; next++ {
+		offsets[next] = offset
+	}
+
+	// Write to temporary file and rename, so readers never see a partial index
+	tmp, err := os.CreateTemp(filepath.Dir(indexPath), filepath.Base(indexPath)+".*")
+	if err != nil {
+		return errors.New("failed to create breach hash index: " + err.Error())
+	}
+	defer os.Remove(tmp.Name())
+
+	writer := bufio.NewWriter(tmp)
+	header := make([]byte, indexHeader)
+	copy(header, indexMagic)
+	binary.BigEndian.PutUint64(header[8:16], uint64(info.Size()))
+	binary.BigEndian.PutUint64(header[16:24], uint64(info.ModTime().UnixNano()))
+	writer.Write(header)
+	var buf [8]byte
+	for _, value := range offsets {
+		binary.BigEndian.PutUint64(buf[:], value)
+		writer.Write(buf[:])
+	}
+	if err := writer.Flush(); err != nil {
+		tmp.Close()
+		return errors.New("failed to write breach hash index: " + err.Error())
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.New("failed to write breach hash index: " + err.Error())
+	}
+	if err := os.Rename(tmp.Name(), indexPath); err != nil {
+		return errors.New("failed to write breach hash index: " + err.Error())
+	}
+	return nil
+}
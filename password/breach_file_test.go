@@ -0,0 +1,185 @@
+package password
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Hashes at the edges of the index: the first and last possible hash, and both sides of a prefix boundary
+var (
+	firstHash       = strings.Repeat("0", hashLength)
+	prefixEndHash   = "00000" + strings.Repeat("F", hashLength-prefixLength)
+	prefixStartHash = "00001" + strings.Repeat("0", hashLength-prefixLength)
+	lastHash        = strings.Repeat("F", hashLength)
+)
+
+// writeHashFile writes lines to a hash file in a temporary directory and returns its path
+func writeHashFile(t *testing.T, dir string, content string) string {
+	t.Helper()
+
+	path := filepath.Join(dir, "pwned.txt")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write hash file: %v", err)
+	}
+	return path
+}
+
+// openTestFileChecker opens checker on content, closing it when the test ends
+func openTestFileChecker(t *testing.T, content string) *FileChecker {
+	t.Helper()
+
+	checker, err := OpenFileChecker(writeHashFile(t, t.TempDir(), content), "")
+	if err != nil {
+		t.Fatalf("open file checker: %v", err)
+	}
+	t.Cleanup(func() { checker.Close() })
+	return checker
+}
+
+func TestFileCheckerLookup(t *testing.T) {
+	lines := []string{
+		firstHash + ":1",
+		prefixEndHash + ":2",
+		prefixStartHash + ":3",
+		hashPassword("password") + ":9545824",
+		hashPassword("123456") + ":37359195",
+		lastHash + ":6",
+	}
+	tests := []struct {
+		hash string
+		want int
+	}{
+		{firstHash, 1},
+		{prefixEndHash, 2},
+		{prefixStartHash, 3},
+		{lastHash, 6},
+		{hashPassword("password"), 9545824},
+		{hashPassword("123456"), 37359195},
+		{"0000000000000000000000000000000000000001", 0}, // Prefix block of the first hash
+		{"00000FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFE", 0}, // Just before the end of a prefix block
+		{"00001" + strings.Repeat("0", hashLength-prefixLength-1) + "1", 0}, // Just after the start of a prefix block
+		{"00002" + strings.Repeat("0", hashLength-prefixLength), 0},         // Prefix without lines after a prefix with lines
+		{"12345" + strings.Repeat("A", hashLength-prefixLength), 0},         // Prefix without lines in the middle
+		{"FFFFE" + strings.Repeat("F", hashLength-prefixLength), 0},         // Prefix without lines before the last one
+		{"FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFE", 0},                     // Prefix block of the last hash
+	}
+
+	// Files from the downloader end lines with CRLF, the last line may lack its line break
+	files := map[string]string{
+		"LF":                  strings.Join(lines, "\n") + "\n",
+		"CRLF":                strings.Join(lines, "\r\n") + "\r\n",
+		"no final line break": strings.Join(lines, "\n"),
+	}
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			checker := openTestFileChecker(t, content)
+			for _, tt := range tests {
+				got, err := checker.lookup(tt.hash)
+				if err != nil {
+					t.Fatalf("lookup(%s) error: %v", tt.hash, err)
+				}
+				if got != tt.want {
+					t.Errorf("lookup(%s) = %d, want %d", tt.hash, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestFileCheckerBreached(t *testing.T) {
+	checker := openTestFileChecker(t, hashPassword("password")+":9545824\n")
+
+	tests := []struct {
+		password string
+		want     int
+	}{
+		{"password", 9545824},
+		{"Password", 0},
+		{"velvet-otter-42", 0},
+		{"", 0},
+	}
+	for _, tt := range tests {
+		if got, err := checker.Breached(tt.password); err != nil || got != tt.want {
+			t.Errorf("Breached(%q) = %d, %v, want %d", tt.password, got, err, tt.want)
+		}
+	}
+
+	// An empty file has no breached passwords
+	empty := openTestFileChecker(t, "")
+	if got, err := empty.lookup(lastHash); err != nil || got != 0 {
+		t.Errorf("lookup in empty file = %d, %v, want 0", got, err)
+	}
+}
+
+func TestOpenFileCheckerInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{name: "not sorted", content: lastHash + ":1\n" + firstHash + ":1\n", wantErr: "not sorted"},
+		{name: "missing count", content: firstHash + "\n", wantErr: "HASH:COUNT"},
+		{name: "wrong separator", content: firstHash + ";1\n", wantErr: "HASH:COUNT"},
+		{name: "invalid hash", content: "XYZ00" + strings.Repeat("0", hashLength-prefixLength) + ":1\n", wantErr: "invalid hash"},
+		{name: "line too long", content: strings.Repeat("0", 2<<20) + "\n", wantErr: "too long"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker, err := OpenFileChecker(writeHashFile(t, t.TempDir(), tt.content), "")
+			if err == nil {
+				checker.Close()
+				t.Fatalf("OpenFileChecker() succeeded, want error containing %q", tt.wantErr)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("OpenFileChecker() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFileCheckerIndexReuse(t *testing.T) {
+	dir := t.TempDir()
+	path := writeHashFile(t, dir, firstHash+":1\n")
+	indexPath := filepath.Join(dir, "pwned.idx")
+
+	checker, err := OpenFileChecker(path, indexPath)
+	if err != nil {
+		t.Fatalf("open file checker: %v", err)
+	}
+	checker.Close()
+	built, err := os.Stat(indexPath)
+	if err != nil {
+		t.Fatalf("stat index: %v", err)
+	}
+
+	// An index of the same hash file is reused
+	past := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(indexPath, past, past); err != nil {
+		t.Fatalf("touch index: %v", err)
+	}
+	checker, err = OpenFileChecker(path, indexPath)
+	if err != nil {
+		t.Fatalf("reopen file checker: %v", err)
+	}
+	checker.Close()
+	if reused, err := os.Stat(indexPath); err != nil || !reused.ModTime().Equal(past) {
+		t.Errorf("index was rebuilt for an unchanged hash file, modified %v", reused.ModTime())
+	}
+	if built.Size() != int64(indexHeader)+int64(prefixCount+1)*8 {
+		t.Errorf("index size %d, want %d", built.Size(), int64(indexHeader)+int64(prefixCount+1)*8)
+	}
+
+	// A replaced hash file gets a new index
+	writeHashFile(t, dir, firstHash+":1\n"+lastHash+":2\n")
+	checker, err = OpenFileChecker(path, indexPath)
+	if err != nil {
+		t.Fatalf("open file checker on replaced file: %v", err)
+	}
+	defer checker.Close()
+	if got, err := checker.lookup(lastHash); err != nil || got != 2 {
+		t.Errorf("lookup in replaced file = %d, %v, want 2", got, err)
+	}
+}
@@ -0,0 +1,169 @@
+package password
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/damonleelcx/go-gin-api/config"
+)
+
+// Hasher hashes and verifies passwords. Hashes are self-describing strings in PHC format
+// ("$id$params$salt$hash"), or bcrypt's own "$2a$cost$..." format, so the algorithm and its
+// parameters can be recovered from a stored hash.
+type Hasher interface {
+	// Hash returns encoded hash of password
+	Hash(password string) (string, error)
+	// Verify checks password against encoded hash
+	Verify(password, encoded string) (bool, error)
+	// NeedsRehash checks if encoded hash was produced by another algorithm or with outdated parameters
+	NeedsRehash(encoded string) bool
+}
+
+// ErrUnknownHash stored hash uses no supported algorithm
+var ErrUnknownHash = errors.New("unknown password hash format")
+
+// NewHasher creates hasher that hashes new passwords with the configured algorithm
+// and verifies hashes of every supported algorithm
+func NewHasher(cfg config.PasswordHashingConfig) (Hasher, error) {
+	hashers := map[string]Hasher{
+		"bcrypt": &BcryptHasher{Cost: cfg.BcryptCost},
+		"scrypt": &ScryptHasher{
+			CostLog:     cfg.Scrypt.CostLog,
+			BlockSize:   cfg.Scrypt.BlockSize,
+			Parallelism: cfg.Scrypt.Parallelism,
+		},
+		"argon2id": &Argon2idHasher{
+			Memory:      cfg.Argon2id.Memory,
+			Iterations:  cfg.Argon2id.Iterations,
+			Parallelism: cfg.Argon2id.Parallelism,
+		},
+	}
+
+	preferred, ok := hashers[cfg.Algorithm]
+	if !ok {
+		return nil, errors.New("unsupported password hashing algorithm: " + cfg.Algorithm)
+	}
+	return &dispatchHasher{preferred: preferred, hashers: hashers}, nil
+}
+
+// dispatchHasher hashes with preferred hasher and verifies with the hasher matching the stored hash
+type dispatchHasher struct {
+	preferred Hasher
+	hashers   map[string]Hasher
+}
+
+// Hash implements Hasher
+func (d *dispatchHasher) Hash(password string) (string, error) {
+	return d.preferred.Hash(password)
+}
+
+// Verify implements Hasher
+func (d *dispatchHasher) Verify(password, encoded string) (bool, error) {
+	hasher, ok := d.hashers[algorithmOf(encoded)]
+	if !ok {
+		return false, ErrUnknownHash
+	}
+	return hasher.Verify(password, encoded)
+}
+
+// NeedsRehash implements Hasher
+func (d *dispatchHasher) NeedsRehash(encoded string) bool {
+	return d.preferred.NeedsRehash(encoded)
+}
+
+// algorithmOf returns algorithm name of encoded hash
+func algorithmOf(encoded string) string {
+	switch {
+	case strings.HasPrefix(encoded, "$2a$"), strings.HasPrefix(encoded, "$2b$"), strings.HasPrefix(encoded, "$2y$"):
+		return "bcrypt"
+	case strings.HasPrefix(encoded, "$scrypt$"):
+		return "scrypt"
+	case strings.HasPrefix(encoded, "$argon2id$"):
+		return "argon2id"
+	default:
+		return ""
+	}
+}
+
+// phcHash decoded PHC string
+type phcHash struct {
+	id      string
+	version string
+	params  map[string]string
+	salt    []byte
+	hash    []byte
+}
+
+// phcEncoding unpadded standard base64 used by PHC strings
+var phcEncoding = base64.RawStdEncoding
+
+// encodePHC formats PHC string, params are given as ordered key=value pairs
+func encodePHC(id, version string, params []string, salt, hash []byte) string {
+	var b strings.Builder
+	b.WriteString("$" + id)
+	if version != "" {
+		b.WriteString("$v=" + version)
+	}
+	b.WriteString("$" + strings.Join(params, ","))
+	b.WriteString("$" + phcEncoding.EncodeToString(salt))
+	b.WriteString("$" + phcEncoding.EncodeToString(hash))
+	return b.String()
+}
+
+// decodePHC parses PHC string with expected id
+func decodePHC(encoded, id string) (*phcHash, error) {
+	parts := strings.Split(encoded, "$")
+	// "", id, [v=version], params, salt, hash
+	if len(parts) < 5 || parts[0] != "" || parts[1] != id {
+		return nil, errors.New("invalid " + id + " hash")
+	}
+	parsed := &phcHash{id: id, params: make(map[string]string)}
+	rest := parts[2:]
+	if strings.HasPrefix(rest[0], "v=") {
+		parsed.version = strings.TrimPrefix(rest[0], "v=")
+		rest = rest[1:]
+	}
+	if len(rest) != 3 {
+		return nil, errors.New("invalid " + id + " hash")
+	}
+	for _, param := range strings.Split(rest[0], ",") {
+		key, value, found := strings.Cut(param, "=")
+		if !found {
+			return nil, errors.New("invalid " + id + " hash parameter: " + param)
+		}
+		parsed.params[key] = value
+	}
+
+	var err error
+	if parsed.salt, err = phcEncoding.DecodeString(rest[1]); err != nil {
+		return nil, errors.New("invalid " + id + " hash salt: " + err.Error())
+	}
+	if parsed.hash, err = phcEncoding.DecodeString(rest[2]); err != nil {
+		return nil, errors.New("invalid " + id + " hash value: " + err.Error())
+	}
+	if len(parsed.hash) == 0 {
+		return nil, errors.New("invalid " + id + " hash value")
+	}
+	return parsed, nil
+}
+
+// intParam returns integer PHC parameter
+func (p *phcHash) intParam(name string) (int, error) {
+	value, err := strconv.Atoi(p.params[name])
+	if err != nil {
+		return 0, errors.New("invalid " + p.id + " hash parameter " + name)
+	}
+	return value, nil
+}
+
+// randomSalt returns n random bytes
+func randomSalt(n int) ([]byte, error) {
+	salt := make([]byte, n)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, errors.New("failed to generate salt: " + err.Error())
+	}
+	return salt, nil
+}
@@ -0,0 +1,217 @@
+package password
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/damonleelcx/go-gin-api/config"
+)
+
+// testHashingConfig returns cheap parameters of every algorithm, preferring algorithm
+func testHashingConfig(algorithm string) config.PasswordHashingConfig {
+	return config.PasswordHashingConfig{
+		Algorithm:  algorithm,
+		BcryptCost: 4,
+		Scrypt:     config.ScryptConfig{CostLog: 4, BlockSize: 8, Parallelism: 1},
+		Argon2id:   config.Argon2idConfig{Memory: 64, Iterations: 1, Parallelism: 1},
+	}
+}
+
+// newTestHasher creates hasher preferring algorithm with cheap parameters
+func newTestHasher(t *testing.T, algorithm string) Hasher {
+	t.Helper()
+
+	hasher, err := NewHasher(testHashingConfig(algorithm))
+	if err != nil {
+		t.Fatalf("NewHasher(%s) error: %v", algorithm, err)
+	}
+	return hasher
+}
+
+var algorithms = []struct {
+	name   string
+	prefix string
+}{
+	{"argon2id", "$argon2id$v=19$m=64,t=1,p=1$"},
+	{"scrypt", "$scrypt$ln=4,r=8,p=1$"},
+	{"bcrypt", "$2a$04$"},
+}
+
+func TestHasherRoundTrip(t *testing.T) {
+	for _, algorithm := range algorithms {
+		t.Run(algorithm.name, func(t *testing.T) {
+			hasher := newTestHasher(t, algorithm.name)
+
+			encoded, err := hasher.Hash("velvet-otter-42")
+			if err != nil {
+				t.Fatalf("Hash() error: %v", err)
+			}
+			if !strings.HasPrefix(encoded, algorithm.prefix) {
+				t.Errorf("Hash() = %s, want prefix %s", encoded, algorithm.prefix)
+			}
+			if again, _ := hasher.Hash("velvet-otter-42"); again == encoded {
+				t.Error("two hashes of the same password are equal, salt is not random")
+			}
+
+			tests := []struct {
+				password string
+				want     bool
+			}{
+				{"velvet-otter-42", true},
+				{"velvet-otter-43", false},
+				{"Velvet-otter-42", false},
+				{"", false},
+			}
+			for _, tt := range tests {
+				got, err := hasher.Verify(tt.password, encoded)
+				if err != nil {
+					t.Fatalf("Verify(%q) error: %v", tt.password, err)
+				}
+				if got != tt.want {
+					t.Errorf("Verify(%q) = %v, want %v", tt.password, got, tt.want)
+				}
+			}
+			if hasher.NeedsRehash(encoded) {
+				t.Error("fresh hash needs rehash")
+			}
+		})
+	}
+}
+
+func TestHasherVerifiesOtherAlgorithms(t *testing.T) {
+	// Hashes of every algorithm verify, whichever algorithm is configured for new hashes
+	for _, made := range algorithms {
+		encoded, err := newTestHasher(t, made.name).Hash("velvet-otter-42")
+		if err != nil {
+			t.Fatalf("hash with %s: %v", made.name, err)
+		}
+		for _, configured := range algorithms {
+			hasher := newTestHasher(t, configured.name)
+			if ok, err := hasher.Verify("velvet-otter-42", encoded); err != nil || !ok {
+				t.Errorf("%s hasher Verify() of %s hash = %v, %v, want true", configured.name, made.name, ok, err)
+			}
+			if ok, err := hasher.Verify("wrong-password", encoded); err != nil || ok {
+				t.Errorf("%s hasher Verify() of %s hash with wrong password = %v, %v, want false", configured.name, made.name, ok, err)
+			}
+			if got, want := hasher.NeedsRehash(encoded), made.name != configured.name; got != want {
+				t.Errorf("%s hasher NeedsRehash() of %s hash = %v, want %v", configured.name, made.name, got, want)
+			}
+		}
+	}
+}
+
+func TestHasherNeedsRehash(t *testing.T) {
+	tests := []struct {
+		name      string
+		configure func(cfg *config.PasswordHashingConfig)
+		want      bool
+	}{
+		{name: "same parameters", configure: func(cfg *config.PasswordHashingConfig) {}, want: false},
+		{name: "argon2id memory", configure: func(cfg *config.PasswordHashingConfig) { cfg.Argon2id.Memory = 128 }, want: true},
+		{name: "argon2id iterations", configure: func(cfg *config.PasswordHashingConfig) { cfg.Argon2id.Iterations = 2 }, want: true},
+		{name: "argon2id parallelism", configure: func(cfg *config.PasswordHashingConfig) { cfg.Argon2id.Parallelism = 2 }, want: true},
+		{name: "parameters of other algorithms", configure: func(cfg *config.PasswordHashingConfig) { cfg.BcryptCost, cfg.Scrypt.CostLog = 5, 5 }, want: false},
+		{name: "algorithm", configure: func(cfg *config.PasswordHashingConfig) { cfg.Algorithm = "bcrypt" }, want: true},
+	}
+
+	encoded, err := newTestHasher(t, "argon2id").Hash("velvet-otter-42")
+	if err != nil {
+		t.Fatalf("hash: %v", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testHashingConfig("argon2id")
+			tt.configure(&cfg)
+			hasher, err := NewHasher(cfg)
+			if err != nil {
+				t.Fatalf("NewHasher() error: %v", err)
+			}
+			if got := hasher.NeedsRehash(encoded); got != tt.want {
+				t.Errorf("NeedsRehash() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// Cost changes of scrypt and bcrypt
+	costs := []struct {
+		algorithm string
+		configure func(cfg *config.PasswordHashingConfig)
+	}{
+		{"scrypt", func(cfg *config.PasswordHashingConfig) { cfg.Scrypt.CostLog = 5 }},
+		{"scrypt", func(cfg *config.PasswordHashingConfig) { cfg.Scrypt.BlockSize = 4 }},
+		{"scrypt", func(cfg *config.PasswordHashingConfig) { cfg.Scrypt.Parallelism = 2 }},
+		{"bcrypt", func(cfg *config.PasswordHashingConfig) { cfg.BcryptCost = 5 }},
+	}
+	for _, cost := range costs {
+		encoded, err := newTestHasher(t, cost.algorithm).Hash("velvet-otter-42")
+		if err != nil {
+			t.Fatalf("hash with %s: %v", cost.algorithm, err)
+		}
+		cfg := testHashingConfig(cost.algorithm)
+		cost.configure(&cfg)
+		hasher, err := NewHasher(cfg)
+		if err != nil {
+			t.Fatalf("NewHasher() error: %v", err)
+		}
+		if !hasher.NeedsRehash(encoded) {
+			t.Errorf("%s hash %s does not need rehash after cost change to %+v", cost.algorithm, encoded, cfg)
+		}
+	}
+}
+
+func TestHasherMalformed(t *testing.T) {
+	const salt, hash = "c2FsdHNhbHRzYWx0c2FsdA", "aGFzaGhhc2hoYXNoaGFzaGhhc2hoYXNoaGFzaGhhc2g"
+	tests := []struct {
+		name    string
+		encoded string
+	}{
+		{"empty", ""},
+		{"unknown algorithm", "$md5$salt$hash"},
+		{"plain text", "velvet-otter-42"},
+		{"argon2id without parts", "$argon2id$"},
+		{"argon2id truncated after params", "$argon2id$v=19$m=64,t=1,p=1"},
+		{"argon2id truncated after salt", "$argon2id$v=19$m=64,t=1,p=1$" + salt},
+		{"argon2id without version", "$argon2id$m=64,t=1,p=1$" + salt + "$" + hash},
+		{"argon2id unsupported version", "$argon2id$v=16$m=64,t=1,p=1$" + salt + "$" + hash},
+		{"argon2id missing parameter", "$argon2id$v=19$m=64,t=1$" + salt + "$" + hash},
+		{"argon2id parameter without value", "$argon2id$v=19$m,t=1,p=1$" + salt + "$" + hash},
+		{"argon2id zero iterations", "$argon2id$v=19$m=64,t=0,p=1$" + salt + "$" + hash},
+		{"argon2id zero parallelism", "$argon2id$v=19$m=64,t=1,p=0$" + salt + "$" + hash},
+		{"argon2id parallelism overflow", "$argon2id$v=19$m=64,t=1,p=256$" + salt + "$" + hash},
+		{"argon2id negative memory", "$argon2id$v=19$m=-1,t=1,p=1$" + salt + "$" + hash},
+		{"argon2id invalid salt", "$argon2id$v=19$m=64,t=1,p=1$!!!$" + hash},
+		{"argon2id invalid hash", "$argon2id$v=19$m=64,t=1,p=1$" + salt + "$!!!"},
+		{"argon2id empty hash", "$argon2id$v=19$m=64,t=1,p=1$" + salt + "$"},
+		{"argon2id extra part", "$argon2id$v=19$m=64,t=1,p=1$" + salt + "$" + hash + "$extra"},
+		{"scrypt truncated", "$scrypt$ln=4,r=8,p=1$" + salt},
+		{"scrypt missing parameter", "$scrypt$ln=4,r=8$" + salt + "$" + hash},
+		{"scrypt cost too high", "$scrypt$ln=31,r=8,p=1$" + salt + "$" + hash},
+		{"scrypt zero cost", "$scrypt$ln=0,r=8,p=1$" + salt + "$" + hash},
+		{"scrypt zero block size", "$scrypt$ln=4,r=0,p=1$" + salt + "$" + hash},
+		{"scrypt zero parallelism", "$scrypt$ln=4,r=8,p=0$" + salt + "$" + hash},
+		{"scrypt negative block size", "$scrypt$ln=4,r=-8,p=1$" + salt + "$" + hash},
+		{"scrypt invalid hash", "$scrypt$ln=4,r=8,p=1$" + salt + "$!!!"},
+		{"bcrypt truncated", "$2a$04$abc"},
+		{"bcrypt invalid cost", "$2a$xx$" + strings.Repeat("a", 53)},
+	}
+	for _, algorithm := range algorithms {
+		hasher := newTestHasher(t, algorithm.name)
+		for _, tt := range tests {
+			t.Run(algorithm.name+"/"+tt.name, func(t *testing.T) {
+				ok, err := hasher.Verify("velvet-otter-42", tt.encoded)
+				if err == nil || ok {
+					t.Errorf("Verify(%q) = %v, %v, want error", tt.encoded, ok, err)
+				}
+				if !hasher.NeedsRehash(tt.encoded) {
+					t.Errorf("NeedsRehash(%q) = false, want true", tt.encoded)
+				}
+			})
+		}
+	}
+}
+
+func TestNewHasherUnsupported(t *testing.T) {
+	if _, err := NewHasher(testHashingConfig("md5")); err == nil {
+		t.Error("NewHasher accepted unsupported algorithm")
+	}
+}
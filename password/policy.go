@@ -0,0 +1,265 @@
+package password
+
+import (
+	"bufio"
+	_ "embed"
+	"errors"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/damonleelcx/go-gin-api/config"
+)
+
+// Rule names reported in violations
+const (
+	RuleMinLength        = "min_length"
+	RuleMaxLength        = "max_length"
+	RuleUppercase        = "uppercase"
+	RuleLowercase        = "lowercase"
+	RuleDigit            = "digit"
+	RuleSymbol           = "symbol"
+	RuleCharacterClasses = "character_classes"
+	RuleUserInfo         = "user_info"
+	RuleCommonPassword   = "common_password"
+	RuleBreached         = "breached"
+)
+
+// Violation failed password rule
+type Violation struct {
+	Rule    string `json:"rule"`    // Rule name
+	Message string `json:"message"` // Human readable explanation
+}
+
+// PolicyError password rejected by policy
+type PolicyError struct {
+	Violations []Violation
+}
+
+// Error implements error interface
+func (e *PolicyError) Error() string {
+	messages := make([]string, 0, len(e.Violations))
+	for _, violation := range e.Violations {
+		messages = append(messages, violation.Message)
+	}
+	return "password does not meet requirements: " + strings.Join(messages, "; ")
+}
+
+// commonPasswords built-in list of frequently used passwords, one per line
+//
+//go:embed common_passwords.txt
+var commonPasswords string
+
+// Policy checks passwords against configured rules
+type Policy struct {
+	config     config.PasswordPolicyConfig
+	dictionary map[string]struct{}
+	breaches   BreachChecker // nil when breach checking is off
+}
+
+// NewPolicy creates a new password policy, loading the built-in dictionary and the optional dictionary file
+func NewPolicy(cfg config.PasswordPolicyConfig, breaches BreachChecker) (*Policy, error) {
+	policy := &Policy{
+		config:     cfg,
+		dictionary: make(map[string]struct{}),
+		breaches:   breaches,
+	}
+
+	if cfg.CheckDictionary {
+		for _, line := range strings.Split(commonPasswords, "\n") {
+			policy.addWord(line)
+		}
+		if cfg.DictionaryFile != "" {
+			if err := policy.loadDictionary(cfg.DictionaryFile); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return policy, nil
+}
+
+// Validate returns PolicyError listing every violated rule, or nil if password is acceptable.
+// Username and email are used to reject passwords resembling the user's own identifiers.
+// A failing breach check is returned as a plain error unless the policy fails open.
+func (p *Policy) Validate(password, username, email string) error {
+	var violations []Violation
+
+	length := utf8.RuneCountInString(password)
+	if length < p.config.MinLength {
+		violations = append(violations, Violation{RuleMinLength, "must be at least " + strconv.Itoa(p.config.MinLength) + " characters"})
+	}
+	if p.config.MaxLength > 0 && length > p.config.MaxLength {
+		violations = append(violations, Violation{RuleMaxLength, "must be at most " + strconv.Itoa(p.config.MaxLength) + " characters"})
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+	if p.config.RequireUppercase && !upper {
+		violations = append(violations, Violation{RuleUppercase, "must contain an uppercase letter"})
+	}
+	if p.config.RequireLowercase && !lower {
+		violations = append(violations, Violation{RuleLowercase, "must contain a lowercase letter"})
+	}
+	if p.config.RequireDigit && !digit {
+		violations = append(violations, Violation{RuleDigit, "must contain a digit"})
+	}
+	if p.config.RequireSymbol && !symbol {
+		violations = append(violations, Violation{RuleSymbol, "must contain a symbol"})
+	}
+	if classes := countTrue(upper, lower, digit, symbol); classes < p.config.MinCharacterClasses {
+		violations = append(violations, Violation{RuleCharacterClasses, "must contain at least " + strconv.Itoa(p.config.MinCharacterClasses) + " of uppercase letters, lowercase letters, digits and symbols"})
+	}
+
+	if p.config.MaxUserInfoSimilarity > 0 && resemblesUserInfo(password, username, email, p.config.MaxUserInfoSimilarity) {
+		violations = append(violations, Violation{RuleUserInfo, "must not resemble your username or email"})
+	}
+
+	if p.config.CheckDictionary && p.isCommon(password) {
+		violations = append(violations, Violation{RuleCommonPassword, "is too common"})
+	}
+
+	if p.breaches != nil && password != "" {
+		count, err := p.breaches.Breached(password)
+		if err != nil {
+			if !p.config.Breach.FailOpen {
+				return errors.New("failed to check password against known breaches: " + err.Error())
+			}
+			log.Printf("Breached password check failed: %v", err)
+		} else if count >= p.config.Breach.MinCount {
+			violations = append(violations, Violation{RuleBreached, "has appeared in a data breach, choose a different password"})
+		}
+	}
+
+	if len(violations) > 0 {
+		return &PolicyError{Violations: violations}
+	}
+	return nil
+}
+
+// isCommon checks password and its base without trailing digits and symbols against the dictionary,
+// so "Password123!" is caught by "password"
+func (p *Policy) isCommon(password string) bool {
+	normalized := strings.ToLower(password)
+	if _, ok := p.dictionary[normalized]; ok {
+		return true
+	}
+	base := strings.TrimRightFunc(normalized, func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	if base != normalized && utf8.RuneCountInString(base) >= 4 {
+		if _, ok := p.dictionary[base]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// addWord adds normalized word to dictionary
+func (p *Policy) addWord(word string) {
+	word = strings.ToLower(strings.TrimSpace(word))
+	if word != "" && !strings.HasPrefix(word, "#") {
+		p.dictionary[word] = struct{}{}
+	}
+}
+
+// loadDictionary adds words from file, one per line
+func (p *Policy) loadDictionary(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return errors.New("failed to open password dictionary: " + err.Error())
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		p.addWord(scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return errors.New("failed to read password dictionary: " + err.Error())
+	}
+	return nil
+}
+
+// resemblesUserInfo checks if password contains, or is too similar to, the username or email local part
+func resemblesUserInfo(password, username, email string, maxSimilarity float64) bool {
+	normalized := strings.ToLower(password)
+
+	candidates := []string{strings.ToLower(username)}
+	if at := strings.LastIndex(email, "@"); at > 0 {
+		candidates = append(candidates, strings.ToLower(email[:at]))
+	}
+	candidates = append(candidates, strings.ToLower(email))
+
+	for _, candidate := range candidates {
+		if utf8.RuneCountInString(candidate) < 3 {
+			continue
+		}
+		if strings.Contains(normalized, candidate) || strings.Contains(candidate, normalized) {
+			return true
+		}
+		if similarity(normalized, candidate) > maxSimilarity {
+			return true
+		}
+	}
+	return false
+}
+
+// similarity returns 1 - normalized Levenshtein distance
+func similarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longest := len(ra)
+	if len(rb) > longest {
+		longest = len(rb)
+	}
+	if longest == 0 {
+		return 1
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+// levenshtein computes edit distance between two rune slices
+func levenshtein(a, b []rune) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+// countTrue counts true values
+func countTrue(values ...bool) int {
+	count := 0
+	for _, value := range values {
+		if value {
+			count++
+		}
+	}
+	return count
+}
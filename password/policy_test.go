@@ -0,0 +1,245 @@
+package password
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/damonleelcx/go-gin-api/config"
+)
+
+// stubBreaches reports the same count or error for every password and records the passwords checked
+type stubBreaches struct {
+	count   int
+	err     error
+	checked []string
+}
+
+// Breached implements BreachChecker
+func (s *stubBreaches) Breached(password string) (int, error) {
+	s.checked = append(s.checked, password)
+	return s.count, s.err
+}
+
+// violatedRules returns rules of the violations in err, nil if err is nil
+func violatedRules(t *testing.T, err error) []string {
+	t.Helper()
+
+	if err == nil {
+		return nil
+	}
+	var policyErr *PolicyError
+	if !errors.As(err, &policyErr) {
+		t.Fatalf("Validate() error = %v, want PolicyError", err)
+	}
+	rules := make([]string, 0, len(policyErr.Violations))
+	for _, violation := range policyErr.Violations {
+		if violation.Message == "" {
+			t.Errorf("violation of %s has no message", violation.Rule)
+		}
+		rules = append(rules, violation.Rule)
+	}
+	return rules
+}
+
+// newTestPolicy creates policy from cfg, failing the test on error
+func newTestPolicy(t *testing.T, cfg config.PasswordPolicyConfig, breaches BreachChecker) *Policy {
+	t.Helper()
+
+	policy, err := NewPolicy(cfg, breaches)
+	if err != nil {
+		t.Fatalf("NewPolicy() error: %v", err)
+	}
+	return policy
+}
+
+func TestPolicyValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   config.PasswordPolicyConfig
+		password string
+		want     []string
+	}{
+		// Length counts characters, not bytes
+		{name: "too short", config: config.PasswordPolicyConfig{MinLength: 8}, password: "velvet", want: []string{RuleMinLength}},
+		{name: "minimum length", config: config.PasswordPolicyConfig{MinLength: 8}, password: "velvetot"},
+		{name: "multibyte too short", config: config.PasswordPolicyConfig{MinLength: 5}, password: "äöüß", want: []string{RuleMinLength}},
+		{name: "maximum length", config: config.PasswordPolicyConfig{MaxLength: 12}, password: "velvet-otter"},
+		{name: "too long", config: config.PasswordPolicyConfig{MaxLength: 12}, password: "velvet-otter-", want: []string{RuleMaxLength}},
+		{name: "multibyte maximum length", config: config.PasswordPolicyConfig{MaxLength: 4}, password: "äöüß"},
+		{name: "no maximum", config: config.PasswordPolicyConfig{}, password: strings.Repeat("velvet-otter", 100)},
+		{name: "empty", config: config.PasswordPolicyConfig{MinLength: 1}, password: "", want: []string{RuleMinLength}},
+
+		// Required character classes
+		{
+			name:     "missing required classes",
+			config:   config.PasswordPolicyConfig{RequireUppercase: true, RequireLowercase: true, RequireDigit: true, RequireSymbol: true},
+			password: "velvetotter",
+			want:     []string{RuleUppercase, RuleDigit, RuleSymbol},
+		},
+		{
+			name:     "all required classes",
+			config:   config.PasswordPolicyConfig{RequireUppercase: true, RequireLowercase: true, RequireDigit: true, RequireSymbol: true},
+			password: "Velvet-Otter-42",
+		},
+		{
+			name:     "missing lowercase",
+			config:   config.PasswordPolicyConfig{RequireLowercase: true},
+			password: "VELVET-OTTER-42",
+			want:     []string{RuleLowercase},
+		},
+		{
+			name:     "non-ASCII letters and space",
+			config:   config.PasswordPolicyConfig{RequireUppercase: true, RequireLowercase: true, RequireSymbol: true},
+			password: "Ärger über",
+		},
+		{name: "too few classes", config: config.PasswordPolicyConfig{MinCharacterClasses: 3}, password: "velvetotter42", want: []string{RuleCharacterClasses}},
+		{name: "enough classes", config: config.PasswordPolicyConfig{MinCharacterClasses: 3}, password: "velvet-otter-42"},
+
+		// Every violated rule is reported, in order
+		{
+			name:     "several violations",
+			config:   config.PasswordPolicyConfig{MinLength: 8, RequireDigit: true, MinCharacterClasses: 2, CheckDictionary: true},
+			password: "dragon",
+			want:     []string{RuleMinLength, RuleDigit, RuleCharacterClasses, RuleCommonPassword},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := newTestPolicy(t, tt.config, nil)
+			got := violatedRules(t, policy.Validate(tt.password, "leonardo", "leonardo@example.com"))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Validate(%q) violations = %v, want %v", tt.password, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicyDictionary(t *testing.T) {
+	dictionaryFile := filepath.Join(t.TempDir(), "dictionary.txt")
+	if err := os.WriteFile(dictionaryFile, []byte("# team words\nGinTonic\n\n  velvetotter  \n"), 0o644); err != nil {
+		t.Fatalf("write dictionary: %v", err)
+	}
+	policy := newTestPolicy(t, config.PasswordPolicyConfig{CheckDictionary: true, DictionaryFile: dictionaryFile}, nil)
+
+	tests := []struct {
+		password string
+		common   bool
+	}{
+		{"password", true},
+		{"PASSWORD", true},
+		{"Password123!", true}, // Base without trailing digits and symbols
+		{"dragon1", true},
+		{"qwerty!!", true},
+		{"ginTONIC", true},      // From the dictionary file, compared case-insensitively
+		{"velvetotter42", true}, // Trimmed dictionary line
+		{"# team words", false}, // Comment lines are not words
+		{"1password", false},    // Only trailing characters are ignored
+		{"velvet-otter-42", false},
+		{"abc1", false}, // Base shorter than 4 characters is not looked up
+	}
+	for _, tt := range tests {
+		got := violatedRules(t, policy.Validate(tt.password, "", ""))
+		if common := reflect.DeepEqual(got, []string{RuleCommonPassword}); common != tt.common {
+			t.Errorf("Validate(%q) violations = %v, want common %v", tt.password, got, tt.common)
+		}
+	}
+
+	// The dictionary is only used when enabled
+	disabled := newTestPolicy(t, config.PasswordPolicyConfig{DictionaryFile: dictionaryFile}, nil)
+	if err := disabled.Validate("password", "", ""); err != nil {
+		t.Errorf("Validate() with dictionary disabled = %v, want nil", err)
+	}
+
+	if _, err := NewPolicy(config.PasswordPolicyConfig{CheckDictionary: true, DictionaryFile: filepath.Join(t.TempDir(), "missing.txt")}, nil); err == nil {
+		t.Error("NewPolicy() accepted missing dictionary file")
+	}
+}
+
+func TestPolicyUserInfo(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		username string
+		email    string
+		want     bool
+	}{
+		{name: "contains username", password: "leonardo123", username: "leonardo", email: "leo@example.com", want: true},
+		{name: "username in other case", password: "LeoNardo!", username: "leonardo", email: "leo@example.com", want: true},
+		{name: "part of username", password: "nardo", username: "leonardo", email: "leo@example.com", want: true},
+		{name: "similar to username", password: "leonrado", username: "leonardo", email: "leo@example.com", want: true},
+		{name: "contains email local part", password: "x-leo.nardo-x", username: "someone", email: "leo.nardo@example.com", want: true},
+		{name: "contains email", password: "leo.nardo@example.com!", username: "someone", email: "leo.nardo@example.com", want: true},
+		{name: "unrelated", password: "velvet-otter-42", username: "leonardo", email: "leo.nardo@example.com", want: false},
+		{name: "below similarity", password: "leopard-tree", username: "leonardo", email: "other@example.com", want: false},
+		{name: "short identifiers are ignored", password: "al-velvet-otter", username: "al", email: "al@x.io", want: false},
+		{name: "empty identifiers", password: "velvet-otter-42", username: "", email: "", want: false},
+	}
+
+	policy := newTestPolicy(t, config.PasswordPolicyConfig{MaxUserInfoSimilarity: 0.7}, nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := violatedRules(t, policy.Validate(tt.password, tt.username, tt.email))
+			if resembles := reflect.DeepEqual(got, []string{RuleUserInfo}); resembles != tt.want {
+				t.Errorf("Validate(%q, %q, %q) violations = %v, want user info %v", tt.password, tt.username, tt.email, got, tt.want)
+			}
+		})
+	}
+
+	// A similarity of 0 disables the rule
+	disabled := newTestPolicy(t, config.PasswordPolicyConfig{}, nil)
+	if err := disabled.Validate("leonardo123", "leonardo", "leonardo@example.com"); err != nil {
+		t.Errorf("Validate() with similarity check disabled = %v, want nil", err)
+	}
+}
+
+func TestPolicyBreached(t *testing.T) {
+	tests := []struct {
+		name     string
+		breaches *stubBreaches
+		failOpen bool
+		want     []string
+		wantErr  bool
+	}{
+		{name: "never seen", breaches: &stubBreaches{count: 0}},
+		{name: "seen less than the minimum", breaches: &stubBreaches{count: 2}},
+		{name: "seen the minimum", breaches: &stubBreaches{count: 3}, want: []string{RuleBreached}},
+		{name: "check fails closed", breaches: &stubBreaches{err: errors.New("unavailable")}, wantErr: true},
+		{name: "check fails open", breaches: &stubBreaches{err: errors.New("unavailable")}, failOpen: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.PasswordPolicyConfig{Breach: config.BreachCheckConfig{MinCount: 3, FailOpen: tt.failOpen}}
+			err := newTestPolicy(t, cfg, tt.breaches).Validate("velvet-otter-42", "", "")
+			if tt.wantErr {
+				var policyErr *PolicyError
+				if err == nil || errors.As(err, &policyErr) {
+					t.Fatalf("Validate() = %v, want plain error", err)
+				}
+				return
+			}
+			if got := violatedRules(t, err); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Validate() violations = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// Empty passwords are rejected by length rules without asking the checker
+	breaches := &stubBreaches{count: 10}
+	newTestPolicy(t, config.PasswordPolicyConfig{MinLength: 8}, breaches).Validate("", "", "")
+	if len(breaches.checked) != 0 {
+		t.Errorf("breach checker asked about %q", breaches.checked)
+	}
+}
+
+func TestPolicyErrorMessage(t *testing.T) {
+	policy := newTestPolicy(t, config.PasswordPolicyConfig{MinLength: 8, RequireDigit: true}, nil)
+	err := policy.Validate("otter", "", "")
+	want := "password does not meet requirements: must be at least 8 characters; must contain a digit"
+	if err == nil || err.Error() != want {
+		t.Errorf("Validate() error = %v, want %q", err, want)
+	}
+}
@@ -0,0 +1,84 @@
+package password
+
+import (
+	"crypto/subtle"
+	"errors"
+	"strconv"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// ScryptHasher scrypt password hasher, encoded as "$scrypt$ln=15,r=8,p=1$salt$hash"
+type ScryptHasher struct {
+	CostLog     int // log2 of CPU/memory cost N
+	BlockSize   int // r
+	Parallelism int // p
+}
+
+// scrypt salt and derived key lengths in bytes
+const (
+	scryptSaltLength = 16
+	scryptKeyLength  = 32
+)
+
+// Hash implements Hasher
+func (s *ScryptHasher) Hash(password string) (string, error) {
+	salt, err := randomSalt(scryptSaltLength)
+	if err != nil {
+		return "", err
+	}
+	key, err := scrypt.Key([]byte(password), salt, 1<<s.CostLog, s.BlockSize, s.Parallelism, scryptKeyLength)
+	if err != nil {
+		return "", err
+	}
+	params := []string{
+		"ln=" + strconv.Itoa(s.CostLog),
+		"r=" + strconv.Itoa(s.BlockSize),
+		"p=" + strconv.Itoa(s.Parallelism),
+	}
+	return encodePHC("scrypt", "", params, salt, key), nil
+}
+
+// Verify implements Hasher
+func (s *ScryptHasher) Verify(password, encoded string) (bool, error) {
+	parsed, costLog, blockSize, parallelism, err := decodeScrypt(encoded)
+	if err != nil {
+		return false, err
+	}
+	key, err := scrypt.Key([]byte(password), parsed.salt, 1<<costLog, blockSize, parallelism, len(parsed.hash))
+	if err != nil {
+		return false, err
+	}
+	return subtle.ConstantTimeCompare(key, parsed.hash) == 1, nil
+}
+
+// NeedsRehash implements Hasher
+func (s *ScryptHasher) NeedsRehash(encoded string) bool {
+	_, costLog, blockSize, parallelism, err := decodeScrypt(encoded)
+	return err != nil || costLog != s.CostLog || blockSize != s.BlockSize || parallelism != s.Parallelism
+}
+
+// decodeScrypt parses scrypt PHC string and its parameters
+func decodeScrypt(encoded string) (parsed *phcHash, costLog, blockSize, parallelism int, err error) {
+	if parsed, err = decodePHC(encoded, "scrypt"); err != nil {
+		return
+	}
+	if costLog, err = parsed.intParam("ln"); err != nil {
+		return
+	}
+	if blockSize, err = parsed.intParam("r"); err != nil {
+		return
+	}
+	if parallelism, err = parsed.intParam("p"); err != nil {
+		return
+	}
+	if costLog < 1 || costLog > 30 {
+		err = errors.New("invalid scrypt hash parameter ln")
+		return
+	}
+	// scrypt divides by r and p, zero values would panic instead of failing verification
+	if blockSize < 1 || parallelism < 1 {
+		err = errors.New("invalid scrypt hash parameters r and p")
+	}
+	return
+}
@@ -0,0 +1,135 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+
+	"github.com/damonleelcx/go-gin-api/config"
+)
+
+// MemoryStore keeps rate limit state in process memory, suitable for single instance deployments
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	windows   map[string]*window
+	lastSweep time.Time
+}
+
+// bucket token bucket state
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+	expiresAt time.Time
+}
+
+// window sliding window state made of the current and previous fixed windows
+type window struct {
+	start     time.Time
+	current   int64
+	previous  int64
+	expiresAt time.Time
+}
+
+// NewMemoryStore creates a new memory store instance
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets:   make(map[string]*bucket),
+		windows:   make(map[string]*window),
+		lastSweep: time.Now(),
+	}
+}
+
+// Allow count request for key under policy and report whether it is allowed
+func (s *MemoryStore) Allow(key string, policy config.RateLimitPolicy) (*Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.sweep(now)
+
+	if policy.Algorithm == TokenBucket {
+		return s.allowBucket(key, policy, now), nil
+	}
+	return s.allowWindow(key, policy, now), nil
+}
+
+// allowBucket token bucket check
+func (s *MemoryStore) allowBucket(key string, policy config.RateLimitPolicy, now time.Time) *Result {
+	limit := policy.Limit
+	period := policy.Window.Std()
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit), updatedAt: now}
+		s.buckets[key] = b
+	}
+
+	// Refill tokens for elapsed time
+	rate := float64(limit) / float64(period)
+	b.tokens += float64(now.Sub(b.updatedAt)) * rate
+	if b.tokens > float64(limit) {
+		b.tokens = float64(limit)
+	}
+	b.updatedAt = now
+	b.expiresAt = now.Add(period)
+
+	if b.tokens < 1 {
+		result := bucketResult(limit, period, b.tokens)
+		result.RetryAfter = time.Duration((1 - b.tokens) / rate)
+		return result
+	}
+
+	b.tokens--
+	result := bucketResult(limit, period, b.tokens)
+	result.Allowed = true
+	return result
+}
+
+// allowWindow sliding window check
+func (s *MemoryStore) allowWindow(key string, policy config.RateLimitPolicy, now time.Time) *Result {
+	period := policy.Window.Std()
+	start := windowStart(now, period)
+
+	w, ok := s.windows[key]
+	if !ok {
+		w = &window{start: start}
+		s.windows[key] = w
+	}
+
+	// Shift fixed windows forward
+	if !w.start.Equal(start) {
+		if w.start.Add(period).Equal(start) {
+			w.previous = w.current
+		} else {
+			w.previous = 0
+		}
+		w.current = 0
+		w.start = start
+	}
+	w.expiresAt = start.Add(2 * period)
+
+	result, allowed := slidingResult(now, period, policy.Limit, w.previous, w.current)
+	if allowed {
+		w.current++
+	}
+	return result
+}
+
+// sweep removes expired state at most once a minute, caller must hold the lock
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	s.lastSweep = now
+
+	for key, b := range s.buckets {
+		if now.After(b.expiresAt) {
+			delete(s.buckets, key)
+		}
+	}
+	for key, w := range s.windows {
+		if now.After(w.expiresAt) {
+			delete(s.windows, key)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/damonleelcx/go-gin-api/config"
+	"github.com/damonleelcx/go-gin-api/middleware"
+	"github.com/damonleelcx/go-gin-api/token"
+	"github.com/gin-gonic/gin"
+)
+
+// recordingStore allows every request and records the keys it was asked about
+type recordingStore struct {
+	keys []string
+}
+
+// Allow implements Store
+func (s *recordingStore) Allow(key string, policy config.RateLimitPolicy) (*Result, error) {
+	s.keys = append(s.keys, key)
+	return &Result{Allowed: true, Limit: policy.Limit, Remaining: policy.Limit - 1}, nil
+}
+
+func TestRequestKeyAPIKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	policy := config.RateLimitPolicy{Algorithm: TokenBucket, Limit: 10, Window: config.Duration(time.Minute), KeyBy: "api_key"}
+
+	tests := []struct {
+		name      string
+		header    string
+		validated string
+		want      string
+	}{
+		{name: "no key", want: "ratelimit:api:ip:192.0.2.1"},
+		{name: "unvalidated header", header: "random-key", want: "ratelimit:api:ip:192.0.2.1"},
+		{name: "validated key", header: "secret-key", validated: "secret-key", want: "ratelimit:api:api_key:" + token.Hash("secret-key")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &recordingStore{}
+			limiter := NewLimiter(store, map[string]config.RateLimitPolicy{"api": policy})
+
+			router := gin.New()
+			router.GET("/", func(c *gin.Context) {
+				if tt.validated != "" {
+					middleware.SetAPIKey(c, tt.validated)
+				}
+			}, limiter.Middleware("api"), func(c *gin.Context) {
+				c.Status(http.StatusNoContent)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = "192.0.2.1:1234"
+			if tt.header != "" {
+				req.Header.Set("X-API-Key", tt.header)
+			}
+			router.ServeHTTP(httptest.NewRecorder(), req)
+
+			if len(store.keys) != 1 || store.keys[0] != tt.want {
+				t.Fatalf("keys = %v, want [%s]", store.keys, tt.want)
+			}
+			if tt.header != "" && strings.Contains(store.keys[0], tt.header) {
+				t.Errorf("key %q contains the raw API key", store.keys[0])
+			}
+		})
+	}
+}
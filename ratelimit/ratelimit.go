@@ -0,0 +1,96 @@
+package ratelimit
+
+import (
+	"errors"
+	"time"
+
+	"github.com/damonleelcx/go-gin-api/config"
+)
+
+// Supported algorithms
+const (
+	TokenBucket   = "token_bucket"   // Bucket of Limit tokens refilled evenly over Window, allows bursts
+	SlidingWindow = "sliding_window" // At most Limit requests in any Window, approximated from two fixed windows
+)
+
+// Result outcome of a rate limit check
+type Result struct {
+	Allowed    bool          // Whether the request may proceed
+	Limit      int           // Configured limit
+	Remaining  int           // Requests left in the current window or bucket
+	Reset      time.Duration // Time until the limit is fully restored
+	RetryAfter time.Duration // Time until the next request is allowed, zero when allowed
+}
+
+// Store keeps rate limit state
+type Store interface {
+	// Allow count request for key under policy and report whether it is allowed
+	Allow(key string, policy config.RateLimitPolicy) (*Result, error)
+}
+
+// NewStore creates store selected by configuration
+func NewStore(cfg config.RateLimitConfig) (Store, error) {
+	switch cfg.Store {
+	case "memory":
+		return NewMemoryStore(), nil
+	case "redis":
+		return NewRedisStore(cfg.Redis), nil
+	default:
+		return nil, errors.New("unsupported rate limit store: " + cfg.Store)
+	}
+}
+
+// windowStart returns start of the fixed window containing now
+func windowStart(now time.Time, window time.Duration) time.Time {
+	return now.Truncate(window)
+}
+
+// slidingResult computes sliding window result from current and previous fixed window counts,
+// the previous window is weighted by how much of it still overlaps the sliding window
+func slidingResult(now time.Time, window time.Duration, limit int, previous, current int64) (*Result, bool) {
+	start := windowStart(now, window)
+	elapsed := now.Sub(start)
+	weight := float64(window-elapsed) / float64(window)
+	estimated := float64(previous)*weight + float64(current)
+
+	result := &Result{
+		Limit: limit,
+		Reset: window - elapsed + window,
+	}
+	if estimated+1 > float64(limit) {
+		// Wait until enough of the previous window has slid out, or the next window when the current one is full
+		retryAfter := window - elapsed
+		if previous > 0 && float64(current)+1 <= float64(limit) {
+			needed := (estimated + 1 - float64(limit)) / float64(previous)
+			if wait := time.Duration(needed * float64(window)); wait < retryAfter {
+				retryAfter = wait
+			}
+		}
+		if retryAfter < time.Second {
+			retryAfter = time.Second
+		}
+		result.RetryAfter = retryAfter
+		return result, false
+	}
+
+	result.Allowed = true
+	result.Remaining = int(float64(limit) - estimated - 1)
+	if result.Remaining < 0 {
+		result.Remaining = 0
+	}
+	return result, true
+}
+
+// bucketResult computes token bucket result after refilling tokens for elapsed time
+func bucketResult(limit int, window time.Duration, tokens float64) *Result {
+	rate := float64(limit) / float64(window) // Tokens per nanosecond
+	result := &Result{
+		Limit:     limit,
+		Remaining: int(tokens),
+		Reset:     time.Duration((float64(limit) - tokens) / rate),
+	}
+	if tokens < 0 {
+		result.Remaining = 0
+	}
+	return result
+}
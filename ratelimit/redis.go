@@ -0,0 +1,304 @@
+package ratelimit
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/damonleelcx/go-gin-api/config"
+)
+
+// tokenBucketScript refills and takes a token atomically.
+// KEYS[1] bucket key, ARGV: capacity, refill rate per millisecond, now in milliseconds, TTL in milliseconds.
+// Returns allowed flag and remaining tokens scaled by 1000, since Lua numbers are truncated to integers.
+const tokenBucketScript = `
+local capacity = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local state = redis.call("HMGET", KEYS[1], "tokens", "ts")
+local tokens = tonumber(state[1]) or capacity
+local ts = tonumber(state[2]) or now
+tokens = math.min(capacity, tokens + math.max(0, now - ts) * rate)
+local allowed = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+end
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "ts", tostring(now))
+redis.call("PEXPIRE", KEYS[1], ARGV[4])
+return {allowed, math.floor(tokens * 1000)}
+`
+
+// slidingWindowScript reads both fixed windows and counts the request if it fits.
+// KEYS[1] current window key, KEYS[2] previous window key,
+// ARGV: limit, previous window weight scaled by 1000000, TTL in milliseconds.
+// Returns allowed flag, previous count and current count before this request.
+const slidingWindowScript = `
+local limit = tonumber(ARGV[1])
+local weight = tonumber(ARGV[2]) / 1000000
+local current = tonumber(redis.call("GET", KEYS[1]) or "0")
+local previous = tonumber(redis.call("GET", KEYS[2]) or "0")
+if previous * weight + current + 1 > limit then
+  return {0, previous, current}
+end
+redis.call("INCR", KEYS[1])
+redis.call("PEXPIRE", KEYS[1], ARGV[3])
+return {1, previous, current}
+`
+
+// RedisStore keeps rate limit state in a Redis compatible server, shared by all instances.
+// It speaks the RESP protocol directly and needs EVAL support on the server.
+type RedisStore struct {
+	addr     string
+	password string
+	db       int
+	timeout  time.Duration
+	mu       sync.Mutex
+	idle     []*redisConn
+}
+
+// redisConn connection to Redis server
+type redisConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// NewRedisStore creates a new Redis store instance
+func NewRedisStore(cfg config.RedisConfig) *RedisStore {
+	return &RedisStore{
+		addr:     cfg.Addr,
+		password: cfg.Password,
+		db:       cfg.DB,
+		timeout:  cfg.Timeout.Std(),
+	}
+}
+
+// Allow count request for key under policy and report whether it is allowed
+func (s *RedisStore) Allow(key string, policy config.RateLimitPolicy) (*Result, error) {
+	now := time.Now()
+	period := policy.Window.Std()
+
+	if policy.Algorithm == TokenBucket {
+		rate := float64(policy.Limit) / float64(period.Milliseconds())
+		reply, err := s.do("EVAL", tokenBucketScript, "1", key,
+			strconv.Itoa(policy.Limit),
+			strconv.FormatFloat(rate, 'f', -1, 64),
+			strconv.FormatInt(now.UnixMilli(), 10),
+			strconv.FormatInt(period.Milliseconds(), 10),
+		)
+		if err != nil {
+			return nil, err
+		}
+		values, err := integers(reply, 2)
+		if err != nil {
+			return nil, err
+		}
+
+		tokens := float64(values[1]) / 1000
+		result := bucketResult(policy.Limit, period, tokens)
+		result.Allowed = values[0] == 1
+		if !result.Allowed {
+			result.RetryAfter = time.Duration((1 - tokens) / (float64(policy.Limit) / float64(period)))
+		}
+		return result, nil
+	}
+
+	start := windowStart(now, period)
+	currentKey := key + ":" + strconv.FormatInt(start.UnixMilli(), 10)
+	previousKey := key + ":" + strconv.FormatInt(start.Add(-period).UnixMilli(), 10)
+	weight := float64(period-now.Sub(start)) / float64(period)
+	reply, err := s.do("EVAL", slidingWindowScript, "2", currentKey, previousKey,
+		strconv.Itoa(policy.Limit),
+		strconv.FormatInt(int64(weight*1000000), 10),
+		strconv.FormatInt((2*period).Milliseconds(), 10),
+	)
+	if err != nil {
+		return nil, err
+	}
+	values, err := integers(reply, 3)
+	if err != nil {
+		return nil, err
+	}
+
+	result, _ := slidingResult(now, period, policy.Limit, values[1], values[2])
+	result.Allowed = values[0] == 1
+	if result.Allowed {
+		result.RetryAfter = 0
+	}
+	return result, nil
+}
+
+// do sends command and reads reply, connections are reused after successful round trips
+func (s *RedisStore) do(args ...string) (interface{}, error) {
+	conn, err := s.get()
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := conn.roundTrip(s.timeout, args...)
+	if err != nil {
+		conn.conn.Close()
+		return nil, err
+	}
+
+	s.put(conn)
+	if replyErr, ok := reply.(redisError); ok {
+		return nil, replyErr
+	}
+	return reply, nil
+}
+
+// get returns idle connection or dials a new one
+func (s *RedisStore) get() (*redisConn, error) {
+	s.mu.Lock()
+	if n := len(s.idle); n > 0 {
+		conn := s.idle[n-1]
+		s.idle = s.idle[:n-1]
+		s.mu.Unlock()
+		return conn, nil
+	}
+	s.mu.Unlock()
+
+	netConn, err := net.DialTimeout("tcp", s.addr, s.timeout)
+	if err != nil {
+		return nil, errors.New("failed to connect to redis: " + err.Error())
+	}
+	conn := &redisConn{conn: netConn, reader: bufio.NewReader(netConn)}
+
+	if s.password != "" {
+		if err := conn.expectOK(s.timeout, "AUTH", s.password); err != nil {
+			netConn.Close()
+			return nil, err
+		}
+	}
+	if s.db != 0 {
+		if err := conn.expectOK(s.timeout, "SELECT", strconv.Itoa(s.db)); err != nil {
+			netConn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+// put returns connection to the idle pool
+func (s *RedisStore) put(conn *redisConn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.idle) >= 16 {
+		conn.conn.Close()
+		return
+	}
+	s.idle = append(s.idle, conn)
+}
+
+// redisError error reply from server
+type redisError string
+
+// Error implements error interface
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+// expectOK sends command and requires a non-error reply
+func (c *redisConn) expectOK(timeout time.Duration, args ...string) error {
+	reply, err := c.roundTrip(timeout, args...)
+	if err != nil {
+		return err
+	}
+	if replyErr, ok := reply.(redisError); ok {
+		return replyErr
+	}
+	return nil
+}
+
+// roundTrip writes command as RESP array of bulk strings and reads one reply
+func (c *redisConn) roundTrip(timeout time.Duration, args ...string) (interface{}, error) {
+	if timeout > 0 {
+		c.conn.SetDeadline(time.Now().Add(timeout))
+	}
+
+	buf := []byte("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, arg := range args {
+		buf = append(buf, "$"+strconv.Itoa(len(arg))+"\r\n"...)
+		buf = append(buf, arg...)
+		buf = append(buf, "\r\n"...)
+	}
+	if _, err := c.conn.Write(buf); err != nil {
+		return nil, err
+	}
+
+	return c.readReply()
+}
+
+// readReply parses one RESP reply: simple string, error, integer, bulk string or array
+func (c *redisConn) readReply() (interface{}, error) {
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, errors.New("redis: malformed reply")
+	}
+	payload := line[1 : len(line)-2]
+
+	switch line[0] {
+	case '+':
+		return payload, nil
+	case '-':
+		return redisError(payload), nil
+	case ':':
+		return strconv.ParseInt(payload, 10, 64)
+	case '$':
+		size, err := strconv.Atoi(payload)
+		if err != nil {
+			return nil, err
+		}
+		if size < 0 {
+			return nil, nil
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(c.reader, data); err != nil {
+			return nil, err
+		}
+		return string(data[:size]), nil
+	case '*':
+		count, err := strconv.Atoi(payload)
+		if err != nil {
+			return nil, err
+		}
+		if count < 0 {
+			return nil, nil
+		}
+		items := make([]interface{}, count)
+		for i := range items {
+			if items[i], err = c.readReply(); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("redis: unexpected reply type %q", line[0])
+	}
+}
+
+// integers converts array reply to integers
+func integers(reply interface{}, count int) ([]int64, error) {
+	items, ok := reply.([]interface{})
+	if !ok || len(items) != count {
+		return nil, errors.New("redis: unexpected script reply")
+	}
+	values := make([]int64, count)
+	for i, item := range items {
+		value, ok := item.(int64)
+		if !ok {
+			return nil, errors.New("redis: unexpected script reply")
+		}
+		values[i] = value
+	}
+	return values, nil
+}
@@ -0,0 +1,292 @@
+package ratelimit
+
+import (
+	"bufio"
+	"net"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/damonleelcx/go-gin-api/config"
+)
+
+// fakeRedis RESP server answering every command with the reply returned by handler
+type fakeRedis struct {
+	listener net.Listener
+	handler  func(args []string) string
+
+	mu       sync.Mutex
+	accepted int
+	commands []string
+}
+
+// newFakeRedis starts fake server on a random local port
+func newFakeRedis(t *testing.T, handler func(args []string) string) *fakeRedis {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	server := &fakeRedis{listener: listener, handler: handler}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			server.mu.Lock()
+			server.accepted++
+			server.mu.Unlock()
+			go server.serve(conn)
+		}
+	}()
+	return server
+}
+
+// serve reads commands, which are RESP arrays themselves, and writes replies until the handler
+// returns an empty reply, which closes the connection
+func (f *fakeRedis) serve(netConn net.Conn) {
+	defer netConn.Close()
+
+	conn := &redisConn{conn: netConn, reader: bufio.NewReader(netConn)}
+	for {
+		reply, err := conn.readReply()
+		if err != nil {
+			return
+		}
+		items, _ := reply.([]interface{})
+		args := make([]string, len(items))
+		for i, item := range items {
+			args[i], _ = item.(string)
+		}
+
+		f.mu.Lock()
+		f.commands = append(f.commands, args[0])
+		f.mu.Unlock()
+
+		response := f.handler(args)
+		if response == "" {
+			return
+		}
+		if _, err := netConn.Write([]byte(response)); err != nil {
+			return
+		}
+	}
+}
+
+// stats returns number of accepted connections and received command names
+func (f *fakeRedis) stats() (int, []string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.accepted, append([]string(nil), f.commands...)
+}
+
+// store returns store connected to the fake server
+func (f *fakeRedis) store(password string, db int) *RedisStore {
+	return NewRedisStore(config.RedisConfig{
+		Addr:     f.listener.Addr().String(),
+		Password: password,
+		DB:       db,
+		Timeout:  config.Duration(time.Second),
+	})
+}
+
+var bucketPolicy = config.RateLimitPolicy{Algorithm: TokenBucket, Limit: 3, Window: config.Duration(time.Hour), KeyBy: "ip"}
+
+func TestReadReply(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    interface{}
+		wantErr bool
+	}{
+		{name: "simple string", input: "+OK\r\n", want: "OK"},
+		{name: "error", input: "-ERR unknown command\r\n", want: redisError("ERR unknown command")},
+		{name: "integer", input: ":42\r\n", want: int64(42)},
+		{name: "negative integer", input: ":-7\r\n", want: int64(-7)},
+		{name: "bulk string", input: "$12\r\nhello\r\nworld\r\n", want: "hello\r\nworld"},
+		{name: "empty bulk string", input: "$0\r\n\r\n", want: ""},
+		{name: "nil bulk string", input: "$-1\r\n", want: nil},
+		{name: "array", input: "*3\r\n:1\r\n$3\r\nabc\r\n+OK\r\n", want: []interface{}{int64(1), "abc", "OK"}},
+		{name: "empty array", input: "*0\r\n", want: []interface{}{}},
+		{name: "nil array", input: "*-1\r\n", want: nil},
+		{name: "array with nil bulk string", input: "*2\r\n$-1\r\n:0\r\n", want: []interface{}{nil, int64(0)}},
+		{name: "nested array", input: "*2\r\n*1\r\n:1\r\n*-1\r\n", want: []interface{}{[]interface{}{int64(1)}, nil}},
+		{name: "missing carriage return", input: "+OK\n", wantErr: true},
+		{name: "unknown type", input: "?what\r\n", wantErr: true},
+		{name: "invalid integer", input: ":forty-two\r\n", wantErr: true},
+		{name: "invalid bulk size", input: "$x\r\n", wantErr: true},
+		{name: "truncated bulk string", input: "$5\r\nhel", wantErr: true},
+		{name: "truncated array", input: "*2\r\n:1\r\n", wantErr: true},
+		{name: "empty input", input: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := &redisConn{reader: bufio.NewReader(strings.NewReader(tt.input))}
+			got, err := conn.readReply()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("readReply() = %#v, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("readReply() error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("readReply() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedisStoreConnectionSetup(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		db       int
+		replies  map[string]string
+		wantErr  string
+		commands []string
+	}{
+		{name: "no setup", commands: []string{"EVAL"}},
+		{name: "auth and select", password: "secret", db: 2, commands: []string{"AUTH", "SELECT", "EVAL"}},
+		{
+			name:     "auth rejected",
+			password: "wrong",
+			replies:  map[string]string{"AUTH": "-WRONGPASS invalid username-password pair\r\n"},
+			wantErr:  "WRONGPASS",
+			commands: []string{"AUTH"},
+		},
+		{
+			name:     "select rejected",
+			db:       99,
+			replies:  map[string]string{"SELECT": "-ERR DB index is out of range\r\n"},
+			wantErr:  "DB index is out of range",
+			commands: []string{"SELECT"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeRedis(t, func(args []string) string {
+				if reply, ok := tt.replies[args[0]]; ok {
+					return reply
+				}
+				if args[0] == "EVAL" {
+					return "*2\r\n:1\r\n:2000\r\n"
+				}
+				return "+OK\r\n"
+			})
+			store := server.store(tt.password, tt.db)
+
+			_, err := store.Allow("key", bucketPolicy)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Allow() error = %v, want %q", err, tt.wantErr)
+				}
+				if len(store.idle) != 0 {
+					t.Error("connection that failed setup was pooled")
+				}
+			} else if err != nil {
+				t.Fatalf("Allow() error: %v", err)
+			}
+
+			if _, commands := server.stats(); !reflect.DeepEqual(commands, tt.commands) {
+				t.Errorf("commands = %v, want %v", commands, tt.commands)
+			}
+		})
+	}
+}
+
+func TestRedisStorePool(t *testing.T) {
+	var mu sync.Mutex
+	replies := []string{
+		"*2\r\n:1\r\n:2000\r\n",  // Successful round trip, connection is reused
+		"-ERR script failed\r\n", // Error reply leaves the protocol in sync, connection is reused
+		"",                       // Connection closed mid-command, it must be discarded
+		"*2\r\n:1\r\n",           // Truncated reply, the connection times out and must be discarded
+		"*2\r\n:0\r\n:500\r\n",   // Served on a fresh connection
+	}
+	server := newFakeRedis(t, func(args []string) string {
+		mu.Lock()
+		defer mu.Unlock()
+		reply := replies[0]
+		replies = replies[1:]
+		return reply
+	})
+	store := server.store("", 0)
+	store.timeout = 200 * time.Millisecond
+
+	steps := []struct {
+		wantErr  bool
+		accepted int
+	}{
+		{accepted: 1},
+		{wantErr: true, accepted: 1},
+		{wantErr: true, accepted: 1},
+		{wantErr: true, accepted: 2},
+		{accepted: 3},
+	}
+	for i, step := range steps {
+		_, err := store.Allow("key", bucketPolicy)
+		if (err != nil) != step.wantErr {
+			t.Fatalf("step %d: Allow() error = %v, want error %v", i, err, step.wantErr)
+		}
+		if accepted, _ := server.stats(); accepted != step.accepted {
+			t.Fatalf("step %d: %d connections opened, want %d", i, accepted, step.accepted)
+		}
+	}
+	if len(store.idle) != 1 {
+		t.Errorf("%d idle connections, want 1", len(store.idle))
+	}
+}
+
+func TestRedisStoreScripts(t *testing.T) {
+	server := miniredis.RunT(t)
+	store := NewRedisStore(config.RedisConfig{Addr: server.Addr(), Timeout: config.Duration(time.Second)})
+
+	policies := []config.RateLimitPolicy{
+		bucketPolicy,
+		{Algorithm: SlidingWindow, Limit: 3, Window: config.Duration(time.Hour), KeyBy: "ip"},
+	}
+	for _, policy := range policies {
+		t.Run(policy.Algorithm, func(t *testing.T) {
+			key := "ratelimit:test:" + policy.Algorithm
+			for i := 0; i < policy.Limit; i++ {
+				result, err := store.Allow(key, policy)
+				if err != nil {
+					t.Fatalf("request %d: %v", i, err)
+				}
+				if !result.Allowed || result.Remaining != policy.Limit-i-1 {
+					t.Fatalf("request %d: allowed %v with %d remaining, want allowed with %d", i, result.Allowed, result.Remaining, policy.Limit-i-1)
+				}
+			}
+
+			result, err := store.Allow(key, policy)
+			if err != nil {
+				t.Fatalf("request over limit: %v", err)
+			}
+			if result.Allowed || result.Remaining != 0 || result.RetryAfter <= 0 {
+				t.Errorf("request over limit: allowed %v with %d remaining, retry after %v", result.Allowed, result.Remaining, result.RetryAfter)
+			}
+
+			// Other clients have their own limit
+			if result, err := store.Allow(key+":other", policy); err != nil || !result.Allowed {
+				t.Errorf("other key: allowed %v, error %v", result != nil && result.Allowed, err)
+			}
+
+			// State expires on its own
+			for _, stored := range server.Keys() {
+				if strings.HasPrefix(stored, key) && server.TTL(stored) <= 0 {
+					t.Errorf("key %s has no expiration", stored)
+				}
+			}
+		})
+	}
+}
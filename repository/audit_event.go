@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"github.com/damonleelcx/go-gin-api/entity"
+	"gorm.io/gorm"
+)
+
+// AuditEventRepository audit event repository interface
+type AuditEventRepository interface {
+	// Create create audit event
+	Create(event *entity.AuditEvent) error
+	// FindByUserID find audit events of user, newest first
+	FindByUserID(userID uint, limit int) ([]*entity.AuditEvent, error)
+}
+
+// auditEventRepository audit event repository implementation
+type auditEventRepository struct {
+	db *gorm.DB
+}
+
+// NewAuditEventRepository creates a new audit event repository instance
+func NewAuditEventRepository(db *gorm.DB) AuditEventRepository {
+	return &auditEventRepository{
+		db: db,
+	}
+}
+
+// Create create audit event
+func (r *auditEventRepository) Create(event *entity.AuditEvent) error {
+	return r.db.Create(event).Error
+}
+
+// FindByUserID find audit events of user, newest first
+func (r *auditEventRepository) FindByUserID(userID uint, limit int) ([]*entity.AuditEvent, error) {
+	var events []*entity.AuditEvent
+	if err := r.db.Where("user_id = ?", userID).Order("created_at DESC, id DESC").Limit(limit).Find(&events).Error; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/damonleelcx/go-gin-api/entity"
+	"gorm.io/gorm"
+)
+
+// CleanupRepository expiry and purging of stale rows, used by the background cleanup job
+type CleanupRepository interface {
+	// ExpireSessions mark active sessions past their expiration time or idle timeout as expired
+	ExpireSessions(now time.Time, idleTimeout, rememberMeIdleTimeout time.Duration) (int64, error)
+	// PurgeSessions delete up to batchSize sessions that ended before cutoff, together with their refresh tokens
+	PurgeSessions(cutoff time.Time, batchSize int) (int64, error)
+	// PurgeBefore delete up to batchSize rows of model whose time column is before cutoff
+	PurgeBefore(model interface{}, column string, cutoff time.Time, batchSize int) (int64, error)
+}
+
+// cleanupRepository cleanup repository implementation
+type cleanupRepository struct {
+	db *gorm.DB
+}
+
+// NewCleanupRepository creates a new cleanup repository instance
+func NewCleanupRepository(db *gorm.DB) CleanupRepository {
+	return &cleanupRepository{
+		db: db,
+	}
+}
+
+// ExpireSessions mark active sessions past their expiration time as expired, as well as sessions unused
+// for longer than the idle timeout of their kind. A zero idle timeout disables the idle check of that kind.
+func (r *cleanupRepository) ExpireSessions(now time.Time, idleTimeout, rememberMeIdleTimeout time.Duration) (int64, error) {
+	// Sessions not used since signin are idle since their creation
+	expired := r.db.Where("expires_at < ?", now)
+	if idleTimeout > 0 {
+		expired = expired.Or("remember_me = ? AND COALESCE(last_used_at, created_at) < ?", false, now.Add(-idleTimeout))
+	}
+	if rememberMeIdleTimeout > 0 {
+		expired = expired.Or("remember_me = ? AND COALESCE(last_used_at, created_at) < ?", true, now.Add(-rememberMeIdleTimeout))
+	}
+
+	result := r.db.Model(&entity.Session{}).
+		Where("status = ?", "active").
+		Where(expired).
+		Update("status", "expired")
+	return result.RowsAffected, result.Error
+}
+
+// PurgeSessions delete up to batchSize sessions that ended before cutoff, together with their refresh tokens
+func (r *cleanupRepository) PurgeSessions(cutoff time.Time, batchSize int) (int64, error) {
+	var ids []uint
+	if err := r.db.Model(&entity.Session{}).
+		Where("status <> ? AND updated_at < ?", "active", cutoff).
+		Order("id").Limit(batchSize).
+		Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	var deleted int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("session_id IN ?", ids).Delete(&entity.RefreshToken{}).Error; err != nil {
+			return err
+		}
+		result := tx.Where("id IN ?", ids).Delete(&entity.Session{})
+		deleted = result.RowsAffected
+		return result.Error
+	})
+	return deleted, err
+}
+
+// PurgeBefore delete up to batchSize rows of model whose time column is before cutoff. IDs are selected
+// first since not every database supports LIMIT in DELETE or in IN subqueries.
+func (r *cleanupRepository) PurgeBefore(model interface{}, column string, cutoff time.Time, batchSize int) (int64, error) {
+	var ids []uint
+	if err := r.db.Model(model).
+		Where(column+" < ?", cutoff).
+		Order("id").Limit(batchSize).
+		Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	result := r.db.Where("id IN ?", ids).Delete(model)
+	return result.RowsAffected, result.Error
+}
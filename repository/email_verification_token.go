@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/damonleelcx/go-gin-api/entity"
+	"github.com/damonleelcx/go-gin-api/token"
+	"gorm.io/gorm"
+)
+
+// EmailVerificationTokenRepository email verification token repository interface
+type EmailVerificationTokenRepository interface {
+	// FindByToken find email verification token by token, looked up by its digest
+	FindByToken(token string) (*entity.EmailVerificationToken, error)
+	// Create create email verification token
+	Create(token *entity.EmailVerificationToken) error
+	// Update update email verification token
+	Update(token *entity.EmailVerificationToken) error
+}
+
+// emailVerificationTokenRepository email verification token repository implementation
+type emailVerificationTokenRepository struct {
+	db *gorm.DB
+}
+
+// NewEmailVerificationTokenRepository creates a new email verification token repository instance
+func NewEmailVerificationTokenRepository(db *gorm.DB) EmailVerificationTokenRepository {
+	return &emailVerificationTokenRepository{
+		db: db,
+	}
+}
+
+// FindByToken find email verification token by token, looked up by its digest
+func (r *emailVerificationTokenRepository) FindByToken(plainToken string) (*entity.EmailVerificationToken, error) {
+	var verificationToken entity.EmailVerificationToken
+	if err := r.db.Where("token_hash = ?", token.Hash(plainToken)).First(&verificationToken).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("verification token invalid")
+		}
+		return nil, err
+	}
+	return &verificationToken, nil
+}
+
+// Create create email verification token
+func (r *emailVerificationTokenRepository) Create(token *entity.EmailVerificationToken) error {
+	return r.db.Create(token).Error
+}
+
+// Update update email verification token
+func (r *emailVerificationTokenRepository) Update(token *entity.EmailVerificationToken) error {
+	return r.db.Save(token).Error
+}
@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/damonleelcx/go-gin-api/entity"
+	"gorm.io/gorm"
+)
+
+// LoginAttemptRepository login attempt repository interface
+type LoginAttemptRepository interface {
+	// FindByKey find login attempt by tracking key, returns nil without error if none exists
+	FindByKey(key string) (*entity.LoginAttempt, error)
+	// Save create or update login attempt
+	Save(attempt *entity.LoginAttempt) error
+	// DeleteByKey delete login attempt by tracking key
+	DeleteByKey(key string) error
+}
+
+// loginAttemptRepository login attempt repository implementation
+type loginAttemptRepository struct {
+	db *gorm.DB
+}
+
+// NewLoginAttemptRepository creates a new login attempt repository instance
+func NewLoginAttemptRepository(db *gorm.DB) LoginAttemptRepository {
+	return &loginAttemptRepository{
+		db: db,
+	}
+}
+
+// FindByKey find login attempt by tracking key, returns nil without error if none exists
+func (r *loginAttemptRepository) FindByKey(key string) (*entity.LoginAttempt, error) {
+	var attempt entity.LoginAttempt
+	if err := r.db.Where("tracking_key = ?", key).First(&attempt).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &attempt, nil
+}
+
+// Save create or update login attempt
+func (r *loginAttemptRepository) Save(attempt *entity.LoginAttempt) error {
+	return r.db.Save(attempt).Error
+}
+
+// DeleteByKey delete login attempt by tracking key
+func (r *loginAttemptRepository) DeleteByKey(key string) error {
+	return r.db.Where("tracking_key = ?", key).Delete(&entity.LoginAttempt{}).Error
+}
@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/damonleelcx/go-gin-api/entity"
+	"github.com/damonleelcx/go-gin-api/token"
+	"gorm.io/gorm"
+)
+
+// LoginTokenRepository passwordless login token repository interface
+type LoginTokenRepository interface {
+	// FindByToken find login token by link token, looked up by its digest
+	FindByToken(token string) (*entity.LoginToken, error)
+	// FindLatestUnused find most recent unused login token of user
+	FindLatestUnused(userID uint) (*entity.LoginToken, error)
+	// Create create login token
+	Create(token *entity.LoginToken) error
+	// Update update login token
+	Update(token *entity.LoginToken) error
+	// MarkUsed mark login token used, returns false if it was used already
+	MarkUsed(id uint) (bool, error)
+}
+
+// loginTokenRepository passwordless login token repository implementation
+type loginTokenRepository struct {
+	db *gorm.DB
+}
+
+// NewLoginTokenRepository creates a new passwordless login token repository instance
+func NewLoginTokenRepository(db *gorm.DB) LoginTokenRepository {
+	return &loginTokenRepository{
+		db: db,
+	}
+}
+
+// FindByToken find login token by link token, looked up by its digest
+func (r *loginTokenRepository) FindByToken(plainToken string) (*entity.LoginToken, error) {
+	var loginToken entity.LoginToken
+	if err := r.db.Where("token_hash = ?", token.Hash(plainToken)).First(&loginToken).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("login token invalid")
+		}
+		return nil, err
+	}
+	return &loginToken, nil
+}
+
+// FindLatestUnused find most recent unused login token of user
+func (r *loginTokenRepository) FindLatestUnused(userID uint) (*entity.LoginToken, error) {
+	var loginToken entity.LoginToken
+	if err := r.db.Where("user_id = ? AND used = ?", userID, false).Order("id DESC").First(&loginToken).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("login code invalid")
+		}
+		return nil, err
+	}
+	return &loginToken, nil
+}
+
+// Create create login token
+func (r *loginTokenRepository) Create(token *entity.LoginToken) error {
+	return r.db.Create(token).Error
+}
+
+// Update update login token
+func (r *loginTokenRepository) Update(token *entity.LoginToken) error {
+	return r.db.Save(token).Error
+}
+
+// MarkUsed mark login token used, returns false if it was used already
+func (r *loginTokenRepository) MarkUsed(id uint) (bool, error) {
+	result := r.db.Model(&entity.LoginToken{}).
+		Where("id = ? AND used = ?", id, false).
+		Update("used", true)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
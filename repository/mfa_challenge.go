@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/damonleelcx/go-gin-api/entity"
+	"github.com/damonleelcx/go-gin-api/token"
+	"gorm.io/gorm"
+)
+
+// MFAChallengeRepository MFA challenge repository interface
+type MFAChallengeRepository interface {
+	// FindByToken find MFA challenge by token, looked up by its digest
+	FindByToken(token string) (*entity.MFAChallenge, error)
+	// Create create MFA challenge
+	Create(challenge *entity.MFAChallenge) error
+	// Update update MFA challenge
+	Update(challenge *entity.MFAChallenge) error
+}
+
+// mfaChallengeRepository MFA challenge repository implementation
+type mfaChallengeRepository struct {
+	db *gorm.DB
+}
+
+// NewMFAChallengeRepository creates a new MFA challenge repository instance
+func NewMFAChallengeRepository(db *gorm.DB) MFAChallengeRepository {
+	return &mfaChallengeRepository{
+		db: db,
+	}
+}
+
+// FindByToken find MFA challenge by token, looked up by its digest
+func (r *mfaChallengeRepository) FindByToken(plainToken string) (*entity.MFAChallenge, error) {
+	var challenge entity.MFAChallenge
+	if err := r.db.Where("token_hash = ?", token.Hash(plainToken)).First(&challenge).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("MFA token invalid")
+		}
+		return nil, err
+	}
+	return &challenge, nil
+}
+
+// Create create MFA challenge
+func (r *mfaChallengeRepository) Create(challenge *entity.MFAChallenge) error {
+	return r.db.Create(challenge).Error
+}
+
+// Update update MFA challenge
+func (r *mfaChallengeRepository) Update(challenge *entity.MFAChallenge) error {
+	return r.db.Save(challenge).Error
+}
@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/damonleelcx/go-gin-api/entity"
+	"github.com/damonleelcx/go-gin-api/token"
+	"gorm.io/gorm"
+)
+
+// OAuthStateRepository OAuth state repository interface
+type OAuthStateRepository interface {
+	// Create create state
+	Create(state *entity.OAuthState) error
+	// Consume find state by value digest and delete it, so each login completes at most once
+	Consume(state string) (*entity.OAuthState, error)
+}
+
+// oauthStateRepository OAuth state repository implementation
+type oauthStateRepository struct {
+	db *gorm.DB
+}
+
+// NewOAuthStateRepository creates a new OAuth state repository instance
+func NewOAuthStateRepository(db *gorm.DB) OAuthStateRepository {
+	return &oauthStateRepository{
+		db: db,
+	}
+}
+
+// Create create state
+func (r *oauthStateRepository) Create(state *entity.OAuthState) error {
+	return r.db.Create(state).Error
+}
+
+// Consume find state by value digest and delete it, so each login completes at most once
+func (r *oauthStateRepository) Consume(plainState string) (*entity.OAuthState, error) {
+	var oauthState entity.OAuthState
+	if err := r.db.Where("state_hash = ?", token.Hash(plainState)).First(&oauthState).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("OAuth state invalid")
+		}
+		return nil, err
+	}
+
+	// Conditional delete so that concurrent callbacks cannot both consume the state
+	result := r.db.Where("id = ?", oauthState.ID).Delete(&entity.OAuthState{})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected != 1 {
+		return nil, errors.New("OAuth state invalid")
+	}
+	return &oauthState, nil
+}
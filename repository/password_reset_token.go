@@ -4,12 +4,13 @@ import (
 	"errors"
 
 	"github.com/damonleelcx/go-gin-api/entity"
+	"github.com/damonleelcx/go-gin-api/token"
 	"gorm.io/gorm"
 )
 
 // PasswordResetTokenRepository password reset token repository interface
 type PasswordResetTokenRepository interface {
-	// FindByToken find password reset token by token
+	// FindByToken find password reset token by token, looked up by its digest
 	FindByToken(token string) (*entity.PasswordResetToken, error)
 	// Create create password reset token
 	Create(token *entity.PasswordResetToken) error
@@ -29,10 +30,10 @@ func NewPasswordResetTokenRepository(db *gorm.DB) PasswordResetTokenRepository {
 	}
 }
 
-// FindByToken find password reset token by token
-func (r *passwordResetTokenRepository) FindByToken(token string) (*entity.PasswordResetToken, error) {
+// FindByToken find password reset token by token, looked up by its digest
+func (r *passwordResetTokenRepository) FindByToken(plainToken string) (*entity.PasswordResetToken, error) {
 	var resetToken entity.PasswordResetToken
-	if err := r.db.Where("token = ?", token).First(&resetToken).Error; err != nil {
+	if err := r.db.Where("token_hash = ?", token.Hash(plainToken)).First(&resetToken).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("reset token invalid")
 		}
@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/damonleelcx/go-gin-api/entity"
+	"gorm.io/gorm"
+)
+
+// RecoveryCodeRepository recovery code repository interface
+type RecoveryCodeRepository interface {
+	// FindUnused find unused recovery code of user by code digest
+	FindUnused(userID uint, codeHash string) (*entity.RecoveryCode, error)
+	// ReplaceForUser delete all recovery codes of user and create the given ones
+	ReplaceForUser(userID uint, codes []*entity.RecoveryCode) error
+	// MarkUsed mark unused code as used, returns false if code was already used
+	MarkUsed(id uint, usedAt time.Time) (bool, error)
+	// CountUnused count unused recovery codes of user
+	CountUnused(userID uint) (int64, error)
+}
+
+// recoveryCodeRepository recovery code repository implementation
+type recoveryCodeRepository struct {
+	db *gorm.DB
+}
+
+// NewRecoveryCodeRepository creates a new recovery code repository instance
+func NewRecoveryCodeRepository(db *gorm.DB) RecoveryCodeRepository {
+	return &recoveryCodeRepository{
+		db: db,
+	}
+}
+
+// FindUnused find unused recovery code of user by code digest
+func (r *recoveryCodeRepository) FindUnused(userID uint, codeHash string) (*entity.RecoveryCode, error) {
+	var code entity.RecoveryCode
+	if err := r.db.Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).First(&code).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("recovery code invalid")
+		}
+		return nil, err
+	}
+	return &code, nil
+}
+
+// ReplaceForUser delete all recovery codes of user and create the given ones
+func (r *recoveryCodeRepository) ReplaceForUser(userID uint, codes []*entity.RecoveryCode) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&entity.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		if len(codes) == 0 {
+			return nil
+		}
+		return tx.Create(codes).Error
+	})
+}
+
+// MarkUsed mark unused code as used, returns false if code was already used
+func (r *recoveryCodeRepository) MarkUsed(id uint, usedAt time.Time) (bool, error) {
+	// Conditional update so that concurrent recoveries with the same code cannot both succeed
+	result := r.db.Model(&entity.RecoveryCode{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", &usedAt)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// CountUnused count unused recovery codes of user
+func (r *recoveryCodeRepository) CountUnused(userID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&entity.RecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Count(&count).Error
+	return count, err
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/damonleelcx/go-gin-api/entity"
+	"github.com/damonleelcx/go-gin-api/token"
+	"gorm.io/gorm"
+)
+
+// RefreshTokenRepository refresh token repository interface
+type RefreshTokenRepository interface {
+	// FindByToken find refresh token by token, looked up by its digest
+	FindByToken(token string) (*entity.RefreshToken, error)
+	// Create create refresh token
+	Create(token *entity.RefreshToken) error
+	// MarkUsed mark unused token as used, returns false if token was already used
+	MarkUsed(id uint, usedAt time.Time) (bool, error)
+	// RevokeBySessionID revoke all refresh tokens of a session
+	RevokeBySessionID(sessionID uint) error
+}
+
+// refreshTokenRepository refresh token repository implementation
+type refreshTokenRepository struct {
+	db *gorm.DB
+}
+
+// NewRefreshTokenRepository creates a new refresh token repository instance
+func NewRefreshTokenRepository(db *gorm.DB) RefreshTokenRepository {
+	return &refreshTokenRepository{
+		db: db,
+	}
+}
+
+// FindByToken find refresh token by token, looked up by its digest
+func (r *refreshTokenRepository) FindByToken(plainToken string) (*entity.RefreshToken, error) {
+	var refreshToken entity.RefreshToken
+	if err := r.db.Where("token_hash = ?", token.Hash(plainToken)).First(&refreshToken).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("refresh token invalid")
+		}
+		return nil, err
+	}
+	return &refreshToken, nil
+}
+
+// Create create refresh token
+func (r *refreshTokenRepository) Create(token *entity.RefreshToken) error {
+	return r.db.Create(token).Error
+}
+
+// MarkUsed mark unused token as used, returns false if token was already used
+func (r *refreshTokenRepository) MarkUsed(id uint, usedAt time.Time) (bool, error) {
+	// Conditional update so that concurrent refreshes with the same token cannot both succeed
+	result := r.db.Model(&entity.RefreshToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", &usedAt)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// RevokeBySessionID revoke all refresh tokens of a session
+func (r *refreshTokenRepository) RevokeBySessionID(sessionID uint) error {
+	return r.db.Model(&entity.RefreshToken{}).
+		Where("session_id = ?", sessionID).
+		Update("revoked", true).Error
+}
@@ -0,0 +1,354 @@
+package repository_test
+
+import (
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/damonleelcx/go-gin-api/config"
+	"github.com/damonleelcx/go-gin-api/database"
+	"github.com/damonleelcx/go-gin-api/entity"
+	"github.com/damonleelcx/go-gin-api/migration"
+	"github.com/damonleelcx/go-gin-api/repository"
+	"github.com/damonleelcx/go-gin-api/token"
+	"gorm.io/gorm"
+)
+
+// openTestDB opens migrated file-backed SQLite database in a temporary directory
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	cfg := config.Default().Database
+	cfg.DSN = filepath.Join(t.TempDir(), "test.db")
+	db, err := database.Open(cfg)
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	if _, err := migration.NewMigrator(db).Up(); err != nil {
+		t.Fatalf("migrate up: %v", err)
+	}
+	return db
+}
+
+// createUser creates active test user
+func createUser(t *testing.T, users repository.UserRepository, username string) *entity.User {
+	t.Helper()
+
+	user := &entity.User{
+		Username: username,
+		Email:    username + "@example.com",
+		Password: "hash",
+		Status:   "active",
+		Role:     "user",
+	}
+	if err := users.Create(user); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	return user
+}
+
+func TestUserRepository(t *testing.T) {
+	users := repository.NewUserRepository(openTestDB(t))
+	user := createUser(t, users, "leo")
+
+	for _, login := range []string{"leo", "leo@example.com"} {
+		found, err := users.FindByUsernameOrEmail(login)
+		if err != nil {
+			t.Fatalf("FindByUsernameOrEmail(%q): %v", login, err)
+		}
+		if found.ID != user.ID {
+			t.Errorf("FindByUsernameOrEmail(%q) = user %d, want %d", login, found.ID, user.ID)
+		}
+	}
+
+	exists, existing, err := users.Exists("someone", "leo@example.com")
+	if err != nil {
+		t.Fatalf("Exists: %v", err)
+	}
+	if !exists || existing.ID != user.ID {
+		t.Errorf("Exists by email = %v, want existing user %d", exists, user.ID)
+	}
+	if exists, _, _ := users.Exists("someone", "someone@example.com"); exists {
+		t.Error("Exists reported unknown user")
+	}
+	if _, err := users.FindByEmail("missing@example.com"); err == nil {
+		t.Error("FindByEmail found missing user")
+	}
+
+	// Unique constraints are enforced by the database
+	duplicate := &entity.User{Username: "leo", Email: "other@example.com", Password: "hash"}
+	if err := users.Create(duplicate); err == nil {
+		t.Error("Create accepted duplicate username")
+	}
+}
+
+func TestSessionRepository(t *testing.T) {
+	db := openTestDB(t)
+	user := createUser(t, repository.NewUserRepository(db), "leo")
+	sessions := repository.NewSessionRepository(db)
+
+	now := time.Now()
+	var created []*entity.Session
+	for _, plain := range []string{"ggs_first", "ggs_second"} {
+		session := &entity.Session{
+			UserID:          user.ID,
+			Token:           plain,
+			TokenHash:       token.Hash(plain),
+			Status:          "active",
+			ExpiresAt:       now.Add(time.Hour),
+			AccessExpiresAt: now.Add(time.Minute),
+		}
+		if err := sessions.Create(session); err != nil {
+			t.Fatalf("create session: %v", err)
+		}
+		created = append(created, session)
+	}
+
+	// Sessions are looked up by the digest of the presented token
+	found, err := sessions.FindByToken("ggs_first")
+	if err != nil {
+		t.Fatalf("FindByToken: %v", err)
+	}
+	if found.ID != created[0].ID || found.Token != "" {
+		t.Errorf("FindByToken = session %d with token %q, want session %d without plain token", found.ID, found.Token, created[0].ID)
+	}
+	if _, err := sessions.FindByToken(token.Hash("ggs_first")); err == nil {
+		t.Error("FindByToken accepted the stored digest as token")
+	}
+
+	expiresAt := now.Add(2 * time.Hour)
+	if err := sessions.UpdateActivity(created[0].ID, now, expiresAt); err != nil {
+		t.Fatalf("UpdateActivity: %v", err)
+	}
+	if err := sessions.UpdateStatusByUserIDExcept(user.ID, created[0].ID, "revoked"); err != nil {
+		t.Fatalf("UpdateStatusByUserIDExcept: %v", err)
+	}
+
+	all, err := sessions.FindByUserID(user.ID)
+	if err != nil {
+		t.Fatalf("FindByUserID: %v", err)
+	}
+	statuses := map[uint]string{}
+	for _, session := range all {
+		statuses[session.ID] = session.Status
+		if session.ID == created[0].ID && !session.ExpiresAt.Equal(expiresAt) {
+			t.Errorf("expires_at = %v, want %v", session.ExpiresAt, expiresAt)
+		}
+	}
+	if statuses[created[0].ID] != "active" || statuses[created[1].ID] != "revoked" {
+		t.Errorf("statuses = %v, want first active and second revoked", statuses)
+	}
+}
+
+func TestRefreshTokenRepository(t *testing.T) {
+	db := openTestDB(t)
+	user := createUser(t, repository.NewUserRepository(db), "leo")
+	refreshTokens := repository.NewRefreshTokenRepository(db)
+
+	refreshToken := &entity.RefreshToken{
+		SessionID: 1,
+		UserID:    user.ID,
+		Token:     "ggrt_refresh",
+		TokenHash: token.Hash("ggrt_refresh"),
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+	if err := refreshTokens.Create(refreshToken); err != nil {
+		t.Fatalf("create refresh token: %v", err)
+	}
+
+	// Only the first of two concurrent rotations may win
+	marked, err := refreshTokens.MarkUsed(refreshToken.ID, time.Now())
+	if err != nil || !marked {
+		t.Fatalf("first MarkUsed = %v, %v, want true", marked, err)
+	}
+	marked, err = refreshTokens.MarkUsed(refreshToken.ID, time.Now())
+	if err != nil || marked {
+		t.Fatalf("second MarkUsed = %v, %v, want false", marked, err)
+	}
+
+	if err := refreshTokens.RevokeBySessionID(1); err != nil {
+		t.Fatalf("RevokeBySessionID: %v", err)
+	}
+	// Refresh tokens are looked up by the digest of the presented token
+	if _, err := refreshTokens.FindByToken(token.Hash("ggrt_refresh")); err == nil {
+		t.Error("FindByToken accepted the stored digest as token")
+	}
+	found, err := refreshTokens.FindByToken("ggrt_refresh")
+	if err != nil {
+		t.Fatalf("FindByToken: %v", err)
+	}
+	if found.IsValid() {
+		t.Error("used and revoked refresh token is still valid")
+	}
+}
+
+// seedSession creates session of user and backdates its timestamps, lastUsedAt may be nil
+func seedSession(t *testing.T, db *gorm.DB, userID uint, name, status string, rememberMe bool, createdAt time.Time, lastUsedAt *time.Time, expiresAt time.Time) *entity.Session {
+	t.Helper()
+
+	session := &entity.Session{
+		UserID:          userID,
+		TokenHash:       token.Hash("ggs_" + name),
+		Status:          status,
+		RememberMe:      rememberMe,
+		ExpiresAt:       expiresAt,
+		AccessExpiresAt: expiresAt,
+		LastUsedAt:      lastUsedAt,
+	}
+	if err := db.Create(session).Error; err != nil {
+		t.Fatalf("create session %s: %v", name, err)
+	}
+	// Automatic timestamps are set on create, so they are moved afterwards
+	if err := db.Model(session).UpdateColumns(map[string]interface{}{"created_at": createdAt, "updated_at": createdAt}).Error; err != nil {
+		t.Fatalf("backdate session %s: %v", name, err)
+	}
+	return session
+}
+
+func TestCleanupRepositoryExpireSessions(t *testing.T) {
+	db := openTestDB(t)
+	user := createUser(t, repository.NewUserRepository(db), "leo")
+	cleanup := repository.NewCleanupRepository(db)
+
+	now := time.Now()
+	ago := func(d time.Duration) *time.Time {
+		at := now.Add(-d)
+		return &at
+	}
+	tests := []struct {
+		name       string
+		status     string
+		rememberMe bool
+		age        time.Duration
+		lastUsed   *time.Time
+		expiresIn  time.Duration
+		want       string
+	}{
+		{name: "live", status: "active", age: time.Hour, lastUsed: ago(time.Minute), expiresIn: time.Hour, want: "active"},
+		{name: "past expiration", status: "active", age: time.Hour, lastUsed: ago(time.Minute), expiresIn: -time.Minute, want: "expired"},
+		{name: "idle", status: "active", age: time.Hour, lastUsed: ago(31 * time.Minute), expiresIn: time.Hour, want: "expired"},
+		{name: "unused since signin", status: "active", age: 31 * time.Minute, expiresIn: time.Hour, want: "expired"},
+		{name: "recently created", status: "active", age: 10 * time.Minute, expiresIn: time.Hour, want: "active"},
+		{name: "remember me within its idle timeout", status: "active", rememberMe: true, age: 3 * time.Hour, lastUsed: ago(time.Hour), expiresIn: time.Hour, want: "active"},
+		{name: "remember me idle", status: "active", rememberMe: true, age: 3 * time.Hour, lastUsed: ago(3 * time.Hour), expiresIn: time.Hour, want: "expired"},
+		{name: "already revoked", status: "revoked", age: time.Hour, lastUsed: ago(time.Hour), expiresIn: -time.Minute, want: "revoked"},
+	}
+
+	ids := make(map[string]uint)
+	for _, tt := range tests {
+		ids[tt.name] = seedSession(t, db, user.ID, tt.name, tt.status, tt.rememberMe, now.Add(-tt.age), tt.lastUsed, now.Add(tt.expiresIn)).ID
+	}
+
+	expired, err := cleanup.ExpireSessions(now, 30*time.Minute, 2*time.Hour)
+	if err != nil {
+		t.Fatalf("ExpireSessions: %v", err)
+	}
+	if expired != 4 {
+		t.Errorf("ExpireSessions expired %d sessions, want 4", expired)
+	}
+	for _, tt := range tests {
+		var session entity.Session
+		if err := db.First(&session, ids[tt.name]).Error; err != nil {
+			t.Fatalf("load session %s: %v", tt.name, err)
+		}
+		if session.Status != tt.want {
+			t.Errorf("session %s status = %s, want %s", tt.name, session.Status, tt.want)
+		}
+	}
+
+	// Without idle timeouts only the expiration counts
+	db.Model(&entity.Session{}).Where("id = ?", ids["idle"]).Update("status", "active")
+	if expired, err := cleanup.ExpireSessions(now, 0, 0); err != nil || expired != 0 {
+		t.Errorf("ExpireSessions without idle timeouts = %d, %v, want 0", expired, err)
+	}
+}
+
+func TestCleanupRepositoryPurge(t *testing.T) {
+	db := openTestDB(t)
+	user := createUser(t, repository.NewUserRepository(db), "leo")
+	cleanup := repository.NewCleanupRepository(db)
+
+	// Seven ended sessions past the cutoff span three batches of three, interleaved with rows to keep
+	now := time.Now()
+	cutoff := now.Add(-24 * time.Hour)
+	var stale, kept []uint
+	for i := 0; i < 7; i++ {
+		name := "stale" + strconv.Itoa(i)
+		stale = append(stale, seedSession(t, db, user.ID, name, []string{"expired", "revoked", "logout"}[i%3], false, now.Add(-48*time.Hour), nil, now.Add(-47*time.Hour)).ID)
+		kept = append(kept,
+			seedSession(t, db, user.ID, "active"+strconv.Itoa(i), "active", false, now.Add(-48*time.Hour), nil, now.Add(time.Hour)).ID,
+			seedSession(t, db, user.ID, "recent"+strconv.Itoa(i), "expired", false, now.Add(-time.Hour), nil, now.Add(-time.Minute)).ID,
+		)
+	}
+	for i, sessionID := range append(append([]uint{}, stale...), kept...) {
+		refreshToken := &entity.RefreshToken{SessionID: sessionID, UserID: user.ID, TokenHash: token.Hash("ggrt_" + strconv.Itoa(i)), ExpiresAt: now.Add(time.Hour)}
+		if err := db.Create(refreshToken).Error; err != nil {
+			t.Fatalf("create refresh token: %v", err)
+		}
+	}
+
+	var batches []int64
+	for {
+		deleted, err := cleanup.PurgeSessions(cutoff, 3)
+		if err != nil {
+			t.Fatalf("PurgeSessions: %v", err)
+		}
+		batches = append(batches, deleted)
+		if deleted < 3 {
+			break
+		}
+	}
+	if len(batches) != 3 || batches[0] != 3 || batches[1] != 3 || batches[2] != 1 {
+		t.Errorf("PurgeSessions batches = %v, want [3 3 1]", batches)
+	}
+
+	var remaining []uint
+	db.Model(&entity.Session{}).Order("id").Pluck("id", &remaining)
+	if len(remaining) != len(kept) {
+		t.Errorf("%d sessions remain, want %d", len(remaining), len(kept))
+	}
+	var orphaned, keptTokens int64
+	db.Model(&entity.RefreshToken{}).Where("session_id IN ?", stale).Count(&orphaned)
+	db.Model(&entity.RefreshToken{}).Where("session_id IN ?", kept).Count(&keptTokens)
+	if orphaned != 0 || keptTokens != int64(len(kept)) {
+		t.Errorf("refresh tokens of purged sessions = %d, of kept sessions = %d, want 0 and %d", orphaned, keptTokens, len(kept))
+	}
+
+	// Rows of other tables are purged by their time column in batches as well
+	for i := 0; i < 5; i++ {
+		for _, attempt := range []*entity.LoginAttempt{
+			{TrackingKey: "ip:stale" + strconv.Itoa(i), Failures: 1, LastFailedAt: now.Add(-48 * time.Hour)},
+			{TrackingKey: "ip:recent" + strconv.Itoa(i), Failures: 1, LastFailedAt: now.Add(-time.Hour)},
+		} {
+			if err := db.Create(attempt).Error; err != nil {
+				t.Fatalf("create login attempt: %v", err)
+			}
+		}
+	}
+	var purged []int64
+	for {
+		deleted, err := cleanup.PurgeBefore(&entity.LoginAttempt{}, "last_failed_at", cutoff, 2)
+		if err != nil {
+			t.Fatalf("PurgeBefore: %v", err)
+		}
+		purged = append(purged, deleted)
+		if deleted < 2 {
+			break
+		}
+	}
+	if len(purged) != 3 || purged[0] != 2 || purged[1] != 2 || purged[2] != 1 {
+		t.Errorf("PurgeBefore batches = %v, want [2 2 1]", purged)
+	}
+	var attempts int64
+	db.Model(&entity.LoginAttempt{}).Where("tracking_key LIKE ?", "ip:recent%").Count(&attempts)
+	if attempts != 5 {
+		t.Errorf("%d recent login attempts remain, want 5", attempts)
+	}
+}
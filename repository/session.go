@@ -5,12 +5,13 @@ import (
 	"time"
 
 	"github.com/damonleelcx/go-gin-api/entity"
+	"github.com/damonleelcx/go-gin-api/token"
 	"gorm.io/gorm"
 )
 
 // SessionRepository session repository interface
 type SessionRepository interface {
-	// FindByToken find session by token
+	// FindByToken find session by token, looked up by its digest
 	FindByToken(token string) (*entity.Session, error)
 	// FindByID find session by ID
 	FindByID(id uint) (*entity.Session, error)
@@ -20,10 +21,12 @@ type SessionRepository interface {
 	Create(session *entity.Session) error
 	// Update update session
 	Update(session *entity.Session) error
-	// UpdateLastUsedAt update last used time
-	UpdateLastUsedAt(sessionID uint, lastUsedAt time.Time) error
+	// UpdateActivity update last used time and the expiration it slides
+	UpdateActivity(sessionID uint, lastUsedAt, expiresAt time.Time) error
 	// UpdateStatusByUserID update status of all active sessions for specified user
 	UpdateStatusByUserID(userID uint, status string) error
+	// UpdateStatusByUserIDExcept update status of all active sessions for specified user except one session
+	UpdateStatusByUserIDExcept(userID, exceptSessionID uint, status string) error
 	// Delete delete session
 	Delete(id uint) error
 }
@@ -40,10 +43,10 @@ func NewSessionRepository(db *gorm.DB) SessionRepository {
 	}
 }
 
-// FindByToken find session by token
-func (r *sessionRepository) FindByToken(token string) (*entity.Session, error) {
+// FindByToken find session by token, looked up by its digest
+func (r *sessionRepository) FindByToken(plainToken string) (*entity.Session, error) {
 	var session entity.Session
-	if err := r.db.Where("token = ?", token).First(&session).Error; err != nil {
+	if err := r.db.Where("token_hash = ?", token.Hash(plainToken)).First(&session).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("session does not exist")
 		}
@@ -83,11 +86,14 @@ func (r *sessionRepository) Update(session *entity.Session) error {
 	return r.db.Save(session).Error
 }
 
-// UpdateLastUsedAt update last used time
-func (r *sessionRepository) UpdateLastUsedAt(sessionID uint, lastUsedAt time.Time) error {
+// UpdateActivity update last used time and the expiration it slides
+func (r *sessionRepository) UpdateActivity(sessionID uint, lastUsedAt, expiresAt time.Time) error {
 	return r.db.Model(&entity.Session{}).
 		Where("id = ?", sessionID).
-		Update("last_used_at", &lastUsedAt).Error
+		Updates(map[string]interface{}{
+			"last_used_at": &lastUsedAt,
+			"expires_at":   expiresAt,
+		}).Error
 }
 
 // UpdateStatusByUserID update status of all active sessions for specified user
@@ -101,6 +107,17 @@ func (r *sessionRepository) UpdateStatusByUserID(userID uint, status string) err
 	return nil
 }
 
+// UpdateStatusByUserIDExcept update status of all active sessions for specified user except one session
+func (r *sessionRepository) UpdateStatusByUserIDExcept(userID, exceptSessionID uint, status string) error {
+	result := r.db.Model(&entity.Session{}).
+		Where("user_id = ? AND status = ? AND id <> ?", userID, "active", exceptSessionID).
+		Update("status", status)
+	if result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
+
 // Delete delete session
 func (r *sessionRepository) Delete(id uint) error {
 	return r.db.Delete(&entity.Session{}, id).Error
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/damonleelcx/go-gin-api/entity"
+	"gorm.io/gorm"
+)
+
+// UserIdentityRepository linked identity repository interface
+type UserIdentityRepository interface {
+	// FindBySubject find identity by provider and subject
+	FindBySubject(provider, subject string) (*entity.UserIdentity, error)
+	// FindByUserID find identities of user
+	FindByUserID(userID uint) ([]*entity.UserIdentity, error)
+	// Create create identity
+	Create(identity *entity.UserIdentity) error
+	// Update update identity
+	Update(identity *entity.UserIdentity) error
+	// DeleteForUser delete identity of user, returns false if it does not exist
+	DeleteForUser(id, userID uint) (bool, error)
+}
+
+// userIdentityRepository linked identity repository implementation
+type userIdentityRepository struct {
+	db *gorm.DB
+}
+
+// NewUserIdentityRepository creates a new linked identity repository instance
+func NewUserIdentityRepository(db *gorm.DB) UserIdentityRepository {
+	return &userIdentityRepository{
+		db: db,
+	}
+}
+
+// FindBySubject find identity by provider and subject
+func (r *userIdentityRepository) FindBySubject(provider, subject string) (*entity.UserIdentity, error) {
+	var identity entity.UserIdentity
+	if err := r.db.Where("provider = ? AND subject = ?", provider, subject).First(&identity).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("identity does not exist")
+		}
+		return nil, err
+	}
+	return &identity, nil
+}
+
+// FindByUserID find identities of user
+func (r *userIdentityRepository) FindByUserID(userID uint) ([]*entity.UserIdentity, error) {
+	var identities []*entity.UserIdentity
+	if err := r.db.Where("user_id = ?", userID).Order("id").Find(&identities).Error; err != nil {
+		return nil, err
+	}
+	return identities, nil
+}
+
+// Create create identity
+func (r *userIdentityRepository) Create(identity *entity.UserIdentity) error {
+	return r.db.Create(identity).Error
+}
+
+// Update update identity
+func (r *userIdentityRepository) Update(identity *entity.UserIdentity) error {
+	return r.db.Save(identity).Error
+}
+
+// DeleteForUser delete identity of user, returns false if it does not exist
+func (r *userIdentityRepository) DeleteForUser(id, userID uint) (bool, error) {
+	result := r.db.Where("id = ? AND user_id = ?", id, userID).Delete(&entity.UserIdentity{})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
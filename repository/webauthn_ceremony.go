@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/damonleelcx/go-gin-api/entity"
+	"github.com/damonleelcx/go-gin-api/token"
+	"gorm.io/gorm"
+)
+
+// WebAuthnCeremonyRepository WebAuthn ceremony repository interface
+type WebAuthnCeremonyRepository interface {
+	// Create create ceremony
+	Create(ceremony *entity.WebAuthnCeremony) error
+	// Consume find ceremony by token digest and delete it, so each ceremony completes at most once
+	Consume(token string) (*entity.WebAuthnCeremony, error)
+}
+
+// webAuthnCeremonyRepository WebAuthn ceremony repository implementation
+type webAuthnCeremonyRepository struct {
+	db *gorm.DB
+}
+
+// NewWebAuthnCeremonyRepository creates a new WebAuthn ceremony repository instance
+func NewWebAuthnCeremonyRepository(db *gorm.DB) WebAuthnCeremonyRepository {
+	return &webAuthnCeremonyRepository{
+		db: db,
+	}
+}
+
+// Create create ceremony
+func (r *webAuthnCeremonyRepository) Create(ceremony *entity.WebAuthnCeremony) error {
+	return r.db.Create(ceremony).Error
+}
+
+// Consume find ceremony by token digest and delete it, so each ceremony completes at most once
+func (r *webAuthnCeremonyRepository) Consume(plainToken string) (*entity.WebAuthnCeremony, error) {
+	var ceremony entity.WebAuthnCeremony
+	if err := r.db.Where("token_hash = ?", token.Hash(plainToken)).First(&ceremony).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("ceremony invalid")
+		}
+		return nil, err
+	}
+
+	// Conditional delete so that concurrent requests cannot both consume the ceremony
+	result := r.db.Where("id = ?", ceremony.ID).Delete(&entity.WebAuthnCeremony{})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected != 1 {
+		return nil, errors.New("ceremony invalid")
+	}
+	return &ceremony, nil
+}
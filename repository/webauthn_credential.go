@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/damonleelcx/go-gin-api/entity"
+	"gorm.io/gorm"
+)
+
+// WebAuthnCredentialRepository WebAuthn credential repository interface
+type WebAuthnCredentialRepository interface {
+	// FindByUserID find all credentials of user
+	FindByUserID(userID uint) ([]*entity.WebAuthnCredential, error)
+	// FindByCredentialID find credential by authenticator credential ID
+	FindByCredentialID(credentialID []byte) (*entity.WebAuthnCredential, error)
+	// Create create credential
+	Create(credential *entity.WebAuthnCredential) error
+	// Update update credential
+	Update(credential *entity.WebAuthnCredential) error
+	// DeleteForUser delete credential of user, returns false if user has no such credential
+	DeleteForUser(id, userID uint) (bool, error)
+}
+
+// webAuthnCredentialRepository WebAuthn credential repository implementation
+type webAuthnCredentialRepository struct {
+	db *gorm.DB
+}
+
+// NewWebAuthnCredentialRepository creates a new WebAuthn credential repository instance
+func NewWebAuthnCredentialRepository(db *gorm.DB) WebAuthnCredentialRepository {
+	return &webAuthnCredentialRepository{
+		db: db,
+	}
+}
+
+// FindByUserID find all credentials of user
+func (r *webAuthnCredentialRepository) FindByUserID(userID uint) ([]*entity.WebAuthnCredential, error) {
+	var credentials []*entity.WebAuthnCredential
+	if err := r.db.Where("user_id = ?", userID).Order("created_at").Find(&credentials).Error; err != nil {
+		return nil, err
+	}
+	return credentials, nil
+}
+
+// FindByCredentialID find credential by authenticator credential ID
+func (r *webAuthnCredentialRepository) FindByCredentialID(credentialID []byte) (*entity.WebAuthnCredential, error) {
+	var credential entity.WebAuthnCredential
+	if err := r.db.Where("credential_id = ?", credentialID).First(&credential).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("credential does not exist")
+		}
+		return nil, err
+	}
+	return &credential, nil
+}
+
+// Create create credential
+func (r *webAuthnCredentialRepository) Create(credential *entity.WebAuthnCredential) error {
+	return r.db.Create(credential).Error
+}
+
+// Update update credential
+func (r *webAuthnCredentialRepository) Update(credential *entity.WebAuthnCredential) error {
+	return r.db.Save(credential).Error
+}
+
+// DeleteForUser delete credential of user, returns false if user has no such credential
+func (r *webAuthnCredentialRepository) DeleteForUser(id, userID uint) (bool, error) {
+	result := r.db.Where("id = ? AND user_id = ?", id, userID).Delete(&entity.WebAuthnCredential{})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
@@ -1,51 +1,181 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 
+	"github.com/damonleelcx/go-gin-api/config"
 	"github.com/damonleelcx/go-gin-api/controller"
-	"github.com/damonleelcx/go-gin-api/entity"
+	"github.com/damonleelcx/go-gin-api/database"
+	"github.com/damonleelcx/go-gin-api/mail"
+	"github.com/damonleelcx/go-gin-api/migration"
+	"github.com/damonleelcx/go-gin-api/password"
+	"github.com/damonleelcx/go-gin-api/ratelimit"
 	"github.com/damonleelcx/go-gin-api/repository"
 	"github.com/damonleelcx/go-gin-api/service"
+	"github.com/damonleelcx/go-gin-api/token"
 	"github.com/gin-gonic/gin"
-	"gorm.io/driver/sqlite"
-	"gorm.io/gorm"
 )
 
 func main() {
+	// Load configuration
+	cfg, args, err := config.Load(os.Args[1:])
+	if err != nil {
+		log.Fatal("Configuration loading failed:", err)
+	}
+
 	// Initialize database
-	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	db, err := database.Open(cfg.Database)
 	if err != nil {
 		log.Fatal("Database connection failed:", err)
 	}
 
-	// Auto migrate database tables
-	err = db.AutoMigrate(
-		&entity.User{},
-		&entity.Session{},
-		&entity.PasswordResetToken{},
-	)
-	if err != nil {
-		log.Fatal("Database migration failed:", err)
+	// Run migration command
+	if len(args) > 0 && args[0] == "migrate" {
+		if err := runMigrate(db, args[1:]); err != nil {
+			log.Fatal("Database migration failed:", err)
+		}
+		return
 	}
 
+	// Apply pending migrations
+	if cfg.Database.AutoMigrate {
+		if _, err := migration.NewMigrator(db).Up(); err != nil {
+			log.Fatal("Database migration failed:", err)
+		}
+	}
+
+	// Stop background jobs and the server on SIGINT or SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	// Initialize repositories
 	userRepo := repository.NewUserRepository(db)
 	sessionRepo := repository.NewSessionRepository(db)
 	passwordResetTokenRepo := repository.NewPasswordResetTokenRepository(db)
+	refreshTokenRepo := repository.NewRefreshTokenRepository(db)
+	emailVerificationTokenRepo := repository.NewEmailVerificationTokenRepository(db)
+	loginAttemptRepo := repository.NewLoginAttemptRepository(db)
+	auditEventRepo := repository.NewAuditEventRepository(db)
+	mfaChallengeRepo := repository.NewMFAChallengeRepository(db)
+	recoveryCodeRepo := repository.NewRecoveryCodeRepository(db)
+	loginTokenRepo := repository.NewLoginTokenRepository(db)
+	oauthStateRepo := repository.NewOAuthStateRepository(db)
+	userIdentityRepo := repository.NewUserIdentityRepository(db)
+	webAuthnCredentialRepo := repository.NewWebAuthnCredentialRepository(db)
+	webAuthnCeremonyRepo := repository.NewWebAuthnCeremonyRepository(db)
+
+	// Initialize signing keys for JWT access tokens and identity assertions
+	var keys *token.KeyManager
+	var signer *token.Signer
+	if cfg.Auth.TokenFormat == "jwt" || cfg.Auth.JWT.KeysConfigured() {
+		keys, err = token.NewKeyManager(cfg.Auth.JWT)
+		if err != nil {
+			log.Fatal("Signing key initialization failed:", err)
+		}
+		go keys.Run(ctx)
+		signer = token.NewSigner(keys, cfg.Auth.JWT)
+	}
+
+	// Initialize mailer
+	mailer, err := mail.New(cfg.Mail)
+	if err != nil {
+		log.Fatal("Mailer initialization failed:", err)
+	}
+
+	// Initialize password policy
+	breachChecker, err := password.NewBreachChecker(cfg.Auth.PasswordPolicy.Breach)
+	if err != nil {
+		log.Fatal("Breached password checker initialization failed:", err)
+	}
+	passwordPolicy, err := password.NewPolicy(cfg.Auth.PasswordPolicy, breachChecker)
+	if err != nil {
+		log.Fatal("Password policy initialization failed:", err)
+	}
+
+	// Initialize password hasher
+	passwordHasher, err := password.NewHasher(cfg.Auth.PasswordHashing)
+	if err != nil {
+		log.Fatal("Password hasher initialization failed:", err)
+	}
 
 	// Initialize services
-	authService := service.NewAuthService(userRepo, sessionRepo, passwordResetTokenRepo)
+	loginThrottle := service.NewLoginThrottle(loginAttemptRepo, cfg.Auth.Lockout)
+	authService := service.NewAuthService(
+		userRepo,
+		sessionRepo,
+		passwordResetTokenRepo,
+		refreshTokenRepo,
+		emailVerificationTokenRepo,
+		auditEventRepo,
+		mfaChallengeRepo,
+		recoveryCodeRepo,
+		loginTokenRepo,
+		loginThrottle,
+		passwordPolicy,
+		passwordHasher,
+		signer,
+		mailer,
+		cfg.Auth,
+	)
+
+	var webAuthnService *service.WebAuthnService
+	if cfg.Auth.WebAuthn.Enabled {
+		webAuthnService, err = service.NewWebAuthnService(authService, userRepo, webAuthnCredentialRepo, webAuthnCeremonyRepo, cfg.Auth.WebAuthn)
+		if err != nil {
+			log.Fatal("WebAuthn initialization failed:", err)
+		}
+	}
+
+	var oauthService *service.OAuthService
+	if len(cfg.Auth.OAuth.Providers) > 0 {
+		oauthService, err = service.NewOAuthService(authService, userRepo, userIdentityRepo, oauthStateRepo, cfg.Auth.OAuth)
+		if err != nil {
+			log.Fatal("OAuth initialization failed:", err)
+		}
+	}
+
+	// Initialize rate limiter
+	var limiter *ratelimit.Limiter
+	if cfg.RateLimit.Enabled {
+		store, err := ratelimit.NewStore(cfg.RateLimit)
+		if err != nil {
+			log.Fatal("Rate limiter initialization failed:", err)
+		}
+		limiter = ratelimit.NewLimiter(store, cfg.RateLimit.Policies)
+	}
 
 	// Initialize controllers
-	authController := controller.NewAuthController(authService)
+	authController := controller.NewAuthController(authService, limiter)
+	mfaController := controller.NewMFAController(authService, limiter)
+	sessionController := controller.NewSessionController(authService)
+	adminController := controller.NewAdminController(authService)
 
 	// Initialize routes
+	gin.SetMode(cfg.Server.Mode)
 	router := gin.Default()
 
 	// Register routes
 	api := router.Group("/api")
 	authController.RegisterRoutes(api)
+	mfaController.RegisterRoutes(api)
+	sessionController.RegisterRoutes(api)
+	if webAuthnService != nil {
+		controller.NewWebAuthnController(authService, webAuthnService, limiter).RegisterRoutes(api)
+	}
+	if oauthService != nil {
+		controller.NewOAuthController(authService, oauthService, limiter).RegisterRoutes(api)
+	}
+	adminController.RegisterRoutes(api)
+	if keys != nil {
+		controller.NewJWKSController(keys).RegisterRoutes(&router.RouterGroup)
+	}
 
 	// Root route
 	router.GET("/", func(c *gin.Context) {
@@ -54,8 +184,37 @@ func main() {
 		})
 	})
 
+	// Start cleanup job
+	var jobs sync.WaitGroup
+	if cfg.Cleanup.Enabled {
+		cleanupService := service.NewCleanupService(repository.NewCleanupRepository(db), cfg.Cleanup, cfg.Auth)
+		jobs.Add(1)
+		go func() {
+			defer jobs.Done()
+			cleanupService.Run(ctx)
+		}()
+	}
+
 	// Start server
-	if err := router.Run(":8080"); err != nil {
-		log.Fatal("Server startup failed:", err)
+	server := &http.Server{
+		Addr:    cfg.Server.Address,
+		Handler: router,
+	}
+	go func() {
+		log.Printf("Server listening on %s", cfg.Server.Address)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal("Server startup failed:", err)
+		}
+	}()
+
+	// Wait for shutdown signal, then let in-flight requests and running jobs finish
+	<-ctx.Done()
+	stop()
+	log.Println("Shutting down server")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout.Std())
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Println("Server shutdown failed:", err)
 	}
-}
\ No newline at end of file
+	jobs.Wait()
+}
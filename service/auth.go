@@ -4,18 +4,38 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"errors"
+	"log"
+	"net/url"
+	"strings"
 	"time"
 
+	"github.com/damonleelcx/go-gin-api/config"
 	"github.com/damonleelcx/go-gin-api/entity"
+	"github.com/damonleelcx/go-gin-api/mail"
+	"github.com/damonleelcx/go-gin-api/password"
 	"github.com/damonleelcx/go-gin-api/repository"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/damonleelcx/go-gin-api/token"
+	"github.com/damonleelcx/go-gin-api/useragent"
 )
 
 // AuthService authentication service
 type AuthService struct {
-	userRepo                repository.UserRepository
-	sessionRepo             repository.SessionRepository
-	passwordResetTokenRepo  repository.PasswordResetTokenRepository
+	userRepo                   repository.UserRepository
+	sessionRepo                repository.SessionRepository
+	passwordResetTokenRepo     repository.PasswordResetTokenRepository
+	refreshTokenRepo           repository.RefreshTokenRepository
+	emailVerificationTokenRepo repository.EmailVerificationTokenRepository
+	auditEventRepo             repository.AuditEventRepository
+	mfaChallengeRepo           repository.MFAChallengeRepository
+	recoveryCodeRepo           repository.RecoveryCodeRepository
+	loginTokenRepo             repository.LoginTokenRepository
+	loginThrottle              *LoginThrottle
+	passwordPolicy             *password.Policy
+	passwordHasher             password.Hasher
+	dummyPasswordHash          string        // Verified against for unknown users so they take as long as known ones
+	signer                     *token.Signer // nil when no signing keys are configured
+	mailer                     mail.Mailer
+	config                     config.AuthConfig
 }
 
 // NewAuthService creates a new authentication service instance
@@ -23,11 +43,42 @@ func NewAuthService(
 	userRepo repository.UserRepository,
 	sessionRepo repository.SessionRepository,
 	passwordResetTokenRepo repository.PasswordResetTokenRepository,
+	refreshTokenRepo repository.RefreshTokenRepository,
+	emailVerificationTokenRepo repository.EmailVerificationTokenRepository,
+	auditEventRepo repository.AuditEventRepository,
+	mfaChallengeRepo repository.MFAChallengeRepository,
+	recoveryCodeRepo repository.RecoveryCodeRepository,
+	loginTokenRepo repository.LoginTokenRepository,
+	loginThrottle *LoginThrottle,
+	passwordPolicy *password.Policy,
+	passwordHasher password.Hasher,
+	signer *token.Signer,
+	mailer mail.Mailer,
+	cfg config.AuthConfig,
 ) *AuthService {
+	// Hashed with current settings so verifying it costs the same as verifying a user's password
+	dummyPasswordHash, err := passwordHasher.Hash("dummy-password")
+	if err != nil {
+		log.Printf("failed to hash dummy password, unknown users sign in faster: %v", err)
+	}
+
 	return &AuthService{
-		userRepo:               userRepo,
-		sessionRepo:            sessionRepo,
-		passwordResetTokenRepo: passwordResetTokenRepo,
+		userRepo:                   userRepo,
+		sessionRepo:                sessionRepo,
+		passwordResetTokenRepo:     passwordResetTokenRepo,
+		refreshTokenRepo:           refreshTokenRepo,
+		emailVerificationTokenRepo: emailVerificationTokenRepo,
+		auditEventRepo:             auditEventRepo,
+		mfaChallengeRepo:           mfaChallengeRepo,
+		recoveryCodeRepo:           recoveryCodeRepo,
+		loginTokenRepo:             loginTokenRepo,
+		loginThrottle:              loginThrottle,
+		passwordPolicy:             passwordPolicy,
+		passwordHasher:             passwordHasher,
+		dummyPasswordHash:          dummyPasswordHash,
+		signer:                     signer,
+		mailer:                     mailer,
+		config:                     cfg,
 	}
 }
 
@@ -35,7 +86,7 @@ func NewAuthService(
 type SignupRequest struct {
 	Username  string `json:"username" binding:"required,min=3,max=50"`
 	Email     string `json:"email" binding:"required,email"`
-	Password  string `json:"password" binding:"required,min=6"`
+	Password  string `json:"password" binding:"required"` // Checked against password policy
 	FirstName string `json:"first_name"`
 	LastName  string `json:"last_name"`
 	Phone     string `json:"phone"`
@@ -49,16 +100,34 @@ type SignupResponse struct {
 
 // SigninRequest login request
 type SigninRequest struct {
-	Username string `json:"username" binding:"required"`
-	Password string `json:"password" binding:"required"`
+	Username   string `json:"username" binding:"required"`
+	Password   string `json:"password" binding:"required"`
+	RememberMe bool   `json:"remember_me"` // Keep the session with the longer remember me policy
 }
 
-// SigninResponse login response
+// SigninResponse login response. When the user has two-factor authentication enabled only
+// MFARequired and MFAToken are set, and the session is issued by SigninMFA.
 type SigninResponse struct {
-	User    *entity.User   `json:"user"`
-	Session *entity.Session `json:"session"`
-	Token   string         `json:"token"`
-	Message string         `json:"message"`
+	User         *entity.User    `json:"user,omitempty"`
+	Session      *entity.Session `json:"session,omitempty"`
+	Token        string          `json:"token,omitempty"`         // Short-lived access token
+	RefreshToken string          `json:"refresh_token,omitempty"` // Single-use refresh token
+	MFARequired  bool            `json:"mfa_required,omitempty"`  // Second factor must be submitted to /auth/signin/mfa
+	MFAToken     string          `json:"mfa_token,omitempty"`     // Challenge token for /auth/signin/mfa
+	Message      string          `json:"message"`
+}
+
+// RefreshRequest refresh token request
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// RefreshResponse refresh token response
+type RefreshResponse struct {
+	Session      *entity.Session `json:"session"`
+	Token        string          `json:"token"`
+	RefreshToken string          `json:"refresh_token"`
+	Message      string          `json:"message"`
 }
 
 // ForgotPasswordRequest forgot password request
@@ -66,14 +135,36 @@ type ForgotPasswordRequest struct {
 	Email string `json:"email" binding:"required,email"`
 }
 
+// VerifyEmailRequest verify email request
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// ResendVerificationRequest resend verification email request
+type ResendVerificationRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
 // ResetPasswordRequest reset password request
 type ResetPasswordRequest struct {
 	Token       string `json:"token" binding:"required"`
-	NewPassword string `json:"new_password" binding:"required,min=6"`
+	NewPassword string `json:"new_password" binding:"required"` // Checked against password policy
+}
+
+// ChangePasswordRequest change password request
+type ChangePasswordRequest struct {
+	CurrentPassword     string `json:"current_password" binding:"required"`
+	NewPassword         string `json:"new_password" binding:"required"` // Checked against password policy
+	RevokeOtherSessions bool   `json:"revoke_other_sessions"`           // Log out every session except the current one
 }
 
 // Signup user registration
 func (s *AuthService) Signup(req *SignupRequest, ipAddress, userAgent string) (*SignupResponse, error) {
+	// Check password policy
+	if err := s.passwordPolicy.Validate(req.Password, req.Username, req.Email); err != nil {
+		return nil, err
+	}
+
 	// Check if username or email already exists
 	exists, existingUser, err := s.userRepo.Exists(req.Username, req.Email)
 	if err != nil {
@@ -89,7 +180,7 @@ func (s *AuthService) Signup(req *SignupRequest, ipAddress, userAgent string) (*
 	}
 
 	// Hash password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	hashedPassword, err := s.passwordHasher.Hash(req.Password)
 	if err != nil {
 		return nil, errors.New("password encryption failed: " + err.Error())
 	}
@@ -98,11 +189,11 @@ func (s *AuthService) Signup(req *SignupRequest, ipAddress, userAgent string) (*
 	user := &entity.User{
 		Username:  req.Username,
 		Email:     req.Email,
-		Password:  string(hashedPassword),
+		Password:  hashedPassword,
 		FirstName: req.FirstName,
 		LastName:  req.LastName,
 		Phone:     req.Phone,
-		Status:    "active",
+		Status:    "pending_verification",
 		Role:      "user",
 	}
 
@@ -110,67 +201,313 @@ func (s *AuthService) Signup(req *SignupRequest, ipAddress, userAgent string) (*
 		return nil, errors.New("failed to create user: " + err.Error())
 	}
 
+	// Send verification email, the user can request another one if delivery fails
+	if err := s.sendVerificationEmail(user); err != nil {
+		log.Printf("failed to send verification email to user %d: %v", user.ID, err)
+	}
+
 	return &SignupResponse{
 		User:    user,
-		Message: "Registration successful",
+		Message: "Registration successful, please check your email to verify your address",
 	}, nil
 }
 
 // Signin user login
 func (s *AuthService) Signin(req *SigninRequest, ipAddress, userAgent string) (*SigninResponse, error) {
+	// Check client IP throttling
+	ipKey := IPKey(ipAddress)
+	if err := s.loginThrottle.Check(ipKey); err != nil {
+		return nil, err
+	}
+
 	// Find user (supports username or email login)
 	user, err := s.userRepo.FindByUsernameOrEmail(req.Username)
 	if err != nil {
+		// Hash anyway, a quick answer would reveal that the username does not exist
+		s.passwordHasher.Verify(req.Password, s.dummyPasswordHash)
+		s.loginThrottle.RecordFailure(ipKey, s.config.Lockout.MaxIPAttempts)
+		return nil, errors.New("username or password incorrect")
+	}
+
+	// Check account throttling
+	userKey := UserKey(user.ID)
+	if err := s.loginThrottle.Check(userKey); err != nil {
+		return nil, err
+	}
+
+	// Verify password before revealing anything about the account
+	valid, err := s.passwordHasher.Verify(req.Password, user.Password)
+	if err != nil {
+		log.Printf("failed to verify password hash of user %d: %v", user.ID, err)
+	}
+	if !valid {
+		s.loginThrottle.RecordFailure(userKey, s.config.Lockout.MaxUserAttempts)
+		s.loginThrottle.RecordFailure(ipKey, s.config.Lockout.MaxIPAttempts)
 		return nil, errors.New("username or password incorrect")
 	}
 
 	// Check user status
-	if user.Status != "active" {
+	if user.Status == "pending_verification" && !s.config.AllowUnverifiedSignin {
+		return nil, errors.New("email address has not been verified")
+	}
+	if !s.isUserAllowed(user) {
 		return nil, errors.New("account has been disabled")
 	}
 
-	// Verify password
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
-		return nil, errors.New("username or password incorrect")
+	// Upgrade hash made with an outdated algorithm or cost, the plaintext is only available now
+	if s.passwordHasher.NeedsRehash(user.Password) {
+		s.rehashPassword(user, req.Password)
+	}
+
+	// Second factor required, account failures are cleared once it is verified
+	if user.TOTPEnabled {
+		return s.createMFAChallenge(user, req.RememberMe, ipAddress, userAgent)
+	}
+
+	// Successful login clears account failures, IP failures only expire over time
+	if err := s.loginThrottle.Reset(userKey); err != nil {
+		log.Printf("failed to reset login attempts of user %d: %v", user.ID, err)
 	}
 
-	// Generate session token
-	token, err := generateToken()
+	// Create session
+	response, err := s.createSession(user, req.RememberMe, ipAddress, userAgent)
+	if err != nil {
+		return nil, err
+	}
+	response.Message = "Login successful"
+
+	return response, nil
+}
+
+// rehashPassword stores password hashed with current settings, failures only delay the upgrade
+func (s *AuthService) rehashPassword(user *entity.User, plaintext string) {
+	hashedPassword, err := s.passwordHasher.Hash(plaintext)
+	if err != nil {
+		log.Printf("failed to rehash password of user %d: %v", user.ID, err)
+		return
+	}
+	user.Password = hashedPassword
+	if err := s.userRepo.Update(user); err != nil {
+		log.Printf("failed to store rehashed password of user %d: %v", user.ID, err)
+	}
+}
+
+// UnlockUser clear failed login attempts and lockout of user
+func (s *AuthService) UnlockUser(userID uint) error {
+	if _, err := s.userRepo.FindByID(userID); err != nil {
+		return err
+	}
+	return s.loginThrottle.Reset(UserKey(userID))
+}
+
+// Refresh exchange refresh token for new access token, rotating the refresh token.
+// Presenting an already rotated refresh token revokes the whole session (token family).
+func (s *AuthService) Refresh(req *RefreshRequest, ipAddress, userAgent string) (*RefreshResponse, error) {
+	// Find refresh token
+	refreshToken, err := s.refreshTokenRepo.FindByToken(req.RefreshToken)
+	if err != nil {
+		return nil, err
+	}
+
+	// Reuse of a rotated or revoked token means it may have been stolen
+	if refreshToken.UsedAt != nil || refreshToken.Revoked {
+		s.revokeTokenFamily(refreshToken.SessionID)
+		return nil, errors.New("refresh token reuse detected, session has been revoked")
+	}
+
+	if refreshToken.IsExpired() {
+		return nil, errors.New("refresh token has expired")
+	}
+
+	// Find session
+	session, err := s.sessionRepo.FindByID(refreshToken.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	if !session.IsActive() {
+		return nil, errors.New("session has expired")
+	}
+
+	// Refreshing is activity, extend the session unless it has been idle for too long
+	if err := s.touchSession(session); err != nil {
+		return nil, err
+	}
+
+	// Find user
+	user, err := s.userRepo.FindByID(session.UserID)
+	if err != nil {
+		return nil, errors.New("user does not exist")
+	}
+	if !s.isUserAllowed(user) {
+		return nil, errors.New("user has been disabled")
+	}
+
+	// Mark token as used, losing the race against a concurrent refresh is also reuse
+	now := time.Now()
+	marked, err := s.refreshTokenRepo.MarkUsed(refreshToken.ID, now)
+	if err != nil {
+		return nil, errors.New("failed to update refresh token: " + err.Error())
+	}
+	if !marked {
+		s.revokeTokenFamily(refreshToken.SessionID)
+		return nil, errors.New("refresh token reuse detected, session has been revoked")
+	}
+
+	// Rotate access token
+	sessionToken, err := generatePrefixedToken(token.SessionPrefix)
+	if err != nil {
+		return nil, errors.New("failed to generate token: " + err.Error())
+	}
+	session.Token = sessionToken
+	session.TokenHash = token.Hash(sessionToken)
+	tokenID, err := generateToken()
+	if err != nil {
+		return nil, errors.New("failed to generate token: " + err.Error())
+	}
+	session.JTIHash = token.Hash(tokenID)
+	session.AccessExpiresAt = now.Add(s.config.AccessTokenTTL.Std())
+	session.IPAddress = ipAddress
+	session.UserAgent = userAgent
+	if err := s.sessionRepo.Update(session); err != nil {
+		return nil, errors.New("failed to update session: " + err.Error())
+	}
+
+	accessToken, err := s.accessToken(user, session, tokenID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Issue next refresh token of the family
+	newRefreshToken, err := s.createRefreshToken(session)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RefreshResponse{
+		Session:      session,
+		Token:        accessToken,
+		RefreshToken: newRefreshToken,
+		Message:      "Token refreshed",
+	}, nil
+}
+
+// createSession create session with access and refresh token for authenticated user, rememberMe
+// selects the longer session policy
+func (s *AuthService) createSession(user *entity.User, rememberMe bool, ipAddress, userAgent string) (*SigninResponse, error) {
+	// Generate session token and ID of the first JWT access token
+	sessionToken, err := generatePrefixedToken(token.SessionPrefix)
+	if err != nil {
+		return nil, errors.New("failed to generate token: " + err.Error())
+	}
+	tokenID, err := generateToken()
 	if err != nil {
 		return nil, errors.New("failed to generate token: " + err.Error())
 	}
 
 	// Create session
 	now := time.Now()
+	rememberMe = rememberMe && s.config.RememberMe.Enabled
+	client := useragent.Parse(userAgent)
 	session := &entity.Session{
-		UserID:    user.ID,
-		Token:     token,
-		IPAddress: ipAddress,
-		UserAgent: userAgent,
-		Status:    "active",
-		ExpiresAt: time.Now().Add(24 * 7 * time.Hour), // 7 days expiry
-		LastUsedAt: &now,
+		UserID:          user.ID,
+		Token:           sessionToken,
+		TokenHash:       token.Hash(sessionToken),
+		JTIHash:         token.Hash(tokenID),
+		IPAddress:       ipAddress,
+		UserAgent:       userAgent,
+		Device:          client.Device,
+		Platform:        client.Platform,
+		Browser:         client.Browser,
+		BrowserVersion:  client.BrowserVersion,
+		Status:          "active",
+		RememberMe:      rememberMe,
+		ExpiresAt:       s.sessionPolicy(rememberMe).expiresAt(now, now),
+		AccessExpiresAt: now.Add(s.config.AccessTokenTTL.Std()),
+		LastUsedAt:      &now,
 	}
 
 	if err := s.sessionRepo.Create(session); err != nil {
 		return nil, errors.New("failed to create session: " + err.Error())
 	}
 
+	// Create first refresh token of the family
+	refreshToken, err := s.createRefreshToken(session)
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken, err := s.accessToken(user, session, tokenID)
+	if err != nil {
+		return nil, err
+	}
+
 	// Clear password field
 	user.Password = ""
 
 	return &SigninResponse{
-		User:    user,
-		Session: session,
-		Token:   token,
-		Message: "Login successful",
+		User:         user,
+		Session:      session,
+		Token:        accessToken,
+		RefreshToken: refreshToken,
 	}, nil
 }
 
+// accessToken returns access token handed to the client for session. Opaque access tokens are the
+// session token itself, JWT access tokens are signed with tokenID, whose digest the session keeps
+// so that only the latest access token of the session is accepted.
+func (s *AuthService) accessToken(user *entity.User, session *entity.Session, tokenID string) (string, error) {
+	if s.config.TokenFormat != "jwt" {
+		return session.Token, nil
+	}
+
+	signed, err := s.signer.Sign(user.ID, user.Role, session.ID, tokenID, session.AccessExpiresAt)
+	if err != nil {
+		return "", errors.New("failed to sign access token: " + err.Error())
+	}
+	return signed, nil
+}
+
+// createRefreshToken create refresh token for session, valid until the session expires
+func (s *AuthService) createRefreshToken(session *entity.Session) (string, error) {
+	plainToken, err := generatePrefixedToken(token.RefreshPrefix)
+	if err != nil {
+		return "", errors.New("failed to generate refresh token: " + err.Error())
+	}
+
+	refreshToken := &entity.RefreshToken{
+		SessionID: session.ID,
+		UserID:    session.UserID,
+		Token:     plainToken,
+		TokenHash: token.Hash(plainToken),
+		ExpiresAt: session.ExpiresAt,
+	}
+	if err := s.refreshTokenRepo.Create(refreshToken); err != nil {
+		return "", errors.New("failed to create refresh token: " + err.Error())
+	}
+
+	return plainToken, nil
+}
+
+// revokeTokenFamily revoke session and all of its refresh tokens
+func (s *AuthService) revokeTokenFamily(sessionID uint) {
+	if err := s.refreshTokenRepo.RevokeBySessionID(sessionID); err != nil {
+		log.Printf("failed to revoke refresh tokens of session %d: %v", sessionID, err)
+	}
+
+	session, err := s.sessionRepo.FindByID(sessionID)
+	if err != nil {
+		return
+	}
+	session.Status = "revoked"
+	if err := s.sessionRepo.Update(session); err != nil {
+		log.Printf("failed to revoke session %d: %v", sessionID, err)
+	}
+}
+
 // Logout user logout
-func (s *AuthService) Logout(token string) error {
+func (s *AuthService) Logout(sessionID uint) error {
 	// Find session
-	session, err := s.sessionRepo.FindByToken(token)
+	session, err := s.sessionRepo.FindByID(sessionID)
 	if err != nil {
 		return err
 	}
@@ -194,26 +531,138 @@ func (s *AuthService) LogoutAll(userID uint) error {
 	return nil
 }
 
-// ForgotPassword forgot password
+// VerifyEmail verify email address with token sent on signup
+func (s *AuthService) VerifyEmail(req *VerifyEmailRequest) error {
+	// Find verification token
+	verificationToken, err := s.emailVerificationTokenRepo.FindByToken(req.Token)
+	if err != nil {
+		return err
+	}
+
+	// Check if token has been used
+	if verificationToken.Used {
+		return errors.New("verification token has been used")
+	}
+
+	// Check if token has expired
+	if verificationToken.IsExpired() {
+		return errors.New("verification token has expired")
+	}
+
+	// Find user
+	user, err := s.userRepo.FindByID(verificationToken.UserID)
+	if err != nil {
+		return errors.New("user does not exist")
+	}
+
+	// Token was issued for an address the user no longer has
+	if user.Email != verificationToken.Email {
+		return errors.New("verification token invalid")
+	}
+
+	// Mark email as verified and activate pending account
+	now := time.Now()
+	user.EmailVerifiedAt = &now
+	if user.Status == "pending_verification" {
+		user.Status = "active"
+	}
+	if err := s.userRepo.Update(user); err != nil {
+		return errors.New("failed to update user: " + err.Error())
+	}
+
+	// Mark token as used
+	verificationToken.Used = true
+	if err := s.emailVerificationTokenRepo.Update(verificationToken); err != nil {
+		return errors.New("failed to update token status: " + err.Error())
+	}
+
+	return nil
+}
+
+// resendVerificationMessage neutral response, returned whether or not the email exists
+const resendVerificationMessage = "If the email is registered and unverified, a verification link has been sent"
+
+// ResendVerification send a new verification email
+func (s *AuthService) ResendVerification(req *ResendVerificationRequest) (string, error) {
+	// Find user, for security return the same message for unknown and verified addresses
+	user, err := s.userRepo.FindByEmail(req.Email)
+	if err != nil || user.EmailVerifiedAt != nil {
+		return resendVerificationMessage, nil
+	}
+
+	if err := s.sendVerificationEmail(user); err != nil {
+		log.Printf("failed to send verification email to user %d: %v", user.ID, err)
+	}
+
+	return resendVerificationMessage, nil
+}
+
+// sendVerificationEmail create verification token and email verification link to user
+func (s *AuthService) sendVerificationEmail(user *entity.User) error {
+	plainToken, err := generatePrefixedToken(token.VerificationPrefix)
+	if err != nil {
+		return errors.New("failed to generate verification token: " + err.Error())
+	}
+
+	verificationToken := &entity.EmailVerificationToken{
+		UserID:    user.ID,
+		Email:     user.Email,
+		Token:     plainToken,
+		TokenHash: token.Hash(plainToken),
+		ExpiresAt: time.Now().Add(s.config.VerificationTokenTTL.Std()),
+	}
+	if err := s.emailVerificationTokenRepo.Create(verificationToken); err != nil {
+		return errors.New("failed to create verification token: " + err.Error())
+	}
+
+	msg, err := mail.Render("email_verification", user.Email, map[string]interface{}{
+		"Username":  user.Username,
+		"Email":     user.Email,
+		"Link":      withQuery(s.config.VerifyEmailURL, "token", plainToken),
+		"ExpiresIn": s.config.VerificationTokenTTL.String(),
+	})
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(msg)
+}
+
+// isUserAllowed checks if user status permits authentication
+func (s *AuthService) isUserAllowed(user *entity.User) bool {
+	switch user.Status {
+	case "active":
+		return true
+	case "pending_verification":
+		return s.config.AllowUnverifiedSignin
+	default:
+		return false
+	}
+}
+
+// forgotPasswordMessage neutral response, returned whether or not the email exists
+const forgotPasswordMessage = "If the email exists, reset link has been sent"
+
+// ForgotPassword forgot password, emails reset link to the user
 func (s *AuthService) ForgotPassword(req *ForgotPasswordRequest) (string, error) {
 	// Find user
 	user, err := s.userRepo.FindByEmail(req.Email)
 	if err != nil {
 		// For security, return success message even if email doesn't exist
-		return "If the email exists, reset link has been sent", nil
+		return forgotPasswordMessage, nil
 	}
 
 	// Generate reset token
-	token, err := generateToken()
+	plainToken, err := generatePrefixedToken(token.ResetPrefix)
 	if err != nil {
 		return "", errors.New("failed to generate reset token: " + err.Error())
 	}
 
-	// Create password reset token (valid for 1 hour)
+	// Create password reset token, only its digest is stored
 	resetToken := &entity.PasswordResetToken{
 		UserID:    user.ID,
-		Token:     token,
-		ExpiresAt: time.Now().Add(1 * time.Hour),
+		Token:     plainToken,
+		TokenHash: token.Hash(plainToken),
+		ExpiresAt: time.Now().Add(s.config.ResetTokenTTL.Std()),
 		Used:      false,
 	}
 
@@ -221,9 +670,20 @@ func (s *AuthService) ForgotPassword(req *ForgotPasswordRequest) (string, error)
 		return "", errors.New("failed to create reset token: " + err.Error())
 	}
 
-	// In actual application, email should be sent here
-	// Now only return token (production should send via email)
-	return token, nil
+	// Send reset email, delivery failures are logged only so the response does not reveal the account exists
+	msg, err := mail.Render("password_reset", user.Email, map[string]interface{}{
+		"Username":  user.Username,
+		"Link":      withQuery(s.config.ResetPasswordURL, "token", plainToken),
+		"ExpiresIn": s.config.ResetTokenTTL.String(),
+	})
+	if err != nil {
+		return "", err
+	}
+	if err := s.mailer.Send(msg); err != nil {
+		log.Printf("failed to send password reset email to user %d: %v", user.ID, err)
+	}
+
+	return forgotPasswordMessage, nil
 }
 
 // ResetPassword reset password
@@ -250,14 +710,19 @@ func (s *AuthService) ResetPassword(req *ResetPasswordRequest) error {
 		return errors.New("user does not exist")
 	}
 
+	// Check password policy
+	if err := s.passwordPolicy.Validate(req.NewPassword, user.Username, user.Email); err != nil {
+		return err
+	}
+
 	// Hash new password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	hashedPassword, err := s.passwordHasher.Hash(req.NewPassword)
 	if err != nil {
 		return errors.New("password encryption failed: " + err.Error())
 	}
 
 	// Update user password
-	user.Password = string(hashedPassword)
+	user.Password = hashedPassword
 	if err := s.userRepo.Update(user); err != nil {
 		return errors.New("failed to update password: " + err.Error())
 	}
@@ -277,8 +742,83 @@ func (s *AuthService) ResetPassword(req *ResetPasswordRequest) error {
 	return nil
 }
 
-// ValidateToken validate session token
+// ChangePassword change password of signed-in user, verifying the current password first
+func (s *AuthService) ChangePassword(userID, sessionID uint, req *ChangePasswordRequest, ipAddress, userAgent string) error {
+	// Find user, the authenticated user in context carries no password hash
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return errors.New("user does not exist")
+	}
+
+	// Guessing the current password counts towards account lockout like failed signins
+	userKey := UserKey(user.ID)
+	if err := s.loginThrottle.Check(userKey); err != nil {
+		return err
+	}
+
+	// Verify current password
+	valid, err := s.passwordHasher.Verify(req.CurrentPassword, user.Password)
+	if err != nil {
+		log.Printf("failed to verify password hash of user %d: %v", user.ID, err)
+	}
+	if !valid {
+		s.loginThrottle.RecordFailure(userKey, s.config.Lockout.MaxUserAttempts)
+		return errors.New("current password is incorrect")
+	}
+	if req.NewPassword == req.CurrentPassword {
+		return errors.New("new password must be different from current password")
+	}
+
+	// Check password policy
+	if err := s.passwordPolicy.Validate(req.NewPassword, user.Username, user.Email); err != nil {
+		return err
+	}
+
+	// Hash and update password
+	hashedPassword, err := s.passwordHasher.Hash(req.NewPassword)
+	if err != nil {
+		return errors.New("password encryption failed: " + err.Error())
+	}
+	user.Password = hashedPassword
+	if err := s.userRepo.Update(user); err != nil {
+		return errors.New("failed to update password: " + err.Error())
+	}
+
+	// Optionally log out every other session, keeping the one that made the change
+	details := ""
+	if req.RevokeOtherSessions {
+		if err := s.sessionRepo.UpdateStatusByUserIDExcept(user.ID, sessionID, "logout"); err != nil {
+			return errors.New("failed to logout other sessions: " + err.Error())
+		}
+		details = "other sessions revoked"
+	}
+
+	s.recordAuditEvent(user.ID, &sessionID, "password_changed", ipAddress, userAgent, details)
+
+	return nil
+}
+
+// recordAuditEvent stores audit event, failures are logged and do not affect the audited action
+func (s *AuthService) recordAuditEvent(userID uint, sessionID *uint, event, ipAddress, userAgent, details string) {
+	auditEvent := &entity.AuditEvent{
+		UserID:    userID,
+		SessionID: sessionID,
+		Event:     event,
+		IPAddress: ipAddress,
+		UserAgent: userAgent,
+		Details:   details,
+	}
+	if err := s.auditEventRepo.Create(auditEvent); err != nil {
+		log.Printf("failed to record %s audit event of user %d: %v", event, userID, err)
+	}
+}
+
+// ValidateToken validate access token
 func (s *AuthService) ValidateToken(token string) (*entity.Session, *entity.User, error) {
+	if s.config.TokenFormat == "jwt" {
+		return s.validateJWT(token)
+	}
+
 	// Find session
 	session, err := s.sessionRepo.FindByToken(token)
 	if err != nil {
@@ -290,10 +830,107 @@ func (s *AuthService) ValidateToken(token string) (*entity.Session, *entity.User
 		return nil, nil, errors.New("session has expired")
 	}
 
-	// Update last used time
-	now := time.Now()
-	if err := s.sessionRepo.UpdateLastUsedAt(session.ID, now); err != nil {
-		// Log error but don't affect validation flow
+	// Check if access token is still valid, expired access tokens must be refreshed
+	if session.IsAccessTokenExpired() {
+		return nil, nil, errors.New("access token has expired")
+	}
+
+	// Record use and slide expiration, idle sessions are ended
+	if err := s.touchSession(session); err != nil {
+		return nil, nil, err
+	}
+
+	// Find user
+	user, err := s.userRepo.FindByID(session.UserID)
+	if err != nil {
+		return nil, nil, errors.New("user does not exist")
+	}
+
+	// Check user status
+	if !s.isUserAllowed(user) {
+		return nil, nil, errors.New("user has been disabled")
+	}
+
+	// Clear password field
+	user.Password = ""
+
+	return session, user, nil
+}
+
+// IdentityAssertion issue short-lived signed assertion of validated user and session, which other
+// services can verify with the published JWKS instead of calling the validate endpoint
+func (s *AuthService) IdentityAssertion(session *entity.Session, user *entity.User) (string, error) {
+	if s.signer == nil {
+		return "", errors.New("signing keys are not configured")
+	}
+
+	claims := &token.IdentityClaims{
+		Username: user.Username,
+		Email:    user.Email,
+		Status:   user.Status,
+	}
+	claims.Role = user.Role
+	claims.SessionID = session.ID
+
+	expiresAt := time.Now().Add(s.config.JWT.AssertionTTL.Std())
+	assertion, err := s.signer.SignIdentity(claims, user.ID, expiresAt)
+	if err != nil {
+		return "", errors.New("failed to sign identity assertion: " + err.Error())
+	}
+	return assertion, nil
+}
+
+// CanSignAssertions checks if identity assertions can be issued
+func (s *AuthService) CanSignAssertions() bool {
+	return s.signer != nil
+}
+
+// validateJWT verify JWT access token locally. With revocation checks enabled the session is
+// loaded to make sure it is still active and the token has not been rotated; otherwise user and
+// session are built from the token claims without touching the database.
+func (s *AuthService) validateJWT(accessToken string) (*entity.Session, *entity.User, error) {
+	claims, err := s.signer.Verify(accessToken)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	userID, err := claims.UserID()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !s.config.JWT.RevocationCheck {
+		session := &entity.Session{
+			ID:              claims.SessionID,
+			UserID:          userID,
+			Status:          "active",
+			AccessExpiresAt: claims.ExpiresAt.Time,
+		}
+		user := &entity.User{
+			ID:     userID,
+			Role:   claims.Role,
+			Status: "active",
+		}
+		return session, user, nil
+	}
+
+	// Find session
+	session, err := s.sessionRepo.FindByID(claims.SessionID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Check if session is valid and token belongs to its current rotation
+	if !session.IsActive() || session.UserID != userID {
+		return nil, nil, errors.New("session has expired")
+	}
+	if session.JTIHash != token.Hash(claims.ID) {
+		return nil, nil, errors.New("access token has been revoked")
+	}
+
+	// Record use and slide expiration, idle sessions are ended
+	if err := s.touchSession(session); err != nil {
+		return nil, nil, err
 	}
 
 	// Find user
@@ -303,7 +940,7 @@ func (s *AuthService) ValidateToken(token string) (*entity.Session, *entity.User
 	}
 
 	// Check user status
-	if user.Status != "active" {
+	if !s.isUserAllowed(user) {
 		return nil, nil, errors.New("user has been disabled")
 	}
 
@@ -313,6 +950,15 @@ func (s *AuthService) ValidateToken(token string) (*entity.Session, *entity.User
 	return session, user, nil
 }
 
+// withQuery appends query parameter to URL
+func withQuery(rawURL, key, value string) string {
+	separator := "?"
+	if strings.Contains(rawURL, "?") {
+		separator = "&"
+	}
+	return rawURL + separator + url.QueryEscape(key) + "=" + url.QueryEscape(value)
+}
+
 // generateToken generate random token
 func generateToken() (string, error) {
 	bytes := make([]byte, 32)
@@ -321,3 +967,12 @@ func generateToken() (string, error) {
 	}
 	return hex.EncodeToString(bytes), nil
 }
+
+// generatePrefixedToken generate random token starting with prefix that identifies its kind
+func generatePrefixedToken(prefix string) (string, error) {
+	value, err := generateToken()
+	if err != nil {
+		return "", err
+	}
+	return prefix + value, nil
+}
@@ -0,0 +1,314 @@
+package service
+
+import (
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/damonleelcx/go-gin-api/config"
+	"github.com/damonleelcx/go-gin-api/entity"
+	"github.com/damonleelcx/go-gin-api/mail"
+	"github.com/damonleelcx/go-gin-api/migration"
+	"github.com/damonleelcx/go-gin-api/password"
+	"github.com/damonleelcx/go-gin-api/token"
+)
+
+func TestAuthFlow(t *testing.T) {
+	env := newTestEnv(t)
+
+	// Signup leaves the account pending until the emailed link is followed
+	signup := env.signup(t, "leo")
+	if signup.User.Status != "pending_verification" {
+		t.Errorf("status after signup = %q, want pending_verification", signup.User.Status)
+	}
+	user, err := env.auth.userRepo.FindByID(signup.User.ID)
+	if err != nil {
+		t.Fatalf("find user: %v", err)
+	}
+	if user.Status != "active" || user.EmailVerifiedAt == nil {
+		t.Errorf("user after verification = %q verified at %v, want active and verified", user.Status, user.EmailVerifiedAt)
+	}
+
+	if _, err := env.auth.Signin(&SigninRequest{Username: "leo", Password: "wrong-password"}, testIP, testUserAgent); err == nil {
+		t.Error("signin accepted wrong password")
+	}
+
+	// Signin by email issues access and refresh token
+	signin, err := env.auth.Signin(&SigninRequest{Username: "leo@example.com", Password: testPassword}, testIP, testUserAgent)
+	if err != nil {
+		t.Fatalf("signin: %v", err)
+	}
+	if signin.Token == "" || signin.RefreshToken == "" {
+		t.Fatalf("signin returned token %q and refresh token %q", signin.Token, signin.RefreshToken)
+	}
+	session, validated, err := env.auth.ValidateToken(signin.Token)
+	if err != nil {
+		t.Fatalf("validate token: %v", err)
+	}
+	if session.ID != signin.Session.ID || validated.ID != user.ID {
+		t.Errorf("validated session %d of user %d, want session %d of user %d", session.ID, validated.ID, signin.Session.ID, user.ID)
+	}
+
+	// Refresh rotates both tokens
+	refreshed, err := env.auth.Refresh(&RefreshRequest{RefreshToken: signin.RefreshToken}, testIP, testUserAgent)
+	if err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	if refreshed.Token == signin.Token || refreshed.RefreshToken == signin.RefreshToken {
+		t.Error("refresh did not rotate tokens")
+	}
+	if _, _, err := env.auth.ValidateToken(signin.Token); err == nil {
+		t.Error("access token replaced by refresh is still valid")
+	}
+	if _, _, err := env.auth.ValidateToken(refreshed.Token); err != nil {
+		t.Fatalf("validate refreshed token: %v", err)
+	}
+
+	// Replaying the rotated refresh token revokes the session
+	if _, err := env.auth.Refresh(&RefreshRequest{RefreshToken: signin.RefreshToken}, testIP, testUserAgent); err == nil {
+		t.Fatal("rotated refresh token was accepted again")
+	}
+	if _, _, err := env.auth.ValidateToken(refreshed.Token); err == nil {
+		t.Error("session is still valid after refresh token reuse")
+	}
+
+	// Reverting every migration leaves no application tables behind
+	migrations := migration.All()
+	if _, err := migration.NewMigrator(env.db).Down(len(migrations)); err != nil {
+		t.Fatalf("migrate down: %v", err)
+	}
+	for _, table := range []string{"users", "sessions", "refresh_tokens", "password_reset_tokens"} {
+		if env.db.Migrator().HasTable(table) {
+			t.Errorf("table %s exists after reverting all migrations", table)
+		}
+	}
+}
+
+func TestSigninUnverified(t *testing.T) {
+	env := newTestEnv(t, func(cfg *config.Config) {
+		cfg.Auth.Lockout.FreeAttempts = 1
+		cfg.Auth.Lockout.MaxUserAttempts = 2
+	})
+	if _, err := env.auth.Signup(&SignupRequest{Username: "mia", Email: "mia@example.com", Password: testPassword}, testIP, testUserAgent); err != nil {
+		t.Fatalf("signup: %v", err)
+	}
+
+	// A wrong password must not reveal that the account exists but is unverified
+	_, err := env.auth.Signin(&SigninRequest{Username: "mia", Password: "wrong-password"}, testIP, testUserAgent)
+	if err == nil || err.Error() != "username or password incorrect" {
+		t.Fatalf("signin with wrong password = %v, want username or password incorrect", err)
+	}
+	_, err = env.auth.Signin(&SigninRequest{Username: "mia", Password: testPassword}, testIP, testUserAgent)
+	if err == nil || err.Error() != "email address has not been verified" {
+		t.Fatalf("signin with correct password = %v, want email address has not been verified", err)
+	}
+
+	// Failures against unverified accounts count towards lockout
+	env.auth.Signin(&SigninRequest{Username: "mia", Password: "wrong-password"}, testIP, testUserAgent)
+	_, err = env.auth.Signin(&SigninRequest{Username: "mia", Password: testPassword}, testIP, testUserAgent)
+	var throttled *TooManyAttemptsError
+	if !errors.As(err, &throttled) {
+		t.Fatalf("signin after repeated failures = %v, want too many attempts", err)
+	}
+}
+
+func TestJWTAccessTokenID(t *testing.T) {
+	env := newTestEnv(t, func(cfg *config.Config) {
+		cfg.Auth.TokenFormat = "jwt"
+		cfg.Auth.JWT.Secret = "0123456789abcdef0123456789abcdef"
+	})
+	env.signup(t, "leo")
+	signin := env.signin(t, "leo")
+
+	// The ID claim is a random value of its own, not the session token
+	claims, err := env.auth.signer.Verify(signin.Token)
+	if err != nil {
+		t.Fatalf("verify access token: %v", err)
+	}
+	session, err := env.auth.sessionRepo.FindByID(signin.Session.ID)
+	if err != nil {
+		t.Fatalf("find session: %v", err)
+	}
+	if token.Hash(claims.ID) == session.TokenHash || claims.ID == signin.Session.Token {
+		t.Error("access token ID claim is the session token")
+	}
+	if session.JTIHash != token.Hash(claims.ID) {
+		t.Errorf("session jti hash = %q, want digest of the ID claim", session.JTIHash)
+	}
+	if _, _, err := env.auth.ValidateToken(signin.Token); err != nil {
+		t.Fatalf("validate access token: %v", err)
+	}
+
+	// Refresh issues a new ID, revoking the previous access token
+	refreshed, err := env.auth.Refresh(&RefreshRequest{RefreshToken: signin.RefreshToken}, testIP, testUserAgent)
+	if err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	refreshedClaims, err := env.auth.signer.Verify(refreshed.Token)
+	if err != nil {
+		t.Fatalf("verify refreshed access token: %v", err)
+	}
+	if refreshedClaims.ID == claims.ID {
+		t.Error("refresh kept the access token ID")
+	}
+	if _, _, err := env.auth.ValidateToken(signin.Token); err == nil {
+		t.Error("access token replaced by refresh is still valid")
+	}
+	if _, _, err := env.auth.ValidateToken(refreshed.Token); err != nil {
+		t.Errorf("validate refreshed access token: %v", err)
+	}
+}
+
+func TestSigninRehashesPassword(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.signup(t, "nia").User
+
+	outdated := []struct {
+		name   string
+		config config.PasswordHashingConfig
+	}{
+		{"bcrypt cost", config.PasswordHashingConfig{Algorithm: "bcrypt", BcryptCost: 5}},
+		{"argon2id", config.PasswordHashingConfig{Algorithm: "argon2id", BcryptCost: 4, Argon2id: config.Argon2idConfig{Memory: 64, Iterations: 1, Parallelism: 1}}},
+	}
+	for _, tt := range outdated {
+		t.Run(tt.name, func(t *testing.T) {
+			hasher, err := password.NewHasher(tt.config)
+			if err != nil {
+				t.Fatalf("hasher: %v", err)
+			}
+			hash, err := hasher.Hash(testPassword)
+			if err != nil {
+				t.Fatalf("hash: %v", err)
+			}
+			if err := env.db.Model(&entity.User{}).Where("id = ?", user.ID).Update("password", hash).Error; err != nil {
+				t.Fatalf("store outdated hash: %v", err)
+			}
+
+			// A failed signin leaves the hash alone
+			if _, err := env.auth.Signin(&SigninRequest{Username: "nia", Password: "wrong-password"}, testIP, testUserAgent); err == nil {
+				t.Fatal("signin with wrong password succeeded")
+			}
+			var stored entity.User
+			if err := env.db.First(&stored, user.ID).Error; err != nil {
+				t.Fatalf("load user: %v", err)
+			}
+			if stored.Password != hash {
+				t.Fatalf("failed signin rewrote password hash to %s", stored.Password)
+			}
+
+			env.signin(t, "nia")
+			if err := env.db.First(&stored, user.ID).Error; err != nil {
+				t.Fatalf("load user: %v", err)
+			}
+			if !strings.HasPrefix(stored.Password, "$2a$04$") {
+				t.Fatalf("password hash after signin = %s, want bcrypt cost 4", stored.Password)
+			}
+
+			// The rewritten hash still signs in and is left as it is
+			rehashed := stored.Password
+			env.signin(t, "nia")
+			if err := env.db.First(&stored, user.ID).Error; err != nil {
+				t.Fatalf("load user: %v", err)
+			}
+			if stored.Password != rehashed {
+				t.Error("current password hash was rewritten at signin")
+			}
+		})
+	}
+}
+
+// countingHasher counts password verifications
+type countingHasher struct {
+	password.Hasher
+	verified []string
+}
+
+// Verify implements password.Hasher
+func (h *countingHasher) Verify(plaintext, encoded string) (bool, error) {
+	h.verified = append(h.verified, encoded)
+	return h.Hasher.Verify(plaintext, encoded)
+}
+
+func TestSigninUnknownUserVerifiesHash(t *testing.T) {
+	env := newTestEnv(t)
+	hasher := &countingHasher{Hasher: env.auth.passwordHasher}
+	env.auth.passwordHasher = hasher
+
+	// An unknown username takes a hash verification like a wrong password does
+	_, err := env.auth.Signin(&SigninRequest{Username: "nobody", Password: testPassword}, testIP, testUserAgent)
+	if err == nil || err.Error() != "username or password incorrect" {
+		t.Fatalf("signin of unknown user = %v, want username or password incorrect", err)
+	}
+	if len(hasher.verified) != 1 {
+		t.Fatalf("signin of unknown user verified %d hashes, want 1", len(hasher.verified))
+	}
+	if !strings.HasPrefix(hasher.verified[0], "$2a$04$") {
+		t.Errorf("unknown user verified against %q, want hash of the current settings", hasher.verified[0])
+	}
+}
+
+func TestForgotPasswordFileMailer(t *testing.T) {
+	env := newTestEnv(t)
+	env.signup(t, "hana")
+
+	dir := t.TempDir()
+	mailer, err := mail.NewFileMailer(dir, "noreply@example.com")
+	if err != nil {
+		t.Fatalf("file mailer: %v", err)
+	}
+	env.auth.mailer = mailer
+
+	tests := []struct {
+		name     string
+		email    string
+		wantMail bool
+	}{
+		{name: "unknown", email: "nobody@example.com", wantMail: false},
+		{name: "known", email: "hana@example.com", wantMail: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before, _ := os.ReadDir(dir)
+			message, err := env.auth.ForgotPassword(&ForgotPasswordRequest{Email: tt.email})
+			if err != nil || message != forgotPasswordMessage {
+				t.Fatalf("ForgotPassword(%s) = %q, %v, want %q", tt.email, message, err, forgotPasswordMessage)
+			}
+			after, _ := os.ReadDir(dir)
+			if got := len(after) > len(before); got != tt.wantMail {
+				t.Errorf("ForgotPassword(%s) wrote mail %v, want %v", tt.email, got, tt.wantMail)
+			}
+		})
+	}
+
+	// The written message is addressed to the user and its link resets the password
+	files, _ := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if len(files) != 1 {
+		t.Fatalf("wrote %d messages, want 1", len(files))
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatalf("read message: %v", err)
+	}
+	for _, want := range []string{"From: noreply@example.com\r\n", "To: hana@example.com\r\n", "Subject: Reset your password\r\n"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("message does not contain %q:\n%s", want, data)
+		}
+	}
+	match := tokenPattern.FindSubmatch(data)
+	if match == nil {
+		t.Fatalf("no reset link in message:\n%s", data)
+	}
+	resetToken, err := url.QueryUnescape(string(match[1]))
+	if err != nil {
+		t.Fatalf("unescape token: %v", err)
+	}
+	if err := env.auth.ResetPassword(&ResetPasswordRequest{Token: resetToken, NewPassword: "amber-falcon-17"}); err != nil {
+		t.Fatalf("reset password with mailed token: %v", err)
+	}
+	if _, err := env.signinFrom("hana", "amber-falcon-17", testIP); err != nil {
+		t.Errorf("signin with reset password: %v", err)
+	}
+}
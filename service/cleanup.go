@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"log"
+	"time"
+
+	"github.com/damonleelcx/go-gin-api/config"
+	"github.com/damonleelcx/go-gin-api/entity"
+	"github.com/damonleelcx/go-gin-api/repository"
+)
+
+// cleanupMetrics counters of the cleanup job, published as "cleanup" by expvar
+var cleanupMetrics = expvar.NewMap("cleanup")
+
+// purgeTarget table whose rows are deleted once column is older than the retention window
+type purgeTarget struct {
+	name   string
+	model  interface{}
+	column string
+}
+
+// purgeTargets tables purged besides sessions. Refresh tokens of purged sessions are deleted with them,
+// the refresh token target catches tokens expiring while their session is still active.
+var purgeTargets = []purgeTarget{
+	{"refresh_tokens", &entity.RefreshToken{}, "expires_at"},
+	{"password_reset_tokens", &entity.PasswordResetToken{}, "expires_at"},
+	{"email_verification_tokens", &entity.EmailVerificationToken{}, "expires_at"},
+	{"mfa_challenges", &entity.MFAChallenge{}, "expires_at"},
+	{"webauthn_ceremonies", &entity.WebAuthnCeremony{}, "expires_at"},
+	{"login_tokens", &entity.LoginToken{}, "expires_at"},
+	{"oauth_states", &entity.OAuthState{}, "expires_at"},
+	{"login_attempts", &entity.LoginAttempt{}, "last_failed_at"},
+}
+
+// CleanupService marks expired sessions and purges stale rows periodically
+type CleanupService struct {
+	cleanupRepo           repository.CleanupRepository
+	config                config.CleanupConfig
+	idleTimeout           time.Duration // Idle timeout of sessions, 0 disables
+	rememberMeIdleTimeout time.Duration // Idle timeout of remember me sessions, 0 disables
+}
+
+// NewCleanupService creates a new cleanup service instance, idle timeouts of sessions are taken from authCfg
+func NewCleanupService(cleanupRepo repository.CleanupRepository, cfg config.CleanupConfig, authCfg config.AuthConfig) *CleanupService {
+	// Remember me sessions follow the default policy while remember me is disabled, like in sessionPolicy
+	rememberMeIdleTimeout := authCfg.IdleTimeout.Std()
+	if authCfg.RememberMe.Enabled {
+		rememberMeIdleTimeout = authCfg.RememberMe.IdleTimeout.Std()
+	}
+
+	return &CleanupService{
+		cleanupRepo:           cleanupRepo,
+		config:                cfg,
+		idleTimeout:           authCfg.IdleTimeout.Std(),
+		rememberMeIdleTimeout: rememberMeIdleTimeout,
+	}
+}
+
+// Run runs cleanup on start and then every interval until ctx is done, a run in progress stops
+// between batches
+func (s *CleanupService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.config.Interval.Std())
+	defer ticker.Stop()
+
+	for {
+		if err := s.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			cleanupMetrics.Add("failures", 1)
+			log.Printf("cleanup failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce expires sessions past their expiration or idle timeout and purges rows older than the retention window
+func (s *CleanupService) RunOnce(ctx context.Context) error {
+	now := time.Now()
+	cutoff := now.Add(-s.config.Retention.Std())
+	cleanupMetrics.Add("runs", 1)
+
+	expired, err := s.cleanupRepo.ExpireSessions(now, s.idleTimeout, s.rememberMeIdleTimeout)
+	if err != nil {
+		return errors.New("failed to expire sessions: " + err.Error())
+	}
+	cleanupMetrics.Add("sessions_expired", expired)
+
+	purged, err := s.purge(ctx, func() (int64, error) {
+		return s.cleanupRepo.PurgeSessions(cutoff, s.config.BatchSize)
+	})
+	cleanupMetrics.Add("sessions_purged", purged)
+	if err != nil {
+		return errors.New("failed to purge sessions: " + err.Error())
+	}
+
+	for _, target := range purgeTargets {
+		purged, err := s.purge(ctx, func() (int64, error) {
+			return s.cleanupRepo.PurgeBefore(target.model, target.column, cutoff, s.config.BatchSize)
+		})
+		cleanupMetrics.Add(target.name+"_purged", purged)
+		if err != nil {
+			return errors.New("failed to purge " + target.name + ": " + err.Error())
+		}
+	}
+
+	lastRun := new(expvar.Int)
+	lastRun.Set(now.Unix())
+	cleanupMetrics.Set("last_run_unix", lastRun)
+	return nil
+}
+
+// purge repeats batch until it deletes less than a full batch, short batches keep each statement's locks brief
+func (s *CleanupService) purge(ctx context.Context, batch func() (int64, error)) (int64, error) {
+	var total int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+		deleted, err := batch()
+		total += deleted
+		if err != nil {
+			return total, err
+		}
+		if deleted < int64(s.config.BatchSize) {
+			return total, nil
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/damonleelcx/go-gin-api/config"
+	"github.com/damonleelcx/go-gin-api/entity"
+	"github.com/damonleelcx/go-gin-api/repository"
+)
+
+// cleanupMetric returns value of cleanup counter name, 0 if it was never set
+func cleanupMetric(name string) int64 {
+	if value, ok := cleanupMetrics.Get(name).(*expvar.Int); ok {
+		return value.Value()
+	}
+	return 0
+}
+
+// newTestCleanup creates cleanup service on the database of env with batches of batchSize rows
+func (e *testEnv) newTestCleanup(batchSize int) *CleanupService {
+	return NewCleanupService(repository.NewCleanupRepository(e.db), config.CleanupConfig{
+		Interval:  config.Duration(time.Hour),
+		Retention: config.Duration(24 * time.Hour),
+		BatchSize: batchSize,
+	}, e.cfg.Auth)
+}
+
+func TestCleanupPurgeBatches(t *testing.T) {
+	service := &CleanupService{config: config.CleanupConfig{BatchSize: 3}}
+	failure := errors.New("database is locked")
+
+	tests := []struct {
+		name      string
+		batches   []int64 // Rows deleted by consecutive batches
+		failAt    int     // Batch returning failure, -1 for none
+		cancelAt  int     // Batch after which the context is canceled, -1 for none
+		wantCalls int
+		wantTotal int64
+		wantErr   error
+	}{
+		{name: "nothing to delete", batches: []int64{0}, failAt: -1, cancelAt: -1, wantCalls: 1, wantTotal: 0},
+		{name: "single short batch", batches: []int64{2}, failAt: -1, cancelAt: -1, wantCalls: 1, wantTotal: 2},
+		{name: "until a short batch", batches: []int64{3, 3, 1}, failAt: -1, cancelAt: -1, wantCalls: 3, wantTotal: 7},
+		{name: "until an empty batch", batches: []int64{3, 3, 0}, failAt: -1, cancelAt: -1, wantCalls: 3, wantTotal: 6},
+		{name: "failure keeps deleted count", batches: []int64{3, 3, 3}, failAt: 1, cancelAt: -1, wantCalls: 2, wantTotal: 6, wantErr: failure},
+		{name: "canceled between batches", batches: []int64{3, 3, 3}, failAt: -1, cancelAt: 1, wantCalls: 2, wantTotal: 6, wantErr: context.Canceled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			calls := 0
+			total, err := service.purge(ctx, func() (int64, error) {
+				call := calls
+				calls++
+				if call == tt.cancelAt {
+					cancel()
+				}
+				if call == tt.failAt {
+					return tt.batches[call], failure
+				}
+				return tt.batches[call], nil
+			})
+			if calls != tt.wantCalls || total != tt.wantTotal || !errors.Is(err, tt.wantErr) {
+				t.Errorf("purge() = %d, %v after %d batches, want %d, %v after %d", total, err, calls, tt.wantTotal, tt.wantErr, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestCleanupRunOnce(t *testing.T) {
+	env := newTestEnv(t, sessionPolicyConfig)
+	user := env.signup(t, "rina").User
+
+	// Two sessions idle past the timeout, one live
+	idle := []uint{env.signin(t, "rina").Session.ID, env.signin(t, "rina").Session.ID}
+	live := env.signin(t, "rina").Session.ID
+	env.db.Model(&entity.Session{}).Where("id IN ?", idle).Update("last_used_at", time.Now().Add(-31*time.Minute))
+
+	// Five stale login attempts and verification tokens of the signup past the retention window
+	for i := 0; i < 5; i++ {
+		env.db.Create(&entity.LoginAttempt{TrackingKey: "ip:198.51.100." + strconv.Itoa(i), Failures: 1, LastFailedAt: time.Now().Add(-48 * time.Hour)})
+	}
+	env.db.Model(&entity.EmailVerificationToken{}).Where("user_id = ?", user.ID).Update("expires_at", time.Now().Add(-48*time.Hour))
+
+	before := map[string]int64{}
+	names := []string{"runs", "failures", "sessions_expired", "sessions_purged", "login_attempts_purged", "email_verification_tokens_purged"}
+	for _, name := range names {
+		before[name] = cleanupMetric(name)
+	}
+
+	cleanup := env.newTestCleanup(2)
+	if err := cleanup.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+
+	got := map[string]int64{}
+	for _, name := range names {
+		got[name] = cleanupMetric(name) - before[name]
+	}
+	want := map[string]int64{
+		"runs":                             1,
+		"failures":                         0,
+		"sessions_expired":                 2,
+		"sessions_purged":                  0, // Expired just now, kept for the retention window
+		"login_attempts_purged":            5,
+		"email_verification_tokens_purged": 1,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("counter increments = %v, want %v", got, want)
+	}
+	if lastRun := cleanupMetric("last_run_unix"); time.Since(time.Unix(lastRun, 0)) > time.Minute {
+		t.Errorf("last_run_unix = %d, want now", lastRun)
+	}
+
+	statuses := map[uint]string{}
+	var sessions []entity.Session
+	env.db.Where("user_id = ?", user.ID).Find(&sessions)
+	for _, session := range sessions {
+		statuses[session.ID] = session.Status
+	}
+	if statuses[idle[0]] != "expired" || statuses[idle[1]] != "expired" || statuses[live] != "active" {
+		t.Errorf("session statuses = %v, want idle sessions %v expired and %d active", statuses, idle, live)
+	}
+
+	// Ended sessions are purged once past the retention window
+	env.db.Model(&entity.Session{}).Where("id IN ?", idle).UpdateColumn("updated_at", time.Now().Add(-48*time.Hour))
+	purgedBefore := cleanupMetric("sessions_purged")
+	if err := cleanup.RunOnce(context.Background()); err != nil {
+		t.Fatalf("second RunOnce: %v", err)
+	}
+	if purged := cleanupMetric("sessions_purged") - purgedBefore; purged != 2 {
+		t.Errorf("second run purged %d sessions, want 2", purged)
+	}
+}
+
+func TestCleanupIdleTimeoutPolicies(t *testing.T) {
+	tests := []struct {
+		name               string
+		configure          func(cfg *config.AuthConfig)
+		wantIdle           time.Duration
+		wantRememberMeIdle time.Duration
+	}{
+		{
+			name: "remember me enabled",
+			configure: func(cfg *config.AuthConfig) {
+				cfg.IdleTimeout = config.Duration(30 * time.Minute)
+				cfg.RememberMe = config.RememberMeConfig{Enabled: true, IdleTimeout: config.Duration(2 * time.Hour)}
+			},
+			wantIdle:           30 * time.Minute,
+			wantRememberMeIdle: 2 * time.Hour,
+		},
+		{
+			name: "remember me disabled follows default policy",
+			configure: func(cfg *config.AuthConfig) {
+				cfg.IdleTimeout = config.Duration(30 * time.Minute)
+				cfg.RememberMe = config.RememberMeConfig{Enabled: false, IdleTimeout: config.Duration(2 * time.Hour)}
+			},
+			wantIdle:           30 * time.Minute,
+			wantRememberMeIdle: 30 * time.Minute,
+		},
+		{
+			name: "idle timeouts disabled",
+			configure: func(cfg *config.AuthConfig) {
+				cfg.IdleTimeout = 0
+				cfg.RememberMe = config.RememberMeConfig{Enabled: true}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.Default().Auth
+			tt.configure(&cfg)
+			cleanup := NewCleanupService(nil, config.CleanupConfig{}, cfg)
+			if cleanup.idleTimeout != tt.wantIdle || cleanup.rememberMeIdleTimeout != tt.wantRememberMeIdle {
+				t.Errorf("idle timeouts = %v, %v, want %v, %v", cleanup.idleTimeout, cleanup.rememberMeIdleTimeout, tt.wantIdle, tt.wantRememberMeIdle)
+			}
+		})
+	}
+}
@@ -0,0 +1,242 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"time"
+
+	"github.com/damonleelcx/go-gin-api/entity"
+	"github.com/damonleelcx/go-gin-api/mail"
+	"github.com/damonleelcx/go-gin-api/token"
+)
+
+// RequestLoginLinkRequest passwordless login request
+type RequestLoginLinkRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ExchangeLoginLinkRequest passwordless login exchange request, either the token from the link
+// or the email together with the code
+type ExchangeLoginLinkRequest struct {
+	Token string `json:"token"`
+	Email string `json:"email" binding:"omitempty,email"`
+	Code  string `json:"code"`
+}
+
+// loginLinkMessage neutral response, returned whether or not the email exists
+const loginLinkMessage = "If the email is registered, a sign-in link has been sent"
+
+// LoginLinksEnabled reports whether passwordless login by email is enabled
+func (s *AuthService) LoginLinksEnabled() bool {
+	return s.config.LoginLink.Enabled
+}
+
+// RequestLoginLink emails single-use login link and code to the user
+func (s *AuthService) RequestLoginLink(req *RequestLoginLinkRequest, ipAddress, userAgent string) (string, error) {
+	// Find user, for security return the same message for unknown and disabled accounts
+	user, err := s.userRepo.FindByEmail(req.Email)
+	if err != nil || !s.canUseLoginLink(user) {
+		return loginLinkMessage, nil
+	}
+
+	plainToken, err := generatePrefixedToken(token.LoginLinkPrefix)
+	if err != nil {
+		return "", errors.New("failed to generate login token: " + err.Error())
+	}
+	code, err := generateLoginCode()
+	if err != nil {
+		return "", errors.New("failed to generate login code: " + err.Error())
+	}
+
+	loginToken := &entity.LoginToken{
+		UserID:    user.ID,
+		Email:     user.Email,
+		Token:     plainToken,
+		TokenHash: token.Hash(plainToken),
+		Code:      code,
+		CodeHash:  loginCodeHash(token.Hash(plainToken), code),
+		IPAddress: ipAddress,
+		UserAgent: userAgent,
+		ExpiresAt: time.Now().Add(s.config.LoginLink.TTL.Std()),
+	}
+	if err := s.loginTokenRepo.Create(loginToken); err != nil {
+		return "", errors.New("failed to create login token: " + err.Error())
+	}
+
+	// Send login email, delivery failures are logged only so the response does not reveal the account exists
+	msg, err := mail.Render("login_link", user.Email, map[string]interface{}{
+		"Username":  user.Username,
+		"Link":      withQuery(s.config.LoginLink.URL, "token", plainToken),
+		"Code":      code,
+		"ExpiresIn": s.config.LoginLink.TTL.String(),
+	})
+	if err != nil {
+		return "", err
+	}
+	if err := s.mailer.Send(msg); err != nil {
+		log.Printf("failed to send login link to user %d: %v", user.ID, err)
+	}
+
+	return loginLinkMessage, nil
+}
+
+// ExchangeLoginLink signs in with login link token or emailed code, creating a session like Signin
+func (s *AuthService) ExchangeLoginLink(req *ExchangeLoginLinkRequest, ipAddress, userAgent string) (*SigninResponse, error) {
+	// Check client IP throttling
+	ipKey := IPKey(ipAddress)
+	if err := s.loginThrottle.Check(ipKey); err != nil {
+		return nil, err
+	}
+
+	// Find login token
+	var loginToken *entity.LoginToken
+	var err error
+	switch {
+	case req.Token != "":
+		loginToken, err = s.loginTokenRepo.FindByToken(req.Token)
+		if err != nil {
+			s.loginThrottle.RecordFailure(ipKey, s.config.Lockout.MaxIPAttempts)
+			return nil, err
+		}
+	case req.Email != "" && req.Code != "":
+		loginToken, err = s.findLoginTokenByCode(req.Email, req.Code, ipKey)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.New("token or email and code are required")
+	}
+
+	// Check if token has been used or expired
+	if loginToken.Used {
+		return nil, errors.New("login token has been used")
+	}
+	if loginToken.IsExpired() {
+		return nil, errors.New("login token has expired")
+	}
+
+	// Find user
+	user, err := s.userRepo.FindByID(loginToken.UserID)
+	if err != nil {
+		return nil, errors.New("user does not exist")
+	}
+
+	// Token was issued for an address the user no longer has
+	if user.Email != loginToken.Email {
+		return nil, errors.New("login token invalid")
+	}
+
+	// Check account throttling and user status
+	userKey := UserKey(user.ID)
+	if err := s.loginThrottle.Check(userKey); err != nil {
+		return nil, err
+	}
+	if !s.canUseLoginLink(user) {
+		return nil, errors.New("account has been disabled")
+	}
+
+	// Conditional update so that concurrent exchanges of the same token cannot both succeed
+	marked, err := s.loginTokenRepo.MarkUsed(loginToken.ID)
+	if err != nil {
+		return nil, errors.New("failed to update token status: " + err.Error())
+	}
+	if !marked {
+		return nil, errors.New("login token has been used")
+	}
+
+	// Receiving the email proves the address, activate pending account
+	if user.EmailVerifiedAt == nil {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+		if user.Status == "pending_verification" {
+			user.Status = "active"
+		}
+		if err := s.userRepo.Update(user); err != nil {
+			return nil, errors.New("failed to update user: " + err.Error())
+		}
+	}
+
+	// The email replaces the password only, the second factor is still required
+	if user.TOTPEnabled {
+		return s.createMFAChallenge(user, false, ipAddress, userAgent)
+	}
+
+	// Successful login clears account failures, IP failures only expire over time
+	if err := s.loginThrottle.Reset(userKey); err != nil {
+		log.Printf("failed to reset login attempts of user %d: %v", user.ID, err)
+	}
+
+	// Create session
+	response, err := s.createSession(user, false, ipAddress, userAgent)
+	if err != nil {
+		return nil, err
+	}
+	response.Message = "Login successful"
+
+	return response, nil
+}
+
+// findLoginTokenByCode finds latest login token of user with email and checks its code,
+// wrong codes count towards the token's attempts and account lockout
+func (s *AuthService) findLoginTokenByCode(email, code, ipKey string) (*entity.LoginToken, error) {
+	user, err := s.userRepo.FindByEmail(email)
+	if err != nil {
+		s.loginThrottle.RecordFailure(ipKey, s.config.Lockout.MaxIPAttempts)
+		return nil, errors.New("login code invalid")
+	}
+
+	userKey := UserKey(user.ID)
+	if err := s.loginThrottle.Check(userKey); err != nil {
+		return nil, err
+	}
+
+	loginToken, err := s.loginTokenRepo.FindLatestUnused(user.ID)
+	if err != nil {
+		s.loginThrottle.RecordFailure(ipKey, s.config.Lockout.MaxIPAttempts)
+		return nil, err
+	}
+	if loginToken.IsExpired() {
+		return nil, errors.New("login code has expired")
+	}
+
+	if subtle.ConstantTimeCompare([]byte(loginCodeHash(loginToken.TokenHash, code)), []byte(loginToken.CodeHash)) != 1 {
+		// Burn the code once too many wrong guesses were made
+		loginToken.Attempts++
+		if loginToken.Attempts >= s.config.LoginLink.MaxAttempts {
+			loginToken.Used = true
+		}
+		if err := s.loginTokenRepo.Update(loginToken); err != nil {
+			log.Printf("failed to update login token %d: %v", loginToken.ID, err)
+		}
+		s.loginThrottle.RecordFailure(userKey, s.config.Lockout.MaxUserAttempts)
+		s.loginThrottle.RecordFailure(ipKey, s.config.Lockout.MaxIPAttempts)
+		return nil, errors.New("login code invalid")
+	}
+
+	return loginToken, nil
+}
+
+// canUseLoginLink checks if user status permits passwordless login, pending accounts are
+// allowed since the emailed token verifies the address
+func (s *AuthService) canUseLoginLink(user *entity.User) bool {
+	return user.Status == "pending_verification" || s.isUserAllowed(user)
+}
+
+// loginCodeHash returns digest of login code stored with the token, salted with the token digest so that
+// equal codes of different tokens differ
+func loginCodeHash(tokenHash, code string) string {
+	return token.Hash(tokenHash + ":" + code)
+}
+
+// generateLoginCode generate random 6-digit login code
+func generateLoginCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
@@ -0,0 +1,268 @@
+package service
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/damonleelcx/go-gin-api/config"
+	"github.com/damonleelcx/go-gin-api/entity"
+	"github.com/damonleelcx/go-gin-api/totp"
+)
+
+// loginCodePattern matches code in login link emails
+var loginCodePattern = regexp.MustCompile(`sign-in page: (\d{6})`)
+
+// lastLoginCode returns code of the last login link email sent to address
+func (m *testMailer) lastLoginCode(t *testing.T, to string) string {
+	t.Helper()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := len(m.messages) - 1; i >= 0; i-- {
+		if m.messages[i].To != to {
+			continue
+		}
+		match := loginCodePattern.FindStringSubmatch(m.messages[i].Text)
+		if match == nil {
+			t.Fatalf("no login code in message %q", m.messages[i].Subject)
+		}
+		return match[1]
+	}
+	t.Fatalf("no message sent to %s", to)
+	return ""
+}
+
+// sentCount returns number of messages sent
+func (m *testMailer) sentCount() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.messages)
+}
+
+// requestLoginLink requests login link for email and returns token and code of the email
+func (e *testEnv) requestLoginLink(t *testing.T, email string) (string, string) {
+	t.Helper()
+
+	message, err := e.auth.RequestLoginLink(&RequestLoginLinkRequest{Email: email}, testIP, testUserAgent)
+	if err != nil {
+		t.Fatalf("request login link: %v", err)
+	}
+	if message != loginLinkMessage {
+		t.Fatalf("request login link = %q, want %q", message, loginLinkMessage)
+	}
+	return e.mailer.lastToken(t, email), e.mailer.lastLoginCode(t, email)
+}
+
+// exchangeLoginLink exchanges token or email and code from ipAddress
+func (e *testEnv) exchangeLoginLink(req *ExchangeLoginLinkRequest, ipAddress string) (*SigninResponse, error) {
+	return e.auth.ExchangeLoginLink(req, ipAddress, testUserAgent)
+}
+
+// wrongLoginCode returns a code differing from code in its last digit
+func wrongLoginCode(code string) string {
+	last := (code[len(code)-1]-'0'+1)%10 + '0'
+	return code[:len(code)-1] + string(last)
+}
+
+func TestLoginLinkSingleUse(t *testing.T) {
+	env := newTestEnv(t)
+	env.signup(t, "abby")
+
+	// The link signs in once
+	linkToken, code := env.requestLoginLink(t, "abby@example.com")
+	response, err := env.exchangeLoginLink(&ExchangeLoginLinkRequest{Token: linkToken}, testIP)
+	if err != nil {
+		t.Fatalf("exchange token: %v", err)
+	}
+	if response.Token == "" || response.Session == nil {
+		t.Fatalf("exchange token returned %+v, want session", response)
+	}
+	if _, err := env.exchangeLoginLink(&ExchangeLoginLinkRequest{Token: linkToken}, testIP); err == nil {
+		t.Fatal("login link was accepted twice")
+	}
+
+	// The code of a used link is spent as well
+	if _, err := env.exchangeLoginLink(&ExchangeLoginLinkRequest{Email: "abby@example.com", Code: code}, testIP); err == nil {
+		t.Fatal("code of used login link was accepted")
+	}
+
+	// The code signs in once, after which its link is spent
+	linkToken, code = env.requestLoginLink(t, "abby@example.com")
+	if _, err := env.exchangeLoginLink(&ExchangeLoginLinkRequest{Email: "abby@example.com", Code: code}, testIP); err != nil {
+		t.Fatalf("exchange code: %v", err)
+	}
+	if _, err := env.exchangeLoginLink(&ExchangeLoginLinkRequest{Email: "abby@example.com", Code: code}, testIP); err == nil {
+		t.Fatal("login code was accepted twice")
+	}
+	if _, err := env.exchangeLoginLink(&ExchangeLoginLinkRequest{Token: linkToken}, testIP); err == nil {
+		t.Fatal("link of used login code was accepted")
+	}
+
+	// Codes are stored as digests only
+	var stored entity.LoginToken
+	if err := env.db.Where("user_id = ?", response.Session.UserID).Last(&stored).Error; err != nil {
+		t.Fatalf("load login token: %v", err)
+	}
+	if stored.CodeHash == "" || stored.CodeHash == code {
+		t.Errorf("stored code hash = %q, want digest", stored.CodeHash)
+	}
+}
+
+func TestLoginCodeAttempts(t *testing.T) {
+	// Lockout is off so that only the attempts of the code are counted
+	env := newTestEnv(t, func(cfg *config.Config) {
+		cfg.Auth.Lockout.Enabled = false
+		cfg.Auth.LoginLink.MaxAttempts = 3
+	})
+	env.signup(t, "bea")
+
+	// Wrong guesses below the limit leave the code usable
+	_, code := env.requestLoginLink(t, "bea@example.com")
+	for i := 0; i < 2; i++ {
+		if _, err := env.exchangeLoginLink(&ExchangeLoginLinkRequest{Email: "bea@example.com", Code: wrongLoginCode(code)}, testIP); err == nil {
+			t.Fatal("wrong login code was accepted")
+		}
+	}
+	if _, err := env.exchangeLoginLink(&ExchangeLoginLinkRequest{Email: "bea@example.com", Code: code}, testIP); err != nil {
+		t.Fatalf("exchange code after wrong guesses: %v", err)
+	}
+
+	// The limit burns the code and its link
+	linkToken, code := env.requestLoginLink(t, "bea@example.com")
+	for i := 0; i < 3; i++ {
+		env.exchangeLoginLink(&ExchangeLoginLinkRequest{Email: "bea@example.com", Code: wrongLoginCode(code)}, testIP)
+	}
+	if _, err := env.exchangeLoginLink(&ExchangeLoginLinkRequest{Email: "bea@example.com", Code: code}, testIP); err == nil {
+		t.Fatal("burned login code was accepted")
+	}
+	if _, err := env.exchangeLoginLink(&ExchangeLoginLinkRequest{Token: linkToken}, testIP); err == nil {
+		t.Fatal("link of burned login code was accepted")
+	}
+}
+
+func TestLoginLinkExpired(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.signup(t, "cleo").User
+
+	linkToken, code := env.requestLoginLink(t, "cleo@example.com")
+	if err := env.db.Model(&entity.LoginToken{}).Where("user_id = ?", user.ID).
+		Update("expires_at", time.Now().Add(-time.Second)).Error; err != nil {
+		t.Fatalf("expire login token: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		req  *ExchangeLoginLinkRequest
+		want string
+	}{
+		{"token", &ExchangeLoginLinkRequest{Token: linkToken}, "login token has expired"},
+		{"code", &ExchangeLoginLinkRequest{Email: "cleo@example.com", Code: code}, "login code has expired"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := env.exchangeLoginLink(tt.req, testIP); err == nil || err.Error() != tt.want {
+				t.Errorf("exchange expired %s = %v, want %s", tt.name, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoginLinkNeutralResponse(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.signup(t, "dana").User
+	env.signup(t, "elle")
+	if err := env.db.Model(&entity.User{}).Where("username = ?", "elle").Update("status", "disabled").Error; err != nil {
+		t.Fatalf("disable user: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		email    string
+		wantMail bool
+	}{
+		{name: "known", email: "dana@example.com", wantMail: true},
+		{name: "unknown", email: "nobody@example.com", wantMail: false},
+		{name: "disabled", email: "elle@example.com", wantMail: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sent := env.mailer.sentCount()
+			message, err := env.auth.RequestLoginLink(&RequestLoginLinkRequest{Email: tt.email}, testIP, testUserAgent)
+			if err != nil || message != loginLinkMessage {
+				t.Fatalf("RequestLoginLink(%s) = %q, %v, want %q", tt.email, message, err, loginLinkMessage)
+			}
+			if got := env.mailer.sentCount() > sent; got != tt.wantMail {
+				t.Errorf("RequestLoginLink(%s) sent mail %v, want %v", tt.email, got, tt.wantMail)
+			}
+		})
+	}
+
+	var tokens int64
+	env.db.Model(&entity.LoginToken{}).Count(&tokens)
+	if tokens != 1 {
+		t.Errorf("created %d login tokens, want 1 for user %d", tokens, user.ID)
+	}
+
+	// Codes for unknown addresses are rejected like wrong codes
+	if _, err := env.exchangeLoginLink(&ExchangeLoginLinkRequest{Email: "nobody@example.com", Code: "123456"}, "192.0.2.40"); err == nil || err.Error() != "login code invalid" {
+		t.Errorf("exchange code of unknown email = %v, want login code invalid", err)
+	}
+}
+
+func TestLoginLinkRequiresMFA(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.signup(t, "fay").User
+	session := env.signin(t, "fay").Session
+
+	enrollment, err := env.auth.EnrollTOTP(user.ID)
+	if err != nil {
+		t.Fatalf("enroll: %v", err)
+	}
+	current := totp.Counter(time.Now())
+	if err := env.auth.ConfirmTOTP(user.ID, session.ID, &ConfirmTOTPRequest{Code: totpCode(t, enrollment.Secret, current)}, testIP, testUserAgent); err != nil {
+		t.Fatalf("confirm: %v", err)
+	}
+
+	// The link replaces the password only, the session is issued after the second factor
+	linkToken, _ := env.requestLoginLink(t, "fay@example.com")
+	response, err := env.exchangeLoginLink(&ExchangeLoginLinkRequest{Token: linkToken}, testIP)
+	if err != nil {
+		t.Fatalf("exchange token: %v", err)
+	}
+	if !response.MFARequired || response.MFAToken == "" || response.Token != "" || response.Session != nil {
+		t.Fatalf("exchange token of user with two-factor authentication returned %+v, want MFA token only", response)
+	}
+
+	if _, err := env.auth.SigninMFA(&SigninMFARequest{MFAToken: response.MFAToken, Code: "000000"}, testIP, testUserAgent); err == nil {
+		t.Fatal("wrong authentication code completed the challenge")
+	}
+	completed, err := env.auth.SigninMFA(&SigninMFARequest{MFAToken: response.MFAToken, Code: totpCode(t, enrollment.Secret, current+1)}, testIP, testUserAgent)
+	if err != nil {
+		t.Fatalf("complete challenge: %v", err)
+	}
+	if completed.Token == "" || completed.Session == nil {
+		t.Errorf("complete challenge returned %+v, want session", completed)
+	}
+}
+
+func TestLoginLinkVerifiesEmail(t *testing.T) {
+	env := newTestEnv(t)
+	response, err := env.auth.Signup(&SignupRequest{Username: "gina", Email: "gina@example.com", Password: testPassword}, testIP, testUserAgent)
+	if err != nil {
+		t.Fatalf("signup: %v", err)
+	}
+
+	// Receiving the link proves the address, so a pending account is activated
+	linkToken, _ := env.requestLoginLink(t, "gina@example.com")
+	if _, err := env.exchangeLoginLink(&ExchangeLoginLinkRequest{Token: linkToken}, testIP); err != nil {
+		t.Fatalf("exchange token: %v", err)
+	}
+	var user entity.User
+	if err := env.db.First(&user, response.User.ID).Error; err != nil {
+		t.Fatalf("load user: %v", err)
+	}
+	if user.Status != "active" || user.EmailVerifiedAt == nil {
+		t.Errorf("user after login link has status %s, verified at %v, want active and verified", user.Status, user.EmailVerifiedAt)
+	}
+}
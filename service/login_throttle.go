@@ -0,0 +1,140 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/damonleelcx/go-gin-api/config"
+	"github.com/damonleelcx/go-gin-api/entity"
+	"github.com/damonleelcx/go-gin-api/repository"
+)
+
+// TooManyAttemptsError returned when login is throttled or locked out
+type TooManyAttemptsError struct {
+	RetryAfter time.Duration // Time until the next attempt is allowed
+}
+
+// Error implements error interface
+func (e *TooManyAttemptsError) Error() string {
+	return "too many failed login attempts, try again in " + e.RetryAfter.Round(time.Second).String()
+}
+
+// LoginThrottle tracks failed login attempts per user and per client IP, enforces progressive
+// delays between attempts and temporarily locks keys with too many failures
+type LoginThrottle struct {
+	loginAttemptRepo repository.LoginAttemptRepository
+	config           config.LockoutConfig
+}
+
+// NewLoginThrottle creates a new login throttle instance
+func NewLoginThrottle(loginAttemptRepo repository.LoginAttemptRepository, cfg config.LockoutConfig) *LoginThrottle {
+	return &LoginThrottle{
+		loginAttemptRepo: loginAttemptRepo,
+		config:           cfg,
+	}
+}
+
+// UserKey returns tracking key of user
+func UserKey(userID uint) string {
+	return "user:" + strconv.FormatUint(uint64(userID), 10)
+}
+
+// IPKey returns tracking key of client IP
+func IPKey(ipAddress string) string {
+	return "ip:" + ipAddress
+}
+
+// Check returns TooManyAttemptsError if key is locked or still inside its progressive delay
+func (t *LoginThrottle) Check(key string) error {
+	if !t.config.Enabled {
+		return nil
+	}
+
+	attempt, err := t.loginAttemptRepo.FindByKey(key)
+	if err != nil {
+		return errors.New("failed to query login attempts: " + err.Error())
+	}
+	if attempt == nil {
+		return nil
+	}
+
+	now := time.Now()
+	if attempt.IsLocked() {
+		return &TooManyAttemptsError{RetryAfter: attempt.LockedUntil.Sub(now)}
+	}
+	if t.isStale(attempt, now) {
+		return nil
+	}
+
+	if delay := t.delay(attempt.Failures); delay > 0 {
+		if next := attempt.LastFailedAt.Add(delay); now.Before(next) {
+			return &TooManyAttemptsError{RetryAfter: next.Sub(now)}
+		}
+	}
+
+	return nil
+}
+
+// RecordFailure counts failed attempt for key and locks it once maxAttempts is reached
+func (t *LoginThrottle) RecordFailure(key string, maxAttempts int) {
+	if !t.config.Enabled {
+		return
+	}
+
+	attempt, err := t.loginAttemptRepo.FindByKey(key)
+	if err != nil {
+		log.Printf("failed to query login attempts of %s: %v", key, err)
+		return
+	}
+
+	now := time.Now()
+	if attempt == nil {
+		attempt = &entity.LoginAttempt{TrackingKey: key}
+	} else if t.isStale(attempt, now) || (attempt.LockedUntil != nil && !attempt.IsLocked()) {
+		// Start over after a quiet period or an expired lockout
+		attempt.Failures = 0
+		attempt.LockedUntil = nil
+	}
+
+	attempt.Failures++
+	attempt.LastFailedAt = now
+	if maxAttempts > 0 && attempt.Failures >= maxAttempts {
+		lockedUntil := now.Add(t.config.LockoutDuration.Std())
+		attempt.LockedUntil = &lockedUntil
+	}
+
+	if err := t.loginAttemptRepo.Save(attempt); err != nil {
+		log.Printf("failed to record login failure of %s: %v", key, err)
+	}
+}
+
+// Reset clears failed attempts and lockout of key
+func (t *LoginThrottle) Reset(key string) error {
+	if err := t.loginAttemptRepo.DeleteByKey(key); err != nil {
+		return errors.New("failed to reset login attempts: " + err.Error())
+	}
+	return nil
+}
+
+// isStale checks if the last failure is older than the reset period
+func (t *LoginThrottle) isStale(attempt *entity.LoginAttempt, now time.Time) bool {
+	return t.config.ResetAfter > 0 && now.Sub(attempt.LastFailedAt) > t.config.ResetAfter.Std()
+}
+
+// delay returns required wait after the given number of consecutive failures,
+// doubling for every failure beyond the free attempts up to the maximum delay
+func (t *LoginThrottle) delay(failures int) time.Duration {
+	excess := failures - t.config.FreeAttempts
+	if excess <= 0 || t.config.BaseDelay <= 0 {
+		return 0
+	}
+
+	delay := float64(t.config.BaseDelay.Std()) * math.Pow(2, float64(excess-1))
+	if maxDelay := float64(t.config.MaxDelay.Std()); maxDelay > 0 && delay > maxDelay {
+		delay = maxDelay
+	}
+	return time.Duration(delay)
+}
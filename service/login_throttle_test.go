@@ -0,0 +1,194 @@
+package service
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/damonleelcx/go-gin-api/config"
+)
+
+// lockoutConfig locks after the given failures without progressive delays, zero disables a limit
+func lockoutConfig(maxUserAttempts, maxIPAttempts int) func(cfg *config.Config) {
+	return func(cfg *config.Config) {
+		cfg.Auth.Lockout.FreeAttempts = 0
+		cfg.Auth.Lockout.BaseDelay = 0
+		cfg.Auth.Lockout.MaxUserAttempts = maxUserAttempts
+		cfg.Auth.Lockout.MaxIPAttempts = maxIPAttempts
+		cfg.Auth.Lockout.LockoutDuration = config.Duration(15 * time.Minute)
+	}
+}
+
+// signinFrom signs username in with plaintext from ipAddress
+func (e *testEnv) signinFrom(username, plaintext, ipAddress string) (*SigninResponse, error) {
+	return e.auth.Signin(&SigninRequest{Username: username, Password: plaintext}, ipAddress, testUserAgent)
+}
+
+// assertThrottled checks that err is TooManyAttemptsError retrying after at most max and more than min
+func assertThrottled(t *testing.T, err error, min, max time.Duration) {
+	t.Helper()
+
+	var throttled *TooManyAttemptsError
+	if !errors.As(err, &throttled) {
+		t.Fatalf("signin = %v, want too many attempts", err)
+	}
+	if throttled.RetryAfter <= min || throttled.RetryAfter > max {
+		t.Errorf("RetryAfter = %v, want in (%v, %v]", throttled.RetryAfter, min, max)
+	}
+}
+
+func TestSigninUserLockout(t *testing.T) {
+	env := newTestEnv(t, lockoutConfig(3, 0))
+	user := env.signup(t, "olga").User
+
+	// Failures from different addresses count towards the same account
+	for i := 0; i < 3; i++ {
+		ip := "192.0.2." + strconv.Itoa(10+i)
+		if _, err := env.signinFrom("olga", "wrong-password", ip); err == nil || err.Error() != "username or password incorrect" {
+			t.Fatalf("failure %d = %v, want username or password incorrect", i+1, err)
+		}
+	}
+
+	// The account is locked for every address, even with the correct password
+	_, err := env.signinFrom("olga", testPassword, "192.0.2.20")
+	assertThrottled(t, err, 14*time.Minute, 15*time.Minute)
+	_, err = env.signinFrom("olga@example.com", testPassword, "192.0.2.21")
+	assertThrottled(t, err, 14*time.Minute, 15*time.Minute)
+
+	// Other accounts are not affected
+	env.signup(t, "pia")
+	if _, err := env.signinFrom("pia", testPassword, "192.0.2.20"); err != nil {
+		t.Fatalf("signin of other user: %v", err)
+	}
+
+	// Unlocking clears the lockout and the failures that led to it
+	if err := env.auth.UnlockUser(user.ID); err != nil {
+		t.Fatalf("unlock: %v", err)
+	}
+	if _, err := env.signinFrom("olga", testPassword, "192.0.2.20"); err != nil {
+		t.Fatalf("signin after unlock: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		env.signinFrom("olga", "wrong-password", "192.0.2.20")
+	}
+	if _, err := env.signinFrom("olga", testPassword, "192.0.2.20"); err != nil {
+		t.Errorf("signin after failures below the limit: %v", err)
+	}
+
+	if err := env.auth.UnlockUser(user.ID + 100); err == nil {
+		t.Error("unlock of unknown user succeeded")
+	}
+}
+
+func TestSigninSuccessResetsUserFailures(t *testing.T) {
+	env := newTestEnv(t, lockoutConfig(3, 0))
+	env.signup(t, "quinn")
+
+	// Two failures, a success and two more failures stay below the limit of three in a row
+	for round := 0; round < 2; round++ {
+		for i := 0; i < 2; i++ {
+			env.signinFrom("quinn", "wrong-password", testIP)
+		}
+		if _, err := env.signinFrom("quinn", testPassword, testIP); err != nil {
+			t.Fatalf("signin in round %d: %v", round+1, err)
+		}
+	}
+}
+
+func TestSigninIPLockout(t *testing.T) {
+	env := newTestEnv(t, lockoutConfig(0, 3))
+	user := env.signup(t, "rosa")
+	env.signup(t, "sami")
+
+	// Failures against existing and unknown accounts count towards the address
+	const ip = "192.0.2.30"
+	env.signinFrom("rosa", "wrong-password", ip)
+	env.signinFrom("sami", "wrong-password", ip)
+	env.signinFrom("nobody", "wrong-password", ip)
+
+	// The address is locked for every account, other addresses are not
+	_, err := env.signinFrom("sami", testPassword, ip)
+	assertThrottled(t, err, 14*time.Minute, 15*time.Minute)
+	if _, err := env.signinFrom("sami", testPassword, "192.0.2.31"); err != nil {
+		t.Fatalf("signin from other address: %v", err)
+	}
+
+	// Unlocking a user does not lift the lockout of the address
+	if err := env.auth.UnlockUser(user.User.ID); err != nil {
+		t.Fatalf("unlock: %v", err)
+	}
+	_, err = env.signinFrom("rosa", testPassword, ip)
+	assertThrottled(t, err, 14*time.Minute, 15*time.Minute)
+}
+
+func TestSigninProgressiveDelay(t *testing.T) {
+	env := newTestEnv(t, func(cfg *config.Config) {
+		cfg.Auth.Lockout.FreeAttempts = 2
+		cfg.Auth.Lockout.BaseDelay = config.Duration(time.Minute)
+		cfg.Auth.Lockout.MaxDelay = config.Duration(5 * time.Minute)
+		cfg.Auth.Lockout.MaxUserAttempts = 0
+		cfg.Auth.Lockout.MaxIPAttempts = 0
+	})
+	env.signup(t, "tara")
+
+	// Free attempts are not delayed
+	for i := 0; i < 2; i++ {
+		if _, err := env.signinFrom("tara", "wrong-password", testIP); err == nil || err.Error() != "username or password incorrect" {
+			t.Fatalf("free attempt %d = %v, want username or password incorrect", i+1, err)
+		}
+	}
+	if _, err := env.signinFrom("tara", testPassword, testIP); err != nil {
+		t.Fatalf("signin after free attempts: %v", err)
+	}
+
+	// The failure after the free attempts delays the next attempt by the base delay
+	for i := 0; i < 3; i++ {
+		env.signinFrom("tara", "wrong-password", testIP)
+	}
+	_, err := env.signinFrom("tara", testPassword, testIP)
+	assertThrottled(t, err, 59*time.Second, time.Minute)
+}
+
+func TestLoginThrottleDelay(t *testing.T) {
+	throttle := NewLoginThrottle(nil, config.LockoutConfig{
+		FreeAttempts: 3,
+		BaseDelay:    config.Duration(time.Second),
+		MaxDelay:     config.Duration(time.Minute),
+	})
+
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, 0},
+		{3, 0},
+		{4, time.Second},
+		{5, 2 * time.Second},
+		{6, 4 * time.Second},
+		{9, 32 * time.Second},
+		{10, time.Minute},
+		{100, time.Minute},
+	}
+	for _, tt := range tests {
+		if got := throttle.delay(tt.failures); got != tt.want {
+			t.Errorf("delay(%d) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestLoginThrottleDisabled(t *testing.T) {
+	env := newTestEnv(t, func(cfg *config.Config) {
+		cfg.Auth.Lockout.Enabled = false
+		cfg.Auth.Lockout.MaxUserAttempts = 1
+		cfg.Auth.Lockout.MaxIPAttempts = 1
+	})
+	env.signup(t, "uma")
+
+	for i := 0; i < 3; i++ {
+		env.signinFrom("uma", "wrong-password", testIP)
+	}
+	if _, err := env.signinFrom("uma", testPassword, testIP); err != nil {
+		t.Errorf("signin with lockout disabled: %v", err)
+	}
+}
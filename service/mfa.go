@@ -0,0 +1,252 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/damonleelcx/go-gin-api/entity"
+	"github.com/damonleelcx/go-gin-api/token"
+	"github.com/damonleelcx/go-gin-api/totp"
+)
+
+// qrCodeSize width and height of enrollment QR code in pixels
+const qrCodeSize = 256
+
+// SigninMFARequest second signin step request
+type SigninMFARequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// TOTPEnrollResponse TOTP enrollment response
+type TOTPEnrollResponse struct {
+	Secret  string `json:"secret"`      // Base32 secret for manual entry
+	URI     string `json:"uri"`         // otpauth:// key URI
+	QRCode  []byte `json:"qr_code_png"` // PNG image of URI, base64 encoded in JSON
+	Message string `json:"message"`
+}
+
+// ConfirmTOTPRequest TOTP enrollment confirmation request
+type ConfirmTOTPRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// DisableTOTPRequest disable TOTP request
+type DisableTOTPRequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// createMFAChallenge creates challenge for user whose password was verified, rememberMe is
+// passed on to the session created once the challenge is completed
+func (s *AuthService) createMFAChallenge(user *entity.User, rememberMe bool, ipAddress, userAgent string) (*SigninResponse, error) {
+	challengeToken, err := generatePrefixedToken(token.MFAPrefix)
+	if err != nil {
+		return nil, errors.New("failed to generate token: " + err.Error())
+	}
+
+	challenge := &entity.MFAChallenge{
+		UserID:     user.ID,
+		Token:      challengeToken,
+		TokenHash:  token.Hash(challengeToken),
+		IPAddress:  ipAddress,
+		UserAgent:  userAgent,
+		RememberMe: rememberMe,
+		ExpiresAt:  time.Now().Add(s.config.MFA.ChallengeTTL.Std()),
+	}
+	if err := s.mfaChallengeRepo.Create(challenge); err != nil {
+		return nil, errors.New("failed to create MFA challenge: " + err.Error())
+	}
+
+	return &SigninResponse{
+		MFARequired: true,
+		MFAToken:    challengeToken,
+		Message:     "Two-factor authentication required",
+	}, nil
+}
+
+// SigninMFA completes signin with the code of the user's authenticator app or an unused recovery code
+func (s *AuthService) SigninMFA(req *SigninMFARequest, ipAddress, userAgent string) (*SigninResponse, error) {
+	// Find challenge
+	challenge, err := s.mfaChallengeRepo.FindByToken(req.MFAToken)
+	if err != nil {
+		return nil, err
+	}
+	if challenge.Used {
+		return nil, errors.New("MFA token has been used")
+	}
+	if challenge.IsExpired() {
+		return nil, errors.New("MFA token has expired")
+	}
+
+	// Find user
+	user, err := s.userRepo.FindByID(challenge.UserID)
+	if err != nil {
+		return nil, errors.New("user does not exist")
+	}
+
+	// Check account throttling and status, both may have changed since the password step
+	userKey := UserKey(user.ID)
+	if err := s.loginThrottle.Check(userKey); err != nil {
+		return nil, err
+	}
+	if !s.isUserAllowed(user) {
+		return nil, errors.New("account has been disabled")
+	}
+
+	// Verify code, falling back to recovery codes for users without their authenticator.
+	// A challenge is burnt after too many wrong codes.
+	valid := user.TOTPEnabled && s.verifyTOTP(user, req.Code)
+	usedRecoveryCode := false
+	if user.TOTPEnabled && !valid {
+		usedRecoveryCode = s.useRecoveryCode(user.ID, req.Code)
+		valid = usedRecoveryCode
+	}
+	if !valid {
+		challenge.Attempts++
+		if challenge.Attempts >= s.config.MFA.MaxAttempts {
+			challenge.Used = true
+		}
+		if err := s.mfaChallengeRepo.Update(challenge); err != nil {
+			return nil, errors.New("failed to update MFA challenge: " + err.Error())
+		}
+		s.loginThrottle.RecordFailure(userKey, s.config.Lockout.MaxUserAttempts)
+		return nil, errors.New("invalid authentication code")
+	}
+
+	// Mark challenge as used
+	challenge.Used = true
+	if err := s.mfaChallengeRepo.Update(challenge); err != nil {
+		return nil, errors.New("failed to update MFA challenge: " + err.Error())
+	}
+
+	if err := s.loginThrottle.Reset(userKey); err != nil {
+		log.Printf("failed to reset login attempts of user %d: %v", user.ID, err)
+	}
+
+	// Create session
+	response, err := s.createSession(user, challenge.RememberMe, ipAddress, userAgent)
+	if err != nil {
+		return nil, err
+	}
+	response.Message = "Login successful"
+
+	if usedRecoveryCode {
+		s.recordAuditEvent(user.ID, &response.Session.ID, "recovery_code_used", ipAddress, userAgent, "")
+	}
+
+	return response, nil
+}
+
+// EnrollTOTP generates new TOTP secret for user. It takes effect once confirmed with a code,
+// until then enrollment can be restarted.
+func (s *AuthService) EnrollTOTP(userID uint) (*TOTPEnrollResponse, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, errors.New("user does not exist")
+	}
+	if user.TOTPEnabled {
+		return nil, errors.New("two-factor authentication is already enabled")
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	uri := totp.URI(s.config.MFA.Issuer, user.Email, secret)
+	qrCode, err := totp.QRCode(uri, qrCodeSize)
+	if err != nil {
+		return nil, err
+	}
+
+	user.TOTPSecret = secret
+	user.TOTPLastCounter = 0
+	if err := s.userRepo.Update(user); err != nil {
+		return nil, errors.New("failed to update user: " + err.Error())
+	}
+
+	return &TOTPEnrollResponse{
+		Secret:  secret,
+		URI:     uri,
+		QRCode:  qrCode,
+		Message: "Scan the QR code with your authenticator app and confirm with a code",
+	}, nil
+}
+
+// ConfirmTOTP enables two-factor authentication after user proved the secret was stored
+func (s *AuthService) ConfirmTOTP(userID, sessionID uint, req *ConfirmTOTPRequest, ipAddress, userAgent string) error {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return errors.New("user does not exist")
+	}
+	if user.TOTPEnabled {
+		return errors.New("two-factor authentication is already enabled")
+	}
+	if user.TOTPSecret == "" {
+		return errors.New("two-factor authentication enrollment has not been started")
+	}
+	if !s.verifyTOTP(user, req.Code) {
+		return errors.New("invalid authentication code")
+	}
+
+	user.TOTPEnabled = true
+	if err := s.userRepo.Update(user); err != nil {
+		return errors.New("failed to update user: " + err.Error())
+	}
+
+	s.recordAuditEvent(user.ID, &sessionID, "totp_enabled", ipAddress, userAgent, "")
+
+	return nil
+}
+
+// DisableTOTP disables two-factor authentication, requiring both password and a current code
+func (s *AuthService) DisableTOTP(userID, sessionID uint, req *DisableTOTPRequest, ipAddress, userAgent string) error {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return errors.New("user does not exist")
+	}
+	if !user.TOTPEnabled {
+		return errors.New("two-factor authentication is not enabled")
+	}
+
+	valid, err := s.passwordHasher.Verify(req.Password, user.Password)
+	if err != nil {
+		log.Printf("failed to verify password hash of user %d: %v", user.ID, err)
+	}
+	if !valid {
+		return errors.New("password is incorrect")
+	}
+	if !s.verifyTOTP(user, req.Code) {
+		return errors.New("invalid authentication code")
+	}
+
+	user.TOTPEnabled = false
+	user.TOTPSecret = ""
+	user.TOTPLastCounter = 0
+	if err := s.userRepo.Update(user); err != nil {
+		return errors.New("failed to update user: " + err.Error())
+	}
+
+	s.recordAuditEvent(user.ID, &sessionID, "totp_disabled", ipAddress, userAgent, "")
+
+	return nil
+}
+
+// verifyTOTP checks code against user's secret and records its time step, so every code is accepted only once
+func (s *AuthService) verifyTOTP(user *entity.User, code string) bool {
+	if user.TOTPSecret == "" {
+		return false
+	}
+	counter, ok := totp.Validate(user.TOTPSecret, code, time.Now(), s.config.MFA.Skew)
+	if !ok || counter <= user.TOTPLastCounter {
+		return false
+	}
+
+	user.TOTPLastCounter = counter
+	if err := s.userRepo.Update(user); err != nil {
+		log.Printf("failed to record TOTP counter of user %d: %v", user.ID, err)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/damonleelcx/go-gin-api/totp"
+)
+
+// totpCode returns code of secret for time step counter
+func totpCode(t *testing.T, secret string, counter int64) string {
+	t.Helper()
+
+	code, err := totp.Code(secret, counter)
+	if err != nil {
+		t.Fatalf("generate code: %v", err)
+	}
+	return code
+}
+
+// mfaSignin signs user in with password and completes the second step with code
+func (e *testEnv) mfaSignin(t *testing.T, username, code string) (*SigninResponse, error) {
+	t.Helper()
+
+	signin := e.signin(t, username)
+	if !signin.MFARequired || signin.MFAToken == "" {
+		t.Fatalf("signin of user with two-factor authentication returned %+v, want MFA token", signin)
+	}
+	return e.auth.SigninMFA(&SigninMFARequest{MFAToken: signin.MFAToken, Code: code}, testIP, testUserAgent)
+}
+
+func TestTOTPReplay(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.signup(t, "leo").User
+	session := env.signin(t, "leo").Session
+
+	enrollment, err := env.auth.EnrollTOTP(user.ID)
+	if err != nil {
+		t.Fatalf("enroll: %v", err)
+	}
+	current := totp.Counter(time.Now())
+	code := totpCode(t, enrollment.Secret, current)
+	if err := env.auth.ConfirmTOTP(user.ID, session.ID, &ConfirmTOTPRequest{Code: code}, testIP, testUserAgent); err != nil {
+		t.Fatalf("confirm: %v", err)
+	}
+
+	// The code that confirmed enrollment cannot sign in
+	if _, err := env.mfaSignin(t, "leo", code); err == nil {
+		t.Fatal("code used to confirm enrollment was accepted at signin")
+	}
+
+	// The next time step is within the default skew and is accepted once
+	next := totpCode(t, enrollment.Secret, current+1)
+	response, err := env.mfaSignin(t, "leo", next)
+	if err != nil {
+		t.Fatalf("signin with code of the next time step: %v", err)
+	}
+	if response.Token == "" {
+		t.Fatal("signin with code returned no token")
+	}
+	if _, err := env.mfaSignin(t, "leo", next); err == nil {
+		t.Error("code was accepted twice")
+	}
+
+	// Codes of earlier time steps are not accepted after a later one, even within the skew
+	if _, err := env.mfaSignin(t, "leo", totpCode(t, enrollment.Secret, current-1)); err == nil {
+		t.Error("code of an earlier time step was accepted")
+	}
+	if err := env.auth.DisableTOTP(user.ID, session.ID, &DisableTOTPRequest{Password: testPassword, Code: next}, testIP, testUserAgent); err == nil {
+		t.Error("used code disabled two-factor authentication")
+	}
+}
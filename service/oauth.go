@@ -0,0 +1,406 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"log"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/damonleelcx/go-gin-api/config"
+	"github.com/damonleelcx/go-gin-api/entity"
+	"github.com/damonleelcx/go-gin-api/oauth"
+	"github.com/damonleelcx/go-gin-api/repository"
+	"github.com/damonleelcx/go-gin-api/token"
+)
+
+// OAuthService social login service
+type OAuthService struct {
+	authService  *AuthService
+	userRepo     repository.UserRepository
+	identityRepo repository.UserIdentityRepository
+	stateRepo    repository.OAuthStateRepository
+	providers    map[string]oauth.Provider
+	config       config.OAuthConfig
+}
+
+// NewOAuthService creates a new social login service instance, OpenID Connect providers are discovered here
+func NewOAuthService(
+	authService *AuthService,
+	userRepo repository.UserRepository,
+	identityRepo repository.UserIdentityRepository,
+	stateRepo repository.OAuthStateRepository,
+	cfg config.OAuthConfig,
+) (*OAuthService, error) {
+	providers := make(map[string]oauth.Provider, len(cfg.Providers))
+	for name, providerConfig := range cfg.Providers {
+		redirectURL := strings.TrimSuffix(cfg.CallbackURL, "/") + "/" + name + "/callback"
+		provider, err := oauth.NewProvider(context.Background(), providerConfig, redirectURL, cfg.Timeout.Std())
+		if err != nil {
+			return nil, errors.New("OAuth provider " + name + ": " + err.Error())
+		}
+		providers[name] = provider
+	}
+
+	return &OAuthService{
+		authService:  authService,
+		userRepo:     userRepo,
+		identityRepo: identityRepo,
+		stateRepo:    stateRepo,
+		providers:    providers,
+		config:       cfg,
+	}, nil
+}
+
+// OAuthAuthorizeResponse provider login page to send the user to
+type OAuthAuthorizeResponse struct {
+	AuthorizationURL string `json:"authorization_url"`
+	Binding          string `json:"-"` // Random value stored in a cookie of the browser, required by the callback
+}
+
+// OAuthCallbackRequest query parameters of the provider's redirect
+type OAuthCallbackRequest struct {
+	Code             string `form:"code"`
+	State            string `form:"state" binding:"required"`
+	Error            string `form:"error"`             // Set when the user denied access or the provider failed
+	ErrorDescription string `form:"error_description"` // Human readable error from the provider
+	Binding          string `form:"-"`                 // Binding cookie of the browser completing the flow
+}
+
+// OAuthCallbackResponse callback response, either the signin response of a login or the identity
+// linked to the signed-in user
+type OAuthCallbackResponse struct {
+	*SigninResponse
+	Identity *entity.UserIdentity `json:"identity,omitempty"`
+}
+
+// BindingCookieOptions returns lifetime of the binding cookie and whether it is sent over HTTPS only,
+// which follows the scheme of the callback URL
+func (s *OAuthService) BindingCookieOptions() (time.Duration, bool) {
+	return s.config.StateTTL.Std(), strings.HasPrefix(s.config.CallbackURL, "https://")
+}
+
+// Providers returns names of configured providers
+func (s *OAuthService) Providers() []string {
+	names := make([]string, 0, len(s.providers))
+	for name := range s.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Authorize starts login with provider, or linking of provider identity when userID is set. The
+// state is bound to the browser by the returned binding, which the caller stores in a cookie.
+func (s *OAuthService) Authorize(providerName string, userID *uint) (*OAuthAuthorizeResponse, error) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return nil, errors.New("unknown OAuth provider")
+	}
+
+	state, err := generatePrefixedToken(token.OAuthStatePrefix)
+	if err != nil {
+		return nil, errors.New("failed to generate state: " + err.Error())
+	}
+	binding, err := generateToken()
+	if err != nil {
+		return nil, errors.New("failed to generate binding: " + err.Error())
+	}
+	nonce, err := oauth.GenerateNonce()
+	if err != nil {
+		return nil, errors.New("failed to generate nonce: " + err.Error())
+	}
+	verifier := oauth.GenerateVerifier()
+
+	oauthState := &entity.OAuthState{
+		State:        state,
+		StateHash:    token.Hash(state),
+		Provider:     providerName,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		BindingHash:  token.Hash(binding),
+		UserID:       userID,
+		ExpiresAt:    time.Now().Add(s.config.StateTTL.Std()),
+	}
+	if err := s.stateRepo.Create(oauthState); err != nil {
+		return nil, errors.New("failed to create OAuth state: " + err.Error())
+	}
+
+	return &OAuthAuthorizeResponse{
+		AuthorizationURL: provider.AuthCodeURL(state, nonce, verifier),
+		Binding:          binding,
+	}, nil
+}
+
+// Callback completes login or linking started by Authorize, in the browser that started it. Linking
+// must also be completed by the user who started it, userID is the user authenticated by the request.
+func (s *OAuthService) Callback(providerName string, req *OAuthCallbackRequest, userID *uint, ipAddress, userAgent string) (*OAuthCallbackResponse, error) {
+	throttle := s.authService.loginThrottle
+	lockout := s.authService.config.Lockout
+
+	// Check client IP throttling
+	ipKey := IPKey(ipAddress)
+	if err := throttle.Check(ipKey); err != nil {
+		return nil, err
+	}
+
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return nil, errors.New("unknown OAuth provider")
+	}
+
+	// The state is consumed even if the provider reports an error, so every login is answered once
+	state, err := s.stateRepo.Consume(req.State)
+	if err != nil {
+		throttle.RecordFailure(ipKey, lockout.MaxIPAttempts)
+		return nil, err
+	}
+	if state.Provider != providerName {
+		return nil, errors.New("OAuth state invalid")
+	}
+	if state.IsExpired() {
+		return nil, errors.New("OAuth state has expired")
+	}
+
+	// A state completed by another browser is login or link CSRF
+	if state.BindingHash == "" || subtle.ConstantTimeCompare([]byte(token.Hash(req.Binding)), []byte(state.BindingHash)) != 1 {
+		throttle.RecordFailure(ipKey, lockout.MaxIPAttempts)
+		return nil, errors.New("OAuth state was started in another browser")
+	}
+	if state.UserID != nil && (userID == nil || *userID != *state.UserID) {
+		return nil, errors.New("identity linking must be completed by the signed-in user who started it")
+	}
+	if req.Error != "" {
+		if req.ErrorDescription != "" {
+			return nil, errors.New("provider denied login: " + req.ErrorDescription)
+		}
+		return nil, errors.New("provider denied login: " + req.Error)
+	}
+	if req.Code == "" {
+		return nil, errors.New("authorization code is required")
+	}
+
+	// Redeem code with the PKCE verifier and check the ID token nonce
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.Timeout.Std())
+	defer cancel()
+	identity, err := provider.Exchange(ctx, req.Code, state.CodeVerifier, state.Nonce)
+	if err != nil {
+		throttle.RecordFailure(ipKey, lockout.MaxIPAttempts)
+		return nil, err
+	}
+	if identity.Subject == "" {
+		return nil, errors.New("provider returned no subject")
+	}
+
+	if state.UserID != nil {
+		linked, err := s.linkIdentity(*state.UserID, providerName, identity, ipAddress, userAgent)
+		if err != nil {
+			return nil, err
+		}
+		return &OAuthCallbackResponse{
+			SigninResponse: &SigninResponse{Message: "Identity linked"},
+			Identity:       linked,
+		}, nil
+	}
+
+	response, err := s.login(providerName, identity, ipAddress, userAgent)
+	if err != nil {
+		return nil, err
+	}
+	return &OAuthCallbackResponse{SigninResponse: response}, nil
+}
+
+// login signs in user of identity, creating the user or linking it by email on first login
+func (s *OAuthService) login(providerName string, identity *oauth.Identity, ipAddress, userAgent string) (*SigninResponse, error) {
+	throttle := s.authService.loginThrottle
+
+	var user *entity.User
+	linked, err := s.identityRepo.FindBySubject(providerName, identity.Subject)
+	if err == nil {
+		user, err = s.userRepo.FindByID(linked.UserID)
+		if err != nil {
+			return nil, errors.New("user does not exist")
+		}
+	} else {
+		user, err = s.findOrCreateUser(identity)
+		if err != nil {
+			return nil, err
+		}
+		linked, err = s.linkIdentity(user.ID, providerName, identity, ipAddress, userAgent)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Check account throttling and status
+	userKey := UserKey(user.ID)
+	if err := throttle.Check(userKey); err != nil {
+		return nil, err
+	}
+	if !s.authService.isUserAllowed(user) {
+		return nil, errors.New("account has been disabled")
+	}
+
+	now := time.Now()
+	linked.Email = identity.Email
+	linked.LastLoginAt = &now
+	if err := s.identityRepo.Update(linked); err != nil {
+		log.Printf("failed to update identity %d of user %d: %v", linked.ID, user.ID, err)
+	}
+
+	// The provider replaces the password only, the second factor is still required
+	if user.TOTPEnabled {
+		return s.authService.createMFAChallenge(user, false, ipAddress, userAgent)
+	}
+
+	if err := throttle.Reset(userKey); err != nil {
+		log.Printf("failed to reset login attempts of user %d: %v", user.ID, err)
+	}
+
+	response, err := s.authService.createSession(user, false, ipAddress, userAgent)
+	if err != nil {
+		return nil, err
+	}
+	response.Message = "Login successful"
+
+	return response, nil
+}
+
+// findOrCreateUser returns user with the identity's email if it may be linked, or creates a new user
+func (s *OAuthService) findOrCreateUser(identity *oauth.Identity) (*entity.User, error) {
+	if identity.Email == "" || !identity.EmailVerified {
+		return nil, errors.New("provider did not return a verified email address")
+	}
+
+	// Both sides must have verified the address, otherwise whoever registered it first could take over the other account
+	existing, err := s.userRepo.FindByEmail(identity.Email)
+	if err == nil {
+		if s.config.LinkByEmail && existing.EmailVerifiedAt != nil {
+			return existing, nil
+		}
+		return nil, errors.New("email already registered, sign in and link the identity from your account instead")
+	}
+
+	username, err := s.availableUsername(identity)
+	if err != nil {
+		return nil, err
+	}
+
+	// Random password nobody knows, the user can set one with the forgot password flow
+	randomPassword, err := generateToken()
+	if err != nil {
+		return nil, errors.New("failed to generate password: " + err.Error())
+	}
+	hashedPassword, err := s.authService.passwordHasher.Hash(randomPassword)
+	if err != nil {
+		return nil, errors.New("password encryption failed: " + err.Error())
+	}
+
+	now := time.Now()
+	firstName, lastName, _ := strings.Cut(strings.TrimSpace(identity.Name), " ")
+	user := &entity.User{
+		Username:        username,
+		Email:           identity.Email,
+		Password:        hashedPassword,
+		FirstName:       firstName,
+		LastName:        strings.TrimSpace(lastName),
+		Status:          "active",
+		Role:            "user",
+		EmailVerifiedAt: &now,
+	}
+	if err := s.userRepo.Create(user); err != nil {
+		return nil, errors.New("failed to create user: " + err.Error())
+	}
+
+	return user, nil
+}
+
+// availableUsername derives unused username from the identity's username or email
+func (s *OAuthService) availableUsername(identity *oauth.Identity) (string, error) {
+	base := sanitizeUsername(identity.Username)
+	if len(base) < 3 {
+		localPart, _, _ := strings.Cut(identity.Email, "@")
+		base = sanitizeUsername(localPart)
+	}
+	if len(base) < 3 {
+		base = "user"
+	}
+	if len(base) > 40 {
+		base = base[:40]
+	}
+
+	candidate := base
+	for i := 0; i < 10; i++ {
+		if _, err := s.userRepo.FindByUsername(candidate); err != nil {
+			return candidate, nil
+		}
+		suffix, err := rand.Int(rand.Reader, big.NewInt(100000))
+		if err != nil {
+			return "", errors.New("failed to generate username: " + err.Error())
+		}
+		candidate = base + strconv.FormatInt(suffix.Int64(), 10)
+	}
+	return "", errors.New("failed to find an available username")
+}
+
+// linkIdentity links provider identity to user, linking it again to the same user is a no-op
+func (s *OAuthService) linkIdentity(userID uint, providerName string, identity *oauth.Identity, ipAddress, userAgent string) (*entity.UserIdentity, error) {
+	if existing, err := s.identityRepo.FindBySubject(providerName, identity.Subject); err == nil {
+		if existing.UserID != userID {
+			return nil, errors.New("identity is already linked to another account")
+		}
+		return existing, nil
+	}
+
+	linked := &entity.UserIdentity{
+		UserID:   userID,
+		Provider: providerName,
+		Subject:  identity.Subject,
+		Email:    identity.Email,
+	}
+	if err := s.identityRepo.Create(linked); err != nil {
+		return nil, errors.New("failed to link identity: " + err.Error())
+	}
+
+	s.authService.recordAuditEvent(userID, nil, "identity_linked", ipAddress, userAgent, providerName)
+	return linked, nil
+}
+
+// ListIdentities lists identities linked to user
+func (s *OAuthService) ListIdentities(userID uint) ([]*entity.UserIdentity, error) {
+	identities, err := s.identityRepo.FindByUserID(userID)
+	if err != nil {
+		return nil, errors.New("failed to query identities: " + err.Error())
+	}
+	return identities, nil
+}
+
+// UnlinkIdentity removes identity of user
+func (s *OAuthService) UnlinkIdentity(userID, sessionID, identityID uint, ipAddress, userAgent string) error {
+	deleted, err := s.identityRepo.DeleteForUser(identityID, userID)
+	if err != nil {
+		return errors.New("failed to unlink identity: " + err.Error())
+	}
+	if !deleted {
+		return errors.New("identity does not exist")
+	}
+
+	s.authService.recordAuditEvent(userID, &sessionID, "identity_unlinked", ipAddress, userAgent, "")
+	return nil
+}
+
+// sanitizeUsername lowercases name and drops characters other than letters, digits, dot, dash and underscore
+func sanitizeUsername(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '.' || r == '-' || r == '_' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
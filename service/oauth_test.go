@@ -0,0 +1,448 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/damonleelcx/go-gin-api/config"
+	"github.com/damonleelcx/go-gin-api/entity"
+	"github.com/damonleelcx/go-gin-api/repository"
+	"github.com/damonleelcx/go-gin-api/token"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testClientID     = "test-client"
+	testClientSecret = "test-secret"
+)
+
+// testGrant authorization code issued by the test provider
+type testGrant struct {
+	challenge   string
+	redirectURI string
+	claims      jwt.MapClaims
+}
+
+// testOIDCProvider OpenID Connect provider serving discovery, token and JWKS endpoints
+type testOIDCProvider struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu     sync.Mutex
+	grants map[string]*testGrant
+}
+
+// newTestOIDCProvider starts provider on a local port
+func newTestOIDCProvider(t *testing.T) *testOIDCProvider {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate provider key: %v", err)
+	}
+	provider := &testOIDCProvider{key: key, grants: map[string]*testGrant{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", provider.discovery)
+	mux.HandleFunc("/token", provider.token)
+	mux.HandleFunc("/jwks", provider.jwks)
+	provider.server = httptest.NewServer(mux)
+	t.Cleanup(provider.server.Close)
+	return provider
+}
+
+func (p *testOIDCProvider) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                p.server.URL,
+		"authorization_endpoint":                p.server.URL + "/authorize",
+		"token_endpoint":                        p.server.URL + "/token",
+		"jwks_uri":                              p.server.URL + "/jwks",
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+	})
+}
+
+func (p *testOIDCProvider) jwks(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "test-key",
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(p.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(p.key.E)).Bytes()),
+		}},
+	})
+}
+
+// token redeems code once, checking the PKCE verifier against the challenge of the authorization request
+func (p *testOIDCProvider) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientID, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if clientID != testClientID || clientSecret != testClientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	p.mu.Lock()
+	grant, ok := p.grants[r.PostForm.Get("code")]
+	delete(p.grants, r.PostForm.Get("code"))
+	p.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || r.PostForm.Get("grant_type") != "authorization_code" || r.PostForm.Get("redirect_uri") != grant.redirectURI ||
+		base64.RawURLEncoding.EncodeToString(sum[:]) != grant.challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, grant.claims)
+	idToken.Header["kid"] = "test-key"
+	signed, err := idToken.SignedString(p.key)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": "provider-access-token",
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     signed,
+	})
+}
+
+// authorize plays the user signing in at the provider's login page: it checks the authorization URL and
+// returns the callback request with a code for subject. modify adjusts the ID token claims.
+func (p *testOIDCProvider) authorize(t *testing.T, response *OAuthAuthorizeResponse, subject, email string, modify ...func(claims jwt.MapClaims)) *OAuthCallbackRequest {
+	t.Helper()
+
+	authURL, err := url.Parse(response.AuthorizationURL)
+	if err != nil {
+		t.Fatalf("parse authorization URL: %v", err)
+	}
+	query := authURL.Query()
+	if authURL.Path != "/authorize" || query.Get("client_id") != testClientID || query.Get("response_type") != "code" {
+		t.Fatalf("unexpected authorization URL %s", response.AuthorizationURL)
+	}
+	if query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		t.Fatalf("authorization URL %s has no S256 code challenge", response.AuthorizationURL)
+	}
+	if query.Get("state") == "" || query.Get("nonce") == "" {
+		t.Fatalf("authorization URL %s has no state or nonce", response.AuthorizationURL)
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":            p.server.URL,
+		"sub":            subject,
+		"aud":            testClientID,
+		"iat":            now.Unix(),
+		"exp":            now.Add(time.Hour).Unix(),
+		"nonce":          query.Get("nonce"),
+		"email":          email,
+		"email_verified": true,
+		"name":           "Test User",
+	}
+	for _, fn := range modify {
+		fn(claims)
+	}
+
+	code, err := generateToken()
+	if err != nil {
+		t.Fatalf("generate code: %v", err)
+	}
+	p.mu.Lock()
+	p.grants[code] = &testGrant{challenge: query.Get("code_challenge"), redirectURI: query.Get("redirect_uri"), claims: claims}
+	p.mu.Unlock()
+
+	return &OAuthCallbackRequest{Code: code, State: query.Get("state"), Binding: response.Binding}
+}
+
+// writeJSON writes v as JSON response
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// newTestOAuthService creates social login service with the test provider configured as "test"
+func newTestOAuthService(t *testing.T, env *testEnv, provider *testOIDCProvider) *OAuthService {
+	t.Helper()
+
+	cfg := env.cfg.Auth.OAuth
+	cfg.Providers = map[string]config.OAuthProviderConfig{
+		"test": {Type: "oidc", ClientID: testClientID, ClientSecret: testClientSecret, IssuerURL: provider.server.URL},
+	}
+	oauthService, err := NewOAuthService(
+		env.auth,
+		repository.NewUserRepository(env.db),
+		repository.NewUserIdentityRepository(env.db),
+		repository.NewOAuthStateRepository(env.db),
+		cfg,
+	)
+	if err != nil {
+		t.Fatalf("create OAuth service: %v", err)
+	}
+	return oauthService
+}
+
+// authorize starts login, or linking when userID is set, with the test provider
+func authorize(t *testing.T, oauthService *OAuthService, userID *uint) *OAuthAuthorizeResponse {
+	t.Helper()
+
+	response, err := oauthService.Authorize("test", userID)
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	if response.Binding == "" {
+		t.Fatal("authorize returned no binding")
+	}
+	return response
+}
+
+func TestOAuthLogin(t *testing.T) {
+	env := newTestEnv(t)
+	provider := newTestOIDCProvider(t)
+	oauthService := newTestOAuthService(t, env, provider)
+
+	// First login creates a verified user and links the identity
+	req := provider.authorize(t, authorize(t, oauthService, nil), "subject-1", "nora@example.com")
+	response, err := oauthService.Callback("test", req, nil, testIP, testUserAgent)
+	if err != nil {
+		t.Fatalf("callback: %v", err)
+	}
+	if response.Token == "" || response.User == nil || response.User.Email != "nora@example.com" {
+		t.Fatalf("callback response %+v, want session of nora@example.com", response.SigninResponse)
+	}
+	if _, validated, err := env.auth.ValidateToken(response.Token); err != nil || validated.ID != response.User.ID {
+		t.Fatalf("validate token of OAuth login: user %v, error %v", validated, err)
+	}
+	if response.User.Status != "active" || response.User.EmailVerifiedAt == nil {
+		t.Errorf("created user status %q verified at %v, want active and verified", response.User.Status, response.User.EmailVerifiedAt)
+	}
+
+	// Next login signs the same user in, whatever the email
+	req = provider.authorize(t, authorize(t, oauthService, nil), "subject-1", "nora@example.org")
+	again, err := oauthService.Callback("test", req, nil, testIP, testUserAgent)
+	if err != nil {
+		t.Fatalf("second callback: %v", err)
+	}
+	if again.User.ID != response.User.ID {
+		t.Errorf("second login signed in user %d, want %d", again.User.ID, response.User.ID)
+	}
+
+	// A verified address of an existing verified user links the identity to it
+	existing := env.signup(t, "leo")
+	req = provider.authorize(t, authorize(t, oauthService, nil), "subject-2", "leo@example.com")
+	linked, err := oauthService.Callback("test", req, nil, testIP, testUserAgent)
+	if err != nil {
+		t.Fatalf("callback with existing email: %v", err)
+	}
+	if linked.User.ID != existing.User.ID {
+		t.Errorf("login with existing email signed in user %d, want %d", linked.User.ID, existing.User.ID)
+	}
+
+	// An unverified address of an existing user does not
+	req = provider.authorize(t, authorize(t, oauthService, nil), "subject-3", "nora@example.com", func(claims jwt.MapClaims) {
+		claims["email_verified"] = false
+	})
+	if _, err := oauthService.Callback("test", req, nil, testIP, testUserAgent); err == nil {
+		t.Error("login with unverified email of existing user succeeded")
+	}
+}
+
+func TestOAuthCallbackRejected(t *testing.T) {
+	tests := []struct {
+		name    string
+		claims  func(claims jwt.MapClaims)
+		request func(req *OAuthCallbackRequest)
+		state   map[string]interface{}
+		wantErr string
+	}{
+		{
+			name:    "PKCE verifier mismatch",
+			state:   map[string]interface{}{"code_verifier": "other-verifier-other-verifier-other-verifier"},
+			wantErr: "failed to exchange authorization code",
+		},
+		{
+			name:    "unknown code",
+			request: func(req *OAuthCallbackRequest) { req.Code = "forged-code" },
+			wantErr: "failed to exchange authorization code",
+		},
+		{
+			name:    "nonce mismatch",
+			claims:  func(claims jwt.MapClaims) { claims["nonce"] = "other-nonce" },
+			wantErr: "nonce mismatch",
+		},
+		{
+			name:    "missing nonce",
+			claims:  func(claims jwt.MapClaims) { delete(claims, "nonce") },
+			wantErr: "nonce mismatch",
+		},
+		{
+			name:    "wrong audience",
+			claims:  func(claims jwt.MapClaims) { claims["aud"] = "other-client" },
+			wantErr: "invalid ID token",
+		},
+		{
+			name:    "wrong issuer",
+			claims:  func(claims jwt.MapClaims) { claims["iss"] = "https://issuer.example.com" },
+			wantErr: "invalid ID token",
+		},
+		{
+			name:    "expired ID token",
+			claims:  func(claims jwt.MapClaims) { claims["exp"] = time.Now().Add(-time.Hour).Unix() },
+			wantErr: "invalid ID token",
+		},
+		{
+			name:    "unverified email",
+			claims:  func(claims jwt.MapClaims) { claims["email_verified"] = false },
+			wantErr: "verified email address",
+		},
+		{
+			name:    "unknown state",
+			request: func(req *OAuthCallbackRequest) { req.State = "ggo_unknown" },
+			wantErr: "invalid",
+		},
+		{
+			name:    "expired state",
+			state:   map[string]interface{}{"expires_at": time.Now().Add(-time.Minute)},
+			wantErr: "OAuth state has expired",
+		},
+		{
+			name:    "missing binding",
+			request: func(req *OAuthCallbackRequest) { req.Binding = "" },
+			wantErr: "started in another browser",
+		},
+		{
+			name:    "binding of another browser",
+			request: func(req *OAuthCallbackRequest) { req.Binding = "ggo_other-browser" },
+			wantErr: "started in another browser",
+		},
+		{
+			name:    "provider error",
+			request: func(req *OAuthCallbackRequest) { req.Code, req.Error = "", "access_denied" },
+			wantErr: "provider denied login: access_denied",
+		},
+	}
+
+	env := newTestEnv(t)
+	provider := newTestOIDCProvider(t)
+	oauthService := newTestOAuthService(t, env, provider)
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var modify []func(claims jwt.MapClaims)
+			if tt.claims != nil {
+				modify = append(modify, tt.claims)
+			}
+			req := provider.authorize(t, authorize(t, oauthService, nil), "subject-"+strconv.Itoa(i), "user"+strconv.Itoa(i)+"@example.com", modify...)
+			if tt.state != nil {
+				if err := env.db.Model(&entity.OAuthState{}).Where("state_hash = ?", token.Hash(req.State)).Updates(tt.state).Error; err != nil {
+					t.Fatalf("update state: %v", err)
+				}
+			}
+			if tt.request != nil {
+				tt.request(req)
+			}
+
+			// Every case fails from its own address, so IP throttling does not mask the error
+			ipAddress := "192.0.2." + strconv.Itoa(10+i)
+			response, err := oauthService.Callback("test", req, nil, ipAddress, testUserAgent)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("callback = %+v, %v, want error containing %q", response, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestOAuthStateSingleUse(t *testing.T) {
+	env := newTestEnv(t)
+	provider := newTestOIDCProvider(t)
+	oauthService := newTestOAuthService(t, env, provider)
+
+	req := provider.authorize(t, authorize(t, oauthService, nil), "subject-1", "nora@example.com")
+	if _, err := oauthService.Callback("test", req, nil, testIP, testUserAgent); err != nil {
+		t.Fatalf("callback: %v", err)
+	}
+
+	// The provider would reject the redeemed code anyway, issue a fresh one to test the state alone
+	replay := provider.authorize(t, authorize(t, oauthService, nil), "subject-1", "nora@example.com")
+	replay.State, replay.Binding = req.State, req.Binding
+	if _, err := oauthService.Callback("test", replay, nil, testIP, testUserAgent); err == nil {
+		t.Fatal("callback accepted a state that was already used")
+	}
+
+	// A failed callback consumes the state too
+	failed := provider.authorize(t, authorize(t, oauthService, nil), "subject-1", "nora@example.com")
+	binding := failed.Binding
+	failed.Binding = "ggo_other-browser"
+	if _, err := oauthService.Callback("test", failed, nil, testIP, testUserAgent); err == nil {
+		t.Fatal("callback accepted binding of another browser")
+	}
+	failed.Binding = binding
+	if _, err := oauthService.Callback("test", failed, nil, testIP, testUserAgent); err == nil {
+		t.Error("callback accepted state after a failed attempt")
+	}
+}
+
+func TestOAuthLink(t *testing.T) {
+	env := newTestEnv(t)
+	provider := newTestOIDCProvider(t)
+	oauthService := newTestOAuthService(t, env, provider)
+	leo := env.signup(t, "leo").User
+	mia := env.signup(t, "mia").User
+
+	// Linking needs the user who started it, anonymous or other users cannot complete it
+	for _, userID := range []*uint{nil, &mia.ID} {
+		req := provider.authorize(t, authorize(t, oauthService, &leo.ID), "subject-leo", "leo@example.org")
+		if _, err := oauthService.Callback("test", req, userID, testIP, testUserAgent); err == nil || !strings.Contains(err.Error(), "signed-in user") {
+			t.Errorf("link completed by user %v = %v, want signed-in user error", userID, err)
+		}
+	}
+
+	req := provider.authorize(t, authorize(t, oauthService, &leo.ID), "subject-leo", "leo@example.org")
+	response, err := oauthService.Callback("test", req, &leo.ID, testIP, testUserAgent)
+	if err != nil {
+		t.Fatalf("link: %v", err)
+	}
+	if response.Identity == nil || response.Identity.UserID != leo.ID || response.Identity.Subject != "subject-leo" {
+		t.Fatalf("link returned identity %+v, want subject-leo of user %d", response.Identity, leo.ID)
+	}
+	if response.Token != "" {
+		t.Error("link issued a session token")
+	}
+
+	// The linked identity signs in to the existing user, although its email differs
+	req = provider.authorize(t, authorize(t, oauthService, nil), "subject-leo", "leo@example.org")
+	login, err := oauthService.Callback("test", req, nil, testIP, testUserAgent)
+	if err != nil {
+		t.Fatalf("login with linked identity: %v", err)
+	}
+	if login.User.ID != leo.ID {
+		t.Errorf("login with linked identity signed in user %d, want %d", login.User.ID, leo.ID)
+	}
+
+	// An identity linked to one user cannot be linked to another
+	req = provider.authorize(t, authorize(t, oauthService, &mia.ID), "subject-leo", "leo@example.org")
+	if _, err := oauthService.Callback("test", req, &mia.ID, testIP, testUserAgent); err == nil || !strings.Contains(err.Error(), "already linked") {
+		t.Errorf("link of identity of another user = %v, want already linked error", err)
+	}
+}
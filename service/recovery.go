@@ -0,0 +1,202 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"log"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/damonleelcx/go-gin-api/entity"
+)
+
+// recoveryCodeBytes random bytes per recovery code, 80 bits encode to 16 base32 characters
+const recoveryCodeBytes = 10
+
+// RegenerateRecoveryCodesRequest regenerate recovery codes request
+type RegenerateRecoveryCodesRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
+// RecoveryCodesResponse newly generated recovery codes, shown only once
+type RecoveryCodesResponse struct {
+	Codes   []string `json:"codes"`
+	Message string   `json:"message"`
+}
+
+// RecoveryCodeStatusResponse recovery code status response
+type RecoveryCodeStatusResponse struct {
+	Remaining int64 `json:"remaining"` // Unused recovery codes
+}
+
+// RecoverAccountRequest recover account request
+type RecoverAccountRequest struct {
+	Username     string `json:"username" binding:"required"` // Username or email
+	RecoveryCode string `json:"recovery_code" binding:"required"`
+	NewPassword  string `json:"new_password" binding:"required"` // Checked against password policy
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes of user with a new batch, requiring the current password
+func (s *AuthService) RegenerateRecoveryCodes(userID, sessionID uint, req *RegenerateRecoveryCodesRequest, ipAddress, userAgent string) (*RecoveryCodesResponse, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, errors.New("user does not exist")
+	}
+
+	valid, err := s.passwordHasher.Verify(req.Password, user.Password)
+	if err != nil {
+		log.Printf("failed to verify password hash of user %d: %v", user.ID, err)
+	}
+	if !valid {
+		return nil, errors.New("password is incorrect")
+	}
+
+	codes := make([]string, 0, s.config.RecoveryCodeCount)
+	records := make([]*entity.RecoveryCode, 0, s.config.RecoveryCodeCount)
+	for i := 0; i < s.config.RecoveryCodeCount; i++ {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, errors.New("failed to generate recovery code: " + err.Error())
+		}
+		codes = append(codes, code)
+		records = append(records, &entity.RecoveryCode{
+			UserID:   user.ID,
+			CodeHash: hashRecoveryCode(code),
+		})
+	}
+
+	if err := s.recoveryCodeRepo.ReplaceForUser(user.ID, records); err != nil {
+		return nil, errors.New("failed to save recovery codes: " + err.Error())
+	}
+
+	s.recordAuditEvent(user.ID, &sessionID, "recovery_codes_generated", ipAddress, userAgent, "")
+
+	return &RecoveryCodesResponse{
+		Codes:   codes,
+		Message: "Store these recovery codes in a safe place, each can be used once and they will not be shown again",
+	}, nil
+}
+
+// RecoveryCodeStatus returns number of unused recovery codes of user
+func (s *AuthService) RecoveryCodeStatus(userID uint) (*RecoveryCodeStatusResponse, error) {
+	remaining, err := s.recoveryCodeRepo.CountUnused(userID)
+	if err != nil {
+		return nil, errors.New("failed to count recovery codes: " + err.Error())
+	}
+	return &RecoveryCodeStatusResponse{Remaining: remaining}, nil
+}
+
+// RecoverAccount sets new password using a recovery code instead of an emailed reset link
+func (s *AuthService) RecoverAccount(req *RecoverAccountRequest, ipAddress, userAgent string) error {
+	// Check client IP throttling
+	ipKey := IPKey(ipAddress)
+	if err := s.loginThrottle.Check(ipKey); err != nil {
+		return err
+	}
+
+	// Find user
+	user, err := s.userRepo.FindByUsernameOrEmail(req.Username)
+	if err != nil {
+		s.loginThrottle.RecordFailure(ipKey, s.config.Lockout.MaxIPAttempts)
+		return errors.New("username or recovery code incorrect")
+	}
+
+	// Guessing recovery codes counts towards account lockout like failed signins
+	userKey := UserKey(user.ID)
+	if err := s.loginThrottle.Check(userKey); err != nil {
+		return err
+	}
+	if !s.isUserAllowed(user) {
+		return errors.New("account has been disabled")
+	}
+
+	// Find recovery code
+	code, err := s.recoveryCodeRepo.FindUnused(user.ID, hashRecoveryCode(req.RecoveryCode))
+	if err != nil {
+		s.loginThrottle.RecordFailure(userKey, s.config.Lockout.MaxUserAttempts)
+		s.loginThrottle.RecordFailure(ipKey, s.config.Lockout.MaxIPAttempts)
+		return errors.New("username or recovery code incorrect")
+	}
+
+	// Check password policy before the code is spent
+	if err := s.passwordPolicy.Validate(req.NewPassword, user.Username, user.Email); err != nil {
+		return err
+	}
+
+	// Mark code as used, losing a concurrent race means the code is spent
+	used, err := s.recoveryCodeRepo.MarkUsed(code.ID, time.Now())
+	if err != nil {
+		return errors.New("failed to update recovery code: " + err.Error())
+	}
+	if !used {
+		return errors.New("username or recovery code incorrect")
+	}
+
+	// Hash and update password
+	hashedPassword, err := s.passwordHasher.Hash(req.NewPassword)
+	if err != nil {
+		return errors.New("password encryption failed: " + err.Error())
+	}
+	user.Password = hashedPassword
+	if err := s.userRepo.Update(user); err != nil {
+		return errors.New("failed to update password: " + err.Error())
+	}
+
+	// Invalidate all sessions for this user, like a password reset
+	if err := s.LogoutAll(user.ID); err != nil {
+		log.Printf("failed to logout sessions of user %d: %v", user.ID, err)
+	}
+	if err := s.loginThrottle.Reset(userKey); err != nil {
+		log.Printf("failed to reset login attempts of user %d: %v", user.ID, err)
+	}
+
+	s.recordAuditEvent(user.ID, nil, "account_recovered", ipAddress, userAgent, "")
+
+	return nil
+}
+
+// useRecoveryCode spends unused recovery code of user, returns false if there is none or a concurrent
+// request spent it first
+func (s *AuthService) useRecoveryCode(userID uint, code string) bool {
+	record, err := s.recoveryCodeRepo.FindUnused(userID, hashRecoveryCode(code))
+	if err != nil {
+		return false
+	}
+	used, err := s.recoveryCodeRepo.MarkUsed(record.ID, time.Now())
+	if err != nil {
+		log.Printf("failed to update recovery code of user %d: %v", userID, err)
+		return false
+	}
+	return used
+}
+
+// generateRecoveryCode returns random code formatted as four dash separated groups, e.g. ABCD-EFGH-IJKL-MNOP
+func generateRecoveryCode() (string, error) {
+	bytes := make([]byte, recoveryCodeBytes)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	encoded := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(bytes)
+	groups := make([]string, 0, len(encoded)/4)
+	for i := 0; i < len(encoded); i += 4 {
+		groups = append(groups, encoded[i:i+4])
+	}
+	return strings.Join(groups, "-"), nil
+}
+
+// hashRecoveryCode returns hex SHA-256 digest of code ignoring case, dashes and whitespace.
+// Codes carry 80 random bits, so a fast digest is sufficient.
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToUpper(strings.Map(func(r rune) rune {
+		if r == '-' || unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, code))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
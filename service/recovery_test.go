@@ -0,0 +1,237 @@
+package service
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/damonleelcx/go-gin-api/entity"
+	"github.com/damonleelcx/go-gin-api/totp"
+)
+
+// recoveryCodePattern matches generated recovery codes
+var recoveryCodePattern = regexp.MustCompile(`^[A-Z2-7]{4}-[A-Z2-7]{4}-[A-Z2-7]{4}-[A-Z2-7]{4}$`)
+
+// regenerateRecoveryCodes generates a new batch of recovery codes for user signed in with session
+func (e *testEnv) regenerateRecoveryCodes(t *testing.T, userID, sessionID uint) []string {
+	t.Helper()
+
+	response, err := e.auth.RegenerateRecoveryCodes(userID, sessionID, &RegenerateRecoveryCodesRequest{Password: testPassword}, testIP, testUserAgent)
+	if err != nil {
+		t.Fatalf("regenerate recovery codes: %v", err)
+	}
+	return response.Codes
+}
+
+// remainingRecoveryCodes returns number of unused recovery codes of user
+func (e *testEnv) remainingRecoveryCodes(t *testing.T, userID uint) int64 {
+	t.Helper()
+
+	status, err := e.auth.RecoveryCodeStatus(userID)
+	if err != nil {
+		t.Fatalf("recovery code status: %v", err)
+	}
+	return status.Remaining
+}
+
+// recoverAccount sets newPassword of username with code
+func (e *testEnv) recoverAccount(username, code, newPassword string) error {
+	return e.auth.RecoverAccount(&RecoverAccountRequest{Username: username, RecoveryCode: code, NewPassword: newPassword}, testIP, testUserAgent)
+}
+
+func TestRecoveryCodeSingleUse(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.signup(t, "vera").User
+	signin := env.signin(t, "vera")
+	codes := env.regenerateRecoveryCodes(t, user.ID, signin.Session.ID)
+
+	if err := env.recoverAccount("vera", codes[0], "amber-falcon-17"); err != nil {
+		t.Fatalf("recover account: %v", err)
+	}
+	if got, want := env.remainingRecoveryCodes(t, user.ID), int64(len(codes)-1); got != want {
+		t.Errorf("remaining codes = %d, want %d", got, want)
+	}
+
+	// Recovery logs out every session and the new password signs in
+	if _, _, err := env.auth.ValidateToken(signin.Token); err == nil {
+		t.Error("session survived account recovery")
+	}
+	if _, err := env.signinFrom("vera", "amber-falcon-17", testIP); err != nil {
+		t.Fatalf("signin with recovered password: %v", err)
+	}
+
+	// A spent code is rejected, other codes still work
+	if err := env.recoverAccount("vera", codes[0], "coral-heron-58"); err == nil {
+		t.Fatal("recovery code was accepted twice")
+	}
+	if err := env.recoverAccount("vera", codes[1], "coral-heron-58"); err != nil {
+		t.Fatalf("recover account with another code: %v", err)
+	}
+
+	// A password rejected by the policy does not spend the code
+	if err := env.recoverAccount("vera", codes[2], "vera"); err == nil {
+		t.Fatal("recover account accepted password rejected by policy")
+	}
+	if err := env.recoverAccount("vera", codes[2], "slate-beetle-93"); err != nil {
+		t.Errorf("recover account after policy rejection: %v", err)
+	}
+}
+
+func TestRecoveryCodeMarkUsed(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.signup(t, "wes").User
+	codes := env.regenerateRecoveryCodes(t, user.ID, env.signin(t, "wes").Session.ID)
+
+	// The code is marked used only if it is still unused, so of two concurrent requests only one wins
+	code, err := env.auth.recoveryCodeRepo.FindUnused(user.ID, hashRecoveryCode(codes[0]))
+	if err != nil {
+		t.Fatalf("find code: %v", err)
+	}
+	if used, err := env.auth.recoveryCodeRepo.MarkUsed(code.ID, time.Now()); err != nil || !used {
+		t.Fatalf("first MarkUsed() = %v, %v, want true", used, err)
+	}
+	if used, err := env.auth.recoveryCodeRepo.MarkUsed(code.ID, time.Now()); err != nil || used {
+		t.Fatalf("second MarkUsed() = %v, %v, want false", used, err)
+	}
+
+	// A code found before a concurrent request spent it is not accepted again
+	if env.auth.useRecoveryCode(user.ID, codes[0]) {
+		t.Error("spent code was used again")
+	}
+	if err := env.recoverAccount("wes", codes[0], "amber-falcon-17"); err == nil {
+		t.Error("recover account accepted spent code")
+	}
+}
+
+func TestRegenerateRecoveryCodes(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.signup(t, "xena").User
+	sessionID := env.signin(t, "xena").Session.ID
+
+	if _, err := env.auth.RegenerateRecoveryCodes(user.ID, sessionID, &RegenerateRecoveryCodesRequest{Password: "wrong-password"}, testIP, testUserAgent); err == nil {
+		t.Fatal("regenerate with wrong password succeeded")
+	}
+	if got := env.remainingRecoveryCodes(t, user.ID); got != 0 {
+		t.Errorf("remaining codes before generation = %d, want 0", got)
+	}
+
+	old := env.regenerateRecoveryCodes(t, user.ID, sessionID)
+	if len(old) != env.cfg.Auth.RecoveryCodeCount {
+		t.Fatalf("generated %d codes, want %d", len(old), env.cfg.Auth.RecoveryCodeCount)
+	}
+	seen := make(map[string]bool)
+	for _, code := range old {
+		if !recoveryCodePattern.MatchString(code) {
+			t.Errorf("code %q does not match %s", code, recoveryCodePattern)
+		}
+		if seen[code] {
+			t.Errorf("code %q generated twice", code)
+		}
+		seen[code] = true
+	}
+
+	// Regenerating replaces the whole batch, used or not
+	if err := env.recoverAccount("xena", old[0], "amber-falcon-17"); err != nil {
+		t.Fatalf("recover account: %v", err)
+	}
+	recovered, err := env.signinFrom("xena", "amber-falcon-17", testIP)
+	if err != nil {
+		t.Fatalf("signin with recovered password: %v", err)
+	}
+	current, err := env.auth.RegenerateRecoveryCodes(user.ID, recovered.Session.ID, &RegenerateRecoveryCodesRequest{Password: "amber-falcon-17"}, testIP, testUserAgent)
+	if err != nil {
+		t.Fatalf("regenerate: %v", err)
+	}
+	if got := env.remainingRecoveryCodes(t, user.ID); got != int64(len(current.Codes)) {
+		t.Errorf("remaining codes after regeneration = %d, want %d", got, len(current.Codes))
+	}
+	if err := env.recoverAccount("xena", old[1], "coral-heron-58"); err == nil {
+		t.Fatal("code of the previous batch was accepted")
+	}
+	if err := env.recoverAccount("xena", current.Codes[0], "coral-heron-58"); err != nil {
+		t.Errorf("recover account with code of the new batch: %v", err)
+	}
+
+	var events int64
+	env.db.Model(&entity.AuditEvent{}).Where("user_id = ? AND event = ?", user.ID, "recovery_codes_generated").Count(&events)
+	if events != 2 {
+		t.Errorf("recorded %d recovery_codes_generated events, want 2", events)
+	}
+}
+
+func TestRecoveryCodeNormalization(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.signup(t, "yara").User
+	codes := env.regenerateRecoveryCodes(t, user.ID, env.signin(t, "yara").Session.ID)
+
+	tests := []struct {
+		name   string
+		format func(code string) string
+	}{
+		{"lower case", strings.ToLower},
+		{"without dashes", func(code string) string { return strings.ReplaceAll(code, "-", "") }},
+		{"spaces for dashes", func(code string) string { return strings.ReplaceAll(code, "-", " ") }},
+		{"surrounding whitespace", func(code string) string { return " \t" + code + "\n" }},
+		{"mixed", func(code string) string { return strings.ToLower(strings.ReplaceAll(code, "-", "\t")) + " " }},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code := tt.format(codes[i])
+			if hashRecoveryCode(code) != hashRecoveryCode(codes[i]) {
+				t.Fatalf("%q hashes differently from %q", code, codes[i])
+			}
+			if err := env.recoverAccount("yara", code, "amber-falcon-"+strconv.Itoa(10+i)); err != nil {
+				t.Errorf("recover account with %q: %v", code, err)
+			}
+		})
+	}
+
+	// Characters other than case, dashes and whitespace still matter
+	if hashRecoveryCode(codes[0]) == hashRecoveryCode(strings.ReplaceAll(codes[0], "-", "_")) {
+		t.Error("underscores were ignored like dashes")
+	}
+}
+
+func TestRecoveryCodeCompletesMFA(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.signup(t, "zoe").User
+	session := env.signin(t, "zoe").Session
+
+	enrollment, err := env.auth.EnrollTOTP(user.ID)
+	if err != nil {
+		t.Fatalf("enroll: %v", err)
+	}
+	if err := env.auth.ConfirmTOTP(user.ID, session.ID, &ConfirmTOTPRequest{Code: totpCode(t, enrollment.Secret, totp.Counter(time.Now()))}, testIP, testUserAgent); err != nil {
+		t.Fatalf("confirm: %v", err)
+	}
+	codes := env.regenerateRecoveryCodes(t, user.ID, session.ID)
+
+	// A recovery code instead of the authenticator code completes the challenge and is spent
+	response, err := env.mfaSignin(t, "zoe", strings.ToLower(codes[0]))
+	if err != nil {
+		t.Fatalf("signin with recovery code: %v", err)
+	}
+	if response.Token == "" || response.Session == nil {
+		t.Fatalf("signin with recovery code returned %+v, want session", response)
+	}
+	if got, want := env.remainingRecoveryCodes(t, user.ID), int64(len(codes)-1); got != want {
+		t.Errorf("remaining codes = %d, want %d", got, want)
+	}
+	var event entity.AuditEvent
+	if err := env.db.Where("user_id = ? AND event = ?", user.ID, "recovery_code_used").First(&event).Error; err != nil {
+		t.Errorf("find recovery_code_used event: %v", err)
+	} else if event.SessionID == nil || *event.SessionID != response.Session.ID {
+		t.Errorf("recovery_code_used event session = %v, want %d", event.SessionID, response.Session.ID)
+	}
+
+	// The spent code does not complete another challenge, which counts it as a wrong code
+	signin := env.signin(t, "zoe")
+	if _, err := env.auth.SigninMFA(&SigninMFARequest{MFAToken: signin.MFAToken, Code: codes[0]}, testIP, testUserAgent); err == nil {
+		t.Fatal("spent recovery code completed a challenge")
+	}
+	if _, err := env.auth.SigninMFA(&SigninMFARequest{MFAToken: signin.MFAToken, Code: codes[1]}, testIP, testUserAgent); err != nil {
+		t.Errorf("complete challenge with another code after a wrong one: %v", err)
+	}
+}
@@ -0,0 +1,168 @@
+package service
+
+import (
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"testing"
+
+	"github.com/damonleelcx/go-gin-api/config"
+	"github.com/damonleelcx/go-gin-api/database"
+	"github.com/damonleelcx/go-gin-api/mail"
+	"github.com/damonleelcx/go-gin-api/migration"
+	"github.com/damonleelcx/go-gin-api/password"
+	"github.com/damonleelcx/go-gin-api/repository"
+	"github.com/damonleelcx/go-gin-api/token"
+	"gorm.io/gorm"
+)
+
+const (
+	testIP        = "192.0.2.1"
+	testUserAgent = "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Safari/537.36"
+	testPassword  = "velvet-otter-42"
+)
+
+// testMailer records sent messages
+type testMailer struct {
+	mu       sync.Mutex
+	messages []*mail.Message
+}
+
+// Send implements mail.Mailer
+func (m *testMailer) Send(msg *mail.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, msg)
+	return nil
+}
+
+// tokenPattern matches token query parameter of links in emails
+var tokenPattern = regexp.MustCompile(`token=([^\s&"<]+)`)
+
+// lastToken returns token of the link in the last message sent to address
+func (m *testMailer) lastToken(t *testing.T, to string) string {
+	t.Helper()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := len(m.messages) - 1; i >= 0; i-- {
+		if m.messages[i].To != to {
+			continue
+		}
+		match := tokenPattern.FindStringSubmatch(m.messages[i].Text)
+		if match == nil {
+			t.Fatalf("no token in message %q", m.messages[i].Subject)
+		}
+		value, err := url.QueryUnescape(match[1])
+		if err != nil {
+			t.Fatalf("unescape token: %v", err)
+		}
+		return value
+	}
+	t.Fatalf("no message sent to %s", to)
+	return ""
+}
+
+// testEnv authentication service backed by file-backed SQLite database
+type testEnv struct {
+	cfg    *config.Config
+	db     *gorm.DB
+	mailer *testMailer
+	auth   *AuthService
+}
+
+// newTestEnv migrates temporary SQLite database and wires services the way server.go does,
+// configure adjusts the default configuration first
+func newTestEnv(t *testing.T, configure ...func(cfg *config.Config)) *testEnv {
+	t.Helper()
+
+	cfg := config.Default()
+	cfg.Database.DSN = filepath.Join(t.TempDir(), "test.db")
+	cfg.Auth.PasswordHashing.Algorithm = "bcrypt"
+	cfg.Auth.PasswordHashing.BcryptCost = 4
+	for _, fn := range configure {
+		fn(cfg)
+	}
+
+	db, err := database.Open(cfg.Database)
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	if _, err := migration.NewMigrator(db).Up(); err != nil {
+		t.Fatalf("migrate up: %v", err)
+	}
+
+	passwordPolicy, err := password.NewPolicy(cfg.Auth.PasswordPolicy, nil)
+	if err != nil {
+		t.Fatalf("password policy: %v", err)
+	}
+	passwordHasher, err := password.NewHasher(cfg.Auth.PasswordHashing)
+	if err != nil {
+		t.Fatalf("password hasher: %v", err)
+	}
+	var signer *token.Signer
+	if cfg.Auth.TokenFormat == "jwt" {
+		keys, err := token.NewKeyManager(cfg.Auth.JWT)
+		if err != nil {
+			t.Fatalf("signing keys: %v", err)
+		}
+		signer = token.NewSigner(keys, cfg.Auth.JWT)
+	}
+
+	mailer := &testMailer{}
+	auth := NewAuthService(
+		repository.NewUserRepository(db),
+		repository.NewSessionRepository(db),
+		repository.NewPasswordResetTokenRepository(db),
+		repository.NewRefreshTokenRepository(db),
+		repository.NewEmailVerificationTokenRepository(db),
+		repository.NewAuditEventRepository(db),
+		repository.NewMFAChallengeRepository(db),
+		repository.NewRecoveryCodeRepository(db),
+		repository.NewLoginTokenRepository(db),
+		NewLoginThrottle(repository.NewLoginAttemptRepository(db), cfg.Auth.Lockout),
+		passwordPolicy,
+		passwordHasher,
+		signer,
+		mailer,
+		cfg.Auth,
+	)
+
+	return &testEnv{cfg: cfg, db: db, mailer: mailer, auth: auth}
+}
+
+// signup registers user and verifies its email address
+func (e *testEnv) signup(t *testing.T, username string) *SignupResponse {
+	t.Helper()
+
+	email := username + "@example.com"
+	response, err := e.auth.Signup(&SignupRequest{
+		Username: username,
+		Email:    email,
+		Password: testPassword,
+	}, testIP, testUserAgent)
+	if err != nil {
+		t.Fatalf("signup: %v", err)
+	}
+	if err := e.auth.VerifyEmail(&VerifyEmailRequest{Token: e.mailer.lastToken(t, email)}); err != nil {
+		t.Fatalf("verify email: %v", err)
+	}
+	return response
+}
+
+// signin signs user in with the test password
+func (e *testEnv) signin(t *testing.T, username string) *SigninResponse {
+	t.Helper()
+
+	response, err := e.auth.Signin(&SigninRequest{Username: username, Password: testPassword}, testIP, testUserAgent)
+	if err != nil {
+		t.Fatalf("signin: %v", err)
+	}
+	return response
+}
@@ -0,0 +1,184 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/damonleelcx/go-gin-api/entity"
+)
+
+// SessionInfo active session as listed to its user, the token is never included
+type SessionInfo struct {
+	ID             uint       `json:"id"`
+	IPAddress      string     `json:"ip_address"`
+	UserAgent      string     `json:"user_agent"`
+	Device         string     `json:"device"`
+	Platform       string     `json:"platform"`
+	Browser        string     `json:"browser"`
+	BrowserVersion string     `json:"browser_version"`
+	LastUsedAt     *time.Time `json:"last_used_at"`
+	ExpiresAt      time.Time  `json:"expires_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	RememberMe     bool       `json:"remember_me"`
+	Current        bool       `json:"current"` // Session of the access token making the request
+}
+
+// sessionPolicy lifetime rules of a session
+type sessionPolicy struct {
+	ttl         time.Duration // Expiration is extended to this long after every use
+	idleTimeout time.Duration // Session ends after this long without use, 0 disables
+	maxLifetime time.Duration // Expiration is never extended past this long after signin
+}
+
+// sessionPolicy returns lifetime rules of sessions, remember me sessions follow the longer policy
+func (s *AuthService) sessionPolicy(rememberMe bool) sessionPolicy {
+	if rememberMe && s.config.RememberMe.Enabled {
+		return sessionPolicy{
+			ttl:         s.config.RememberMe.SessionTTL.Std(),
+			idleTimeout: s.config.RememberMe.IdleTimeout.Std(),
+			maxLifetime: s.config.RememberMe.MaxSessionLifetime.Std(),
+		}
+	}
+	return sessionPolicy{
+		ttl:         s.config.SessionTTL.Std(),
+		idleTimeout: s.config.IdleTimeout.Std(),
+		maxLifetime: s.config.MaxSessionLifetime.Std(),
+	}
+}
+
+// expiresAt returns expiration of session created at createdAt and used at now
+func (p sessionPolicy) expiresAt(createdAt, now time.Time) time.Time {
+	expiresAt := now.Add(p.ttl)
+	if limit := createdAt.Add(p.maxLifetime); expiresAt.After(limit) {
+		return limit
+	}
+	return expiresAt
+}
+
+// isIdle checks if session has not been used for longer than the idle timeout
+func (p sessionPolicy) isIdle(session *entity.Session, now time.Time) bool {
+	if p.idleTimeout <= 0 {
+		return false
+	}
+	lastUsedAt := session.CreatedAt
+	if session.LastUsedAt != nil {
+		lastUsedAt = *session.LastUsedAt
+	}
+	return now.Sub(lastUsedAt) > p.idleTimeout
+}
+
+// touchSession records use of active session and slides its expiration, sessions left idle
+// for longer than the idle timeout are expired instead
+func (s *AuthService) touchSession(session *entity.Session) error {
+	now := time.Now()
+	policy := s.sessionPolicy(session.RememberMe)
+	if policy.isIdle(session, now) {
+		session.Status = "expired"
+		if err := s.sessionRepo.Update(session); err != nil {
+			log.Printf("failed to expire idle session %d: %v", session.ID, err)
+		}
+		return errors.New("session has expired")
+	}
+
+	session.LastUsedAt = &now
+	session.ExpiresAt = policy.expiresAt(session.CreatedAt, now)
+	if err := s.sessionRepo.UpdateActivity(session.ID, now, session.ExpiresAt); err != nil {
+		log.Printf("failed to update activity of session %d: %v", session.ID, err)
+	}
+	return nil
+}
+
+// ListSessions lists active sessions of user, most recently used first
+func (s *AuthService) ListSessions(userID, currentSessionID uint) ([]*SessionInfo, error) {
+	sessions, err := s.sessionRepo.FindByUserID(userID)
+	if err != nil {
+		return nil, errors.New("failed to query sessions: " + err.Error())
+	}
+
+	now := time.Now()
+	infos := make([]*SessionInfo, 0, len(sessions))
+	for _, session := range sessions {
+		if !session.IsActive() || s.sessionPolicy(session.RememberMe).isIdle(session, now) {
+			continue
+		}
+		infos = append(infos, newSessionInfo(session, currentSessionID))
+	}
+	sort.SliceStable(infos, func(i, j int) bool {
+		return lastUsed(infos[i]).After(lastUsed(infos[j]))
+	})
+
+	return infos, nil
+}
+
+// RevokeSession revokes active session owned by user, together with its refresh tokens
+func (s *AuthService) RevokeSession(userID, currentSessionID, sessionID uint, ipAddress, userAgent string) error {
+	// Sessions of other users are reported as missing so their IDs cannot be probed
+	session, err := s.sessionRepo.FindByID(sessionID)
+	if err != nil || session.UserID != userID || !session.IsActive() {
+		return errors.New("session does not exist")
+	}
+
+	if err := s.refreshTokenRepo.RevokeBySessionID(session.ID); err != nil {
+		return errors.New("failed to revoke refresh tokens: " + err.Error())
+	}
+	session.Status = "revoked"
+	if err := s.sessionRepo.Update(session); err != nil {
+		return errors.New("failed to revoke session: " + err.Error())
+	}
+
+	s.recordAuditEvent(userID, &currentSessionID, "session_revoked", ipAddress, userAgent, "session "+strconv.FormatUint(uint64(session.ID), 10))
+	return nil
+}
+
+// RevokeOtherSessions revokes every active session of user except the current one, together with
+// their refresh tokens
+func (s *AuthService) RevokeOtherSessions(userID, currentSessionID uint, ipAddress, userAgent string) error {
+	sessions, err := s.sessionRepo.FindByUserID(userID)
+	if err != nil {
+		return errors.New("failed to query sessions: " + err.Error())
+	}
+	for _, session := range sessions {
+		if session.ID == currentSessionID || session.Status != "active" {
+			continue
+		}
+		if err := s.refreshTokenRepo.RevokeBySessionID(session.ID); err != nil {
+			return errors.New("failed to revoke refresh tokens: " + err.Error())
+		}
+	}
+
+	if err := s.sessionRepo.UpdateStatusByUserIDExcept(userID, currentSessionID, "revoked"); err != nil {
+		return errors.New("failed to revoke sessions: " + err.Error())
+	}
+
+	s.recordAuditEvent(userID, &currentSessionID, "other_sessions_revoked", ipAddress, userAgent, "")
+	return nil
+}
+
+// newSessionInfo converts session to its listed form
+func newSessionInfo(session *entity.Session, currentSessionID uint) *SessionInfo {
+	return &SessionInfo{
+		ID:             session.ID,
+		IPAddress:      session.IPAddress,
+		UserAgent:      session.UserAgent,
+		Device:         session.Device,
+		Platform:       session.Platform,
+		Browser:        session.Browser,
+		BrowserVersion: session.BrowserVersion,
+		LastUsedAt:     session.LastUsedAt,
+		ExpiresAt:      session.ExpiresAt,
+		CreatedAt:      session.CreatedAt,
+		RememberMe:     session.RememberMe,
+		Current:        session.ID == currentSessionID,
+	}
+}
+
+// lastUsed returns last use of session, falling back to its creation
+func lastUsed(info *SessionInfo) time.Time {
+	if info.LastUsedAt != nil {
+		return *info.LastUsedAt
+	}
+	return info.CreatedAt
+}
@@ -0,0 +1,331 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/damonleelcx/go-gin-api/config"
+	"github.com/damonleelcx/go-gin-api/entity"
+)
+
+// sessionPolicyConfig short policies: sessions last 1h up to 3h after signin and end after 30m idle,
+// remember me sessions last 24h up to 48h and never idle out
+func sessionPolicyConfig(cfg *config.Config) {
+	cfg.Auth.AccessTokenTTL = config.Duration(15 * time.Minute)
+	cfg.Auth.SessionTTL = config.Duration(time.Hour)
+	cfg.Auth.MaxSessionLifetime = config.Duration(3 * time.Hour)
+	cfg.Auth.IdleTimeout = config.Duration(30 * time.Minute)
+	cfg.Auth.RememberMe = config.RememberMeConfig{
+		Enabled:            true,
+		SessionTTL:         config.Duration(24 * time.Hour),
+		MaxSessionLifetime: config.Duration(48 * time.Hour),
+	}
+}
+
+// agedSession signs username in and moves creation and last use of the session into the past,
+// lastUsed 0 leaves the session unused since signin
+func (e *testEnv) agedSession(t *testing.T, username string, rememberMe bool, age, lastUsed time.Duration) *entity.Session {
+	t.Helper()
+
+	response, err := e.auth.Signin(&SigninRequest{Username: username, Password: testPassword, RememberMe: rememberMe}, testIP, testUserAgent)
+	if err != nil {
+		t.Fatalf("signin: %v", err)
+	}
+	now := time.Now()
+	updates := map[string]interface{}{"created_at": now.Add(-age), "last_used_at": nil}
+	if lastUsed > 0 {
+		updates["last_used_at"] = now.Add(-lastUsed)
+	}
+	if err := e.db.Model(&entity.Session{}).Where("id = ?", response.Session.ID).Updates(updates).Error; err != nil {
+		t.Fatalf("age session: %v", err)
+	}
+
+	session, err := e.auth.sessionRepo.FindByID(response.Session.ID)
+	if err != nil {
+		t.Fatalf("load session: %v", err)
+	}
+	return session
+}
+
+// assertNear checks that got is within a few seconds of want
+func assertNear(t *testing.T, name string, got, want time.Time) {
+	t.Helper()
+
+	if diff := got.Sub(want); diff < -5*time.Second || diff > 5*time.Second {
+		t.Errorf("%s = %v, want %v", name, got, want)
+	}
+}
+
+func TestTouchSession(t *testing.T) {
+	tests := []struct {
+		name       string
+		rememberMe bool
+		age        time.Duration // Time since signin
+		lastUsed   time.Duration // Time since last use, 0 if unused since signin
+		expiresIn  time.Duration // Expected expiration relative to now
+		idle       bool          // Expected to be ended for inactivity
+	}{
+		{name: "fresh", expiresIn: time.Hour},
+		{name: "slides forward", age: 90 * time.Minute, lastUsed: 10 * time.Minute, expiresIn: time.Hour},
+		{name: "capped by absolute limit", age: 150 * time.Minute, lastUsed: 10 * time.Minute, expiresIn: 30 * time.Minute},
+		{name: "idle since last use", age: time.Hour, lastUsed: 31 * time.Minute, idle: true},
+		{name: "idle since signin", age: 31 * time.Minute, idle: true},
+		{name: "remember me slides forward", rememberMe: true, age: 10 * time.Hour, lastUsed: 5 * time.Hour, expiresIn: 24 * time.Hour},
+		{name: "remember me capped by absolute limit", rememberMe: true, age: 40 * time.Hour, lastUsed: time.Hour, expiresIn: 8 * time.Hour},
+		{name: "remember me without idle timeout", rememberMe: true, age: 20 * time.Hour, expiresIn: 24 * time.Hour},
+	}
+
+	env := newTestEnv(t, sessionPolicyConfig)
+	env.signup(t, "hana")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := env.agedSession(t, "hana", tt.rememberMe, tt.age, tt.lastUsed)
+
+			err := env.auth.touchSession(session)
+			stored, findErr := env.auth.sessionRepo.FindByID(session.ID)
+			if findErr != nil {
+				t.Fatalf("reload session: %v", findErr)
+			}
+			if tt.idle {
+				if err == nil {
+					t.Fatal("touchSession() of idle session succeeded")
+				}
+				if stored.Status != "expired" {
+					t.Errorf("idle session status = %s, want expired", stored.Status)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("touchSession() error: %v", err)
+			}
+
+			now := time.Now()
+			assertNear(t, "ExpiresAt", session.ExpiresAt, now.Add(tt.expiresIn))
+			assertNear(t, "stored ExpiresAt", stored.ExpiresAt, now.Add(tt.expiresIn))
+			if stored.LastUsedAt == nil {
+				t.Fatal("last use was not recorded")
+			}
+			assertNear(t, "stored LastUsedAt", *stored.LastUsedAt, now)
+
+			// Expiration never passes the absolute limit of the policy
+			limit := 3 * time.Hour
+			if tt.rememberMe {
+				limit = 48 * time.Hour
+			}
+			if stored.ExpiresAt.After(stored.CreatedAt.Add(limit).Add(time.Second)) {
+				t.Errorf("ExpiresAt %v is past the absolute limit %v", stored.ExpiresAt, stored.CreatedAt.Add(limit))
+			}
+		})
+	}
+}
+
+func TestTouchSessionRememberMeDisabled(t *testing.T) {
+	// Without remember me support the flag is ignored and the default policy applies
+	env := newTestEnv(t, sessionPolicyConfig, func(cfg *config.Config) {
+		cfg.Auth.RememberMe.Enabled = false
+	})
+	env.signup(t, "ines")
+
+	session := env.agedSession(t, "ines", true, 10*time.Minute, 0)
+	if err := env.auth.touchSession(session); err != nil {
+		t.Fatalf("touchSession() error: %v", err)
+	}
+	assertNear(t, "ExpiresAt", session.ExpiresAt, time.Now().Add(time.Hour))
+
+	idle := env.agedSession(t, "ines", true, time.Hour, 31*time.Minute)
+	if err := env.auth.touchSession(idle); err == nil {
+		t.Error("idle session with remember me flag was extended")
+	}
+}
+
+func TestValidateTokenIdleSession(t *testing.T) {
+	env := newTestEnv(t, sessionPolicyConfig)
+	env.signup(t, "jade")
+	signin := env.signin(t, "jade")
+
+	// Using the session slides it forward
+	if _, _, err := env.auth.ValidateToken(signin.Token); err != nil {
+		t.Fatalf("validate token: %v", err)
+	}
+
+	// After the idle timeout the access token is rejected even though it has not expired
+	if err := env.db.Model(&entity.Session{}).Where("id = ?", signin.Session.ID).
+		Update("last_used_at", time.Now().Add(-31*time.Minute)).Error; err != nil {
+		t.Fatalf("age session: %v", err)
+	}
+	if _, _, err := env.auth.ValidateToken(signin.Token); err == nil {
+		t.Fatal("token of idle session was accepted")
+	}
+	if _, _, err := env.auth.ValidateToken(signin.Token); err == nil {
+		t.Error("token of expired idle session was accepted on retry")
+	}
+}
+
+// iPhoneUserAgent user agent of Safari on an iPhone
+const iPhoneUserAgent = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Mobile/15E148 Safari/604.1"
+
+// signinWith signs username in with the test password from userAgent
+func (e *testEnv) signinWith(t *testing.T, username, userAgent string) *SigninResponse {
+	t.Helper()
+
+	response, err := e.auth.Signin(&SigninRequest{Username: username, Password: testPassword}, testIP, userAgent)
+	if err != nil {
+		t.Fatalf("signin: %v", err)
+	}
+	return response
+}
+
+// revokedRefreshTokens returns revoked flag of the refresh tokens of session
+func (e *testEnv) revokedRefreshTokens(t *testing.T, sessionID uint) []bool {
+	t.Helper()
+
+	var tokens []entity.RefreshToken
+	if err := e.db.Where("session_id = ?", sessionID).Find(&tokens).Error; err != nil {
+		t.Fatalf("load refresh tokens: %v", err)
+	}
+	revoked := make([]bool, 0, len(tokens))
+	for _, token := range tokens {
+		revoked = append(revoked, token.Revoked)
+	}
+	return revoked
+}
+
+// assertSessionUsable checks whether access and refresh token of signin are accepted
+func (e *testEnv) assertSessionUsable(t *testing.T, name string, signin *SigninResponse, want bool) {
+	t.Helper()
+
+	if _, _, err := e.auth.ValidateToken(signin.Token); (err == nil) != want {
+		t.Errorf("access token of %s session accepted = %v, want %v", name, err == nil, want)
+	}
+	if _, err := e.auth.Refresh(&RefreshRequest{RefreshToken: signin.RefreshToken}, testIP, testUserAgent); (err == nil) != want {
+		t.Errorf("refresh token of %s session accepted = %v, want %v", name, err == nil, want)
+	}
+	for _, revoked := range e.revokedRefreshTokens(t, signin.Session.ID) {
+		if revoked == want {
+			t.Errorf("refresh token of %s session revoked = %v, want %v", name, revoked, !want)
+		}
+	}
+}
+
+func TestListSessions(t *testing.T) {
+	env := newTestEnv(t, sessionPolicyConfig)
+	user := env.signup(t, "kira").User
+	env.signup(t, "lena")
+
+	desktop := env.signinWith(t, "kira", testUserAgent)
+	phone := env.signinWith(t, "kira", iPhoneUserAgent)
+	revoked := env.signinWith(t, "kira", testUserAgent)
+	expired := env.signinWith(t, "kira", testUserAgent)
+	idle := env.signinWith(t, "kira", testUserAgent)
+	env.signinWith(t, "lena", testUserAgent)
+
+	if err := env.auth.RevokeSession(user.ID, phone.Session.ID, revoked.Session.ID, testIP, testUserAgent); err != nil {
+		t.Fatalf("revoke session: %v", err)
+	}
+	env.db.Model(&entity.Session{}).Where("id = ?", expired.Session.ID).Update("expires_at", time.Now().Add(-time.Second))
+	env.db.Model(&entity.Session{}).Where("id = ?", idle.Session.ID).Update("last_used_at", time.Now().Add(-time.Hour))
+
+	// The desktop session was used before the phone session but is used again now, so it is listed first
+	env.db.Model(&entity.Session{}).Where("id = ?", desktop.Session.ID).Update("last_used_at", time.Now().Add(-20*time.Minute))
+	env.db.Model(&entity.Session{}).Where("id = ?", phone.Session.ID).Update("last_used_at", time.Now().Add(-10*time.Minute))
+	if _, _, err := env.auth.ValidateToken(desktop.Token); err != nil {
+		t.Fatalf("validate token: %v", err)
+	}
+
+	sessions, err := env.auth.ListSessions(user.ID, phone.Session.ID)
+	if err != nil {
+		t.Fatalf("list sessions: %v", err)
+	}
+	if len(sessions) != 2 {
+		t.Fatalf("listed %d sessions, want the desktop and phone session", len(sessions))
+	}
+
+	want := []SessionInfo{
+		{ID: desktop.Session.ID, Device: "web", Platform: "linux", Browser: "Chrome", BrowserVersion: "126.0", Current: false},
+		{ID: phone.Session.ID, Device: "mobile", Platform: "ios", Browser: "Safari", BrowserVersion: "17.5", Current: true},
+	}
+	for i, w := range want {
+		got := sessions[i]
+		if got.ID != w.ID || got.Device != w.Device || got.Platform != w.Platform || got.Browser != w.Browser ||
+			got.BrowserVersion != w.BrowserVersion || got.Current != w.Current {
+			t.Errorf("session %d = %+v, want %+v", i, *got, w)
+		}
+		if got.IPAddress != testIP {
+			t.Errorf("session %d IP address = %s, want %s", i, got.IPAddress, testIP)
+		}
+	}
+}
+
+func TestRevokeSession(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.signup(t, "mira").User
+	other := env.signup(t, "nova").User
+
+	current := env.signin(t, "mira")
+	target := env.signin(t, "mira")
+	foreign := env.signin(t, "nova")
+
+	// Sessions of other users and unknown sessions cannot be revoked
+	for _, sessionID := range []uint{foreign.Session.ID, foreign.Session.ID + 100} {
+		if err := env.auth.RevokeSession(user.ID, current.Session.ID, sessionID, testIP, testUserAgent); err == nil || err.Error() != "session does not exist" {
+			t.Errorf("revoke session %d = %v, want session does not exist", sessionID, err)
+		}
+	}
+
+	if err := env.auth.RevokeSession(user.ID, current.Session.ID, target.Session.ID, testIP, testUserAgent); err != nil {
+		t.Fatalf("revoke session: %v", err)
+	}
+	env.assertSessionUsable(t, "revoked", target, false)
+	env.assertSessionUsable(t, "current", current, true)
+	env.assertSessionUsable(t, "other user's", foreign, true)
+
+	if err := env.auth.RevokeSession(user.ID, current.Session.ID, target.Session.ID, testIP, testUserAgent); err == nil {
+		t.Error("revoked session was revoked again")
+	}
+
+	var event entity.AuditEvent
+	if err := env.db.Where("user_id = ? AND event = ?", user.ID, "session_revoked").First(&event).Error; err != nil {
+		t.Fatalf("find session_revoked event: %v", err)
+	}
+	if event.SessionID == nil || *event.SessionID != current.Session.ID {
+		t.Errorf("session_revoked event session = %v, want %d", event.SessionID, current.Session.ID)
+	}
+	var foreignEvents int64
+	env.db.Model(&entity.AuditEvent{}).Where("user_id = ?", other.ID).Count(&foreignEvents)
+	if foreignEvents != 0 {
+		t.Errorf("recorded %d events for the other user, want 0", foreignEvents)
+	}
+}
+
+func TestRevokeOtherSessions(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.signup(t, "opal").User
+	env.signup(t, "pearl")
+
+	current := env.signin(t, "opal")
+	others := []*SigninResponse{env.signin(t, "opal"), env.signinWith(t, "opal", iPhoneUserAgent)}
+	foreign := env.signin(t, "pearl")
+
+	if err := env.auth.RevokeOtherSessions(user.ID, current.Session.ID, testIP, testUserAgent); err != nil {
+		t.Fatalf("revoke other sessions: %v", err)
+	}
+	for _, other := range others {
+		env.assertSessionUsable(t, "other", other, false)
+	}
+	env.assertSessionUsable(t, "current", current, true)
+	env.assertSessionUsable(t, "other user's", foreign, true)
+
+	sessions, err := env.auth.ListSessions(user.ID, current.Session.ID)
+	if err != nil {
+		t.Fatalf("list sessions: %v", err)
+	}
+	if len(sessions) != 1 || sessions[0].ID != current.Session.ID {
+		t.Errorf("sessions after revoking others = %+v, want only the current one", sessions)
+	}
+
+	var events int64
+	env.db.Model(&entity.AuditEvent{}).Where("user_id = ? AND event = ?", user.ID, "other_sessions_revoked").Count(&events)
+	if events != 1 {
+		t.Errorf("recorded %d other_sessions_revoked events, want 1", events)
+	}
+}
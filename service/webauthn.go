@@ -0,0 +1,482 @@
+package service
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/damonleelcx/go-gin-api/config"
+	"github.com/damonleelcx/go-gin-api/entity"
+	"github.com/damonleelcx/go-gin-api/repository"
+	"github.com/damonleelcx/go-gin-api/token"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
+// WebAuthnService passkey registration and login service
+type WebAuthnService struct {
+	authService    *AuthService
+	userRepo       repository.UserRepository
+	credentialRepo repository.WebAuthnCredentialRepository
+	ceremonyRepo   repository.WebAuthnCeremonyRepository
+	webAuthn       *webauthn.WebAuthn
+	config         config.WebAuthnConfig
+}
+
+// NewWebAuthnService creates a new passkey service instance
+func NewWebAuthnService(
+	authService *AuthService,
+	userRepo repository.UserRepository,
+	credentialRepo repository.WebAuthnCredentialRepository,
+	ceremonyRepo repository.WebAuthnCeremonyRepository,
+	cfg config.WebAuthnConfig,
+) (*WebAuthnService, error) {
+	timeout := webauthn.TimeoutConfig{
+		Enforce:    true,
+		Timeout:    cfg.CeremonyTTL.Std(),
+		TimeoutUVD: cfg.CeremonyTTL.Std(),
+	}
+	webAuthn, err := webauthn.New(&webauthn.Config{
+		RPID:          cfg.RPID,
+		RPDisplayName: cfg.RPDisplayName,
+		RPOrigins:     cfg.RPOrigins,
+		Timeouts: webauthn.TimeoutsConfig{
+			Login:        timeout,
+			Registration: timeout,
+		},
+	})
+	if err != nil {
+		return nil, errors.New("invalid WebAuthn configuration: " + err.Error())
+	}
+
+	return &WebAuthnService{
+		authService:    authService,
+		userRepo:       userRepo,
+		credentialRepo: credentialRepo,
+		ceremonyRepo:   ceremonyRepo,
+		webAuthn:       webAuthn,
+		config:         cfg,
+	}, nil
+}
+
+// Ceremony kinds
+const (
+	ceremonyRegistration = "registration"
+	ceremonyLogin        = "login"
+)
+
+// WebAuthnOptionsResponse options to pass to navigator.credentials.create() or .get()
+type WebAuthnOptionsResponse struct {
+	CeremonyID string `json:"ceremony_id"` // Must be sent back with the verify request
+	Options    any    `json:"options"`     // PublicKeyCredentialCreationOptions or PublicKeyCredentialRequestOptions wrapped in "publicKey"
+}
+
+// WebAuthnRegisterRequest finish passkey registration request
+type WebAuthnRegisterRequest struct {
+	CeremonyID string          `json:"ceremony_id" binding:"required"`
+	Name       string          `json:"name" binding:"max=100"`        // Label shown in the credential list
+	Credential json.RawMessage `json:"credential" binding:"required"` // PublicKeyCredential returned by navigator.credentials.create()
+}
+
+// WebAuthnLoginOptionsRequest begin passkey login request
+type WebAuthnLoginOptionsRequest struct {
+	Username string `json:"username"` // Optional username or email, omit for discoverable passkey login
+}
+
+// WebAuthnLoginRequest finish passkey login request
+type WebAuthnLoginRequest struct {
+	CeremonyID string          `json:"ceremony_id" binding:"required"`
+	Credential json.RawMessage `json:"credential" binding:"required"` // PublicKeyCredential returned by navigator.credentials.get()
+}
+
+// webAuthnUser adapts user and its credentials to webauthn.User
+type webAuthnUser struct {
+	user        *entity.User
+	credentials []*entity.WebAuthnCredential
+}
+
+// WebAuthnID implements webauthn.User, the user handle is the big-endian user ID
+func (u *webAuthnUser) WebAuthnID() []byte {
+	return userHandle(u.user.ID)
+}
+
+// WebAuthnName implements webauthn.User
+func (u *webAuthnUser) WebAuthnName() string {
+	return u.user.Username
+}
+
+// WebAuthnDisplayName implements webauthn.User
+func (u *webAuthnUser) WebAuthnDisplayName() string {
+	if name := strings.TrimSpace(u.user.FirstName + " " + u.user.LastName); name != "" {
+		return name
+	}
+	return u.user.Username
+}
+
+// WebAuthnCredentials implements webauthn.User
+func (u *webAuthnUser) WebAuthnCredentials() []webauthn.Credential {
+	credentials := make([]webauthn.Credential, 0, len(u.credentials))
+	for _, credential := range u.credentials {
+		credentials = append(credentials, toWebAuthnCredential(credential))
+	}
+	return credentials
+}
+
+// BeginRegistration returns options for registering a new passkey of user
+func (s *WebAuthnService) BeginRegistration(userID uint) (*WebAuthnOptionsResponse, error) {
+	user, err := s.loadUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Exclude existing credentials so the same authenticator is not registered twice
+	creation, session, err := s.webAuthn.BeginRegistration(user,
+		webauthn.WithExclusions(webauthn.Credentials(user.WebAuthnCredentials()).CredentialDescriptors()),
+		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementPreferred),
+	)
+	if err != nil {
+		return nil, errors.New("failed to begin registration: " + err.Error())
+	}
+
+	ceremonyID, err := s.saveCeremony(ceremonyRegistration, &userID, session)
+	if err != nil {
+		return nil, err
+	}
+	return &WebAuthnOptionsResponse{CeremonyID: ceremonyID, Options: creation}, nil
+}
+
+// FinishRegistration verifies authenticator attestation and stores the new passkey
+func (s *WebAuthnService) FinishRegistration(userID, sessionID uint, req *WebAuthnRegisterRequest, ipAddress, userAgent string) (*entity.WebAuthnCredential, error) {
+	ceremony, session, err := s.consumeCeremony(req.CeremonyID, ceremonyRegistration)
+	if err != nil {
+		return nil, err
+	}
+	if ceremony.UserID == nil || *ceremony.UserID != userID {
+		return nil, errors.New("ceremony invalid")
+	}
+
+	user, err := s.loadUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBytes(req.Credential)
+	if err != nil {
+		return nil, errors.New("invalid credential: " + protocolErrorDetails(err))
+	}
+	created, err := s.webAuthn.CreateCredential(user, *session, parsed)
+	if err != nil {
+		return nil, errors.New("credential verification failed: " + protocolErrorDetails(err))
+	}
+
+	if _, err := s.credentialRepo.FindByCredentialID(created.ID); err == nil {
+		return nil, errors.New("passkey is already registered")
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = "Passkey"
+	}
+	credential := &entity.WebAuthnCredential{UserID: userID, Name: name}
+	applyWebAuthnCredential(credential, created)
+	if err := s.credentialRepo.Create(credential); err != nil {
+		return nil, errors.New("failed to save credential: " + err.Error())
+	}
+
+	s.authService.recordAuditEvent(userID, &sessionID, "passkey_added", ipAddress, userAgent, name)
+
+	return credential, nil
+}
+
+// BeginLogin returns options for passkey login. Without username, or when the username is unknown or has
+// no passkeys, discoverable login options are returned so the response does not reveal which accounts exist.
+func (s *WebAuthnService) BeginLogin(req *WebAuthnLoginOptionsRequest) (*WebAuthnOptionsResponse, error) {
+	if req.Username != "" {
+		if user, err := s.userRepo.FindByUsernameOrEmail(req.Username); err == nil {
+			waUser, err := s.loadUser(user.ID)
+			if err != nil {
+				return nil, err
+			}
+			if len(waUser.credentials) > 0 {
+				assertion, session, err := s.webAuthn.BeginLogin(waUser, webauthn.WithUserVerification(protocol.VerificationRequired))
+				if err != nil {
+					return nil, errors.New("failed to begin login: " + err.Error())
+				}
+				ceremonyID, err := s.saveCeremony(ceremonyLogin, &user.ID, session)
+				if err != nil {
+					return nil, err
+				}
+				return &WebAuthnOptionsResponse{CeremonyID: ceremonyID, Options: assertion}, nil
+			}
+		}
+	}
+
+	assertion, session, err := s.webAuthn.BeginDiscoverableLogin(webauthn.WithUserVerification(protocol.VerificationRequired))
+	if err != nil {
+		return nil, errors.New("failed to begin login: " + err.Error())
+	}
+	ceremonyID, err := s.saveCeremony(ceremonyLogin, nil, session)
+	if err != nil {
+		return nil, err
+	}
+	return &WebAuthnOptionsResponse{CeremonyID: ceremonyID, Options: assertion}, nil
+}
+
+// FinishLogin verifies authenticator assertion and creates session. Passkeys require user verification,
+// so they satisfy two-factor authentication on their own.
+func (s *WebAuthnService) FinishLogin(req *WebAuthnLoginRequest, ipAddress, userAgent string) (*SigninResponse, error) {
+	throttle := s.authService.loginThrottle
+	lockout := s.authService.config.Lockout
+
+	// Check client IP throttling
+	ipKey := IPKey(ipAddress)
+	if err := throttle.Check(ipKey); err != nil {
+		return nil, err
+	}
+
+	ceremony, session, err := s.consumeCeremony(req.CeremonyID, ceremonyLogin)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(req.Credential)
+	if err != nil {
+		throttle.RecordFailure(ipKey, lockout.MaxIPAttempts)
+		return nil, errors.New("invalid credential: " + protocolErrorDetails(err))
+	}
+
+	// Resolve user from the ceremony, or from the user handle of a discoverable credential
+	var user *webAuthnUser
+	if ceremony.UserID != nil {
+		user, err = s.loadUser(*ceremony.UserID)
+	} else {
+		user, err = s.loadUserByHandle(parsed.Response.UserHandle)
+	}
+	if err != nil {
+		throttle.RecordFailure(ipKey, lockout.MaxIPAttempts)
+		return nil, errors.New("passkey not recognized")
+	}
+
+	// Check account throttling and status
+	userKey := UserKey(user.user.ID)
+	if err := throttle.Check(userKey); err != nil {
+		return nil, err
+	}
+	if !s.authService.isUserAllowed(user.user) {
+		return nil, errors.New("account has been disabled")
+	}
+
+	var validated *webauthn.Credential
+	if ceremony.UserID != nil {
+		validated, err = s.webAuthn.ValidateLogin(user, *session, parsed)
+	} else {
+		validated, err = s.webAuthn.ValidateDiscoverableLogin(func(rawID, handle []byte) (webauthn.User, error) {
+			return user, nil
+		}, *session, parsed)
+	}
+	if err != nil {
+		throttle.RecordFailure(userKey, lockout.MaxUserAttempts)
+		throttle.RecordFailure(ipKey, lockout.MaxIPAttempts)
+		return nil, errors.New("passkey verification failed: " + protocolErrorDetails(err))
+	}
+
+	// Record sign count and flags; a counter that did not increase suggests a cloned authenticator
+	credential := user.credential(validated.ID)
+	if credential == nil {
+		return nil, errors.New("passkey not recognized")
+	}
+	// The warning is kept on the credential, which stays unusable until removed and registered again
+	applyWebAuthnCredential(credential, validated)
+	if validated.Authenticator.CloneWarning {
+		if err := s.credentialRepo.Update(credential); err != nil {
+			log.Printf("failed to flag passkey %d of user %d: %v", credential.ID, user.user.ID, err)
+		}
+		log.Printf("passkey %d of user %d reported a non-increasing sign count, possible clone", credential.ID, user.user.ID)
+		return nil, errors.New("passkey sign count check failed, the authenticator may be cloned")
+	}
+	now := time.Now()
+	credential.LastUsedAt = &now
+	if err := s.credentialRepo.Update(credential); err != nil {
+		return nil, errors.New("failed to update credential: " + err.Error())
+	}
+
+	if err := throttle.Reset(userKey); err != nil {
+		log.Printf("failed to reset login attempts of user %d: %v", user.user.ID, err)
+	}
+
+	// Create session
+	response, err := s.authService.createSession(user.user, false, ipAddress, userAgent)
+	if err != nil {
+		return nil, err
+	}
+	response.Message = "Login successful"
+
+	return response, nil
+}
+
+// ListCredentials returns passkeys of user
+func (s *WebAuthnService) ListCredentials(userID uint) ([]*entity.WebAuthnCredential, error) {
+	credentials, err := s.credentialRepo.FindByUserID(userID)
+	if err != nil {
+		return nil, errors.New("failed to query credentials: " + err.Error())
+	}
+	return credentials, nil
+}
+
+// DeleteCredential removes passkey of user
+func (s *WebAuthnService) DeleteCredential(userID, sessionID, credentialID uint, ipAddress, userAgent string) error {
+	deleted, err := s.credentialRepo.DeleteForUser(credentialID, userID)
+	if err != nil {
+		return errors.New("failed to delete credential: " + err.Error())
+	}
+	if !deleted {
+		return errors.New("credential does not exist")
+	}
+
+	s.authService.recordAuditEvent(userID, &sessionID, "passkey_removed", ipAddress, userAgent, "")
+
+	return nil
+}
+
+// loadUser loads user and its credentials
+func (s *WebAuthnService) loadUser(userID uint) (*webAuthnUser, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, errors.New("user does not exist")
+	}
+	credentials, err := s.credentialRepo.FindByUserID(userID)
+	if err != nil {
+		return nil, errors.New("failed to query credentials: " + err.Error())
+	}
+	return &webAuthnUser{user: user, credentials: credentials}, nil
+}
+
+// loadUserByHandle loads user identified by user handle of a discoverable credential
+func (s *WebAuthnService) loadUserByHandle(handle []byte) (*webAuthnUser, error) {
+	if len(handle) != 8 {
+		return nil, errors.New("invalid user handle")
+	}
+	return s.loadUser(uint(binary.BigEndian.Uint64(handle)))
+}
+
+// saveCeremony stores WebAuthn session data and returns ceremony ID
+func (s *WebAuthnService) saveCeremony(kind string, userID *uint, session *webauthn.SessionData) (string, error) {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return "", errors.New("failed to encode ceremony: " + err.Error())
+	}
+	plainToken, err := generatePrefixedToken(token.CeremonyPrefix)
+	if err != nil {
+		return "", errors.New("failed to generate token: " + err.Error())
+	}
+
+	ceremony := &entity.WebAuthnCeremony{
+		Token:     plainToken,
+		TokenHash: token.Hash(plainToken),
+		UserID:    userID,
+		Kind:      kind,
+		Data:      string(data),
+		ExpiresAt: time.Now().Add(s.config.CeremonyTTL.Std()),
+	}
+	if err := s.ceremonyRepo.Create(ceremony); err != nil {
+		return "", errors.New("failed to save ceremony: " + err.Error())
+	}
+	return plainToken, nil
+}
+
+// consumeCeremony loads and deletes ceremony, checking kind and expiration
+func (s *WebAuthnService) consumeCeremony(token, kind string) (*entity.WebAuthnCeremony, *webauthn.SessionData, error) {
+	ceremony, err := s.ceremonyRepo.Consume(token)
+	if err != nil {
+		return nil, nil, err
+	}
+	if ceremony.Kind != kind {
+		return nil, nil, errors.New("ceremony invalid")
+	}
+	if ceremony.IsExpired() {
+		return nil, nil, errors.New("ceremony has expired")
+	}
+
+	var session webauthn.SessionData
+	if err := json.Unmarshal([]byte(ceremony.Data), &session); err != nil {
+		return nil, nil, errors.New("failed to decode ceremony: " + err.Error())
+	}
+	return ceremony, &session, nil
+}
+
+// credential returns stored credential with authenticator credential ID
+func (u *webAuthnUser) credential(id []byte) *entity.WebAuthnCredential {
+	for _, credential := range u.credentials {
+		if bytes.Equal(credential.CredentialID, id) {
+			return credential
+		}
+	}
+	return nil
+}
+
+// userHandle returns WebAuthn user handle of user ID
+func userHandle(userID uint) []byte {
+	handle := make([]byte, 8)
+	binary.BigEndian.PutUint64(handle, uint64(userID))
+	return handle
+}
+
+// toWebAuthnCredential converts stored credential to library credential
+func toWebAuthnCredential(credential *entity.WebAuthnCredential) webauthn.Credential {
+	var transports []protocol.AuthenticatorTransport
+	if credential.Transports != "" {
+		for _, transport := range strings.Split(credential.Transports, ",") {
+			transports = append(transports, protocol.AuthenticatorTransport(transport))
+		}
+	}
+	return webauthn.Credential{
+		ID:              credential.CredentialID,
+		PublicKey:       credential.PublicKey,
+		AttestationType: credential.AttestationType,
+		Transport:       transports,
+		Flags: webauthn.CredentialFlags{
+			UserPresent:    credential.UserPresent,
+			UserVerified:   credential.UserVerified,
+			BackupEligible: credential.BackupEligible,
+			BackupState:    credential.BackupState,
+		},
+		Authenticator: webauthn.Authenticator{
+			AAGUID:       credential.AAGUID,
+			SignCount:    credential.SignCount,
+			CloneWarning: credential.CloneWarning,
+		},
+	}
+}
+
+// applyWebAuthnCredential copies library credential fields to stored credential
+func applyWebAuthnCredential(credential *entity.WebAuthnCredential, source *webauthn.Credential) {
+	transports := make([]string, 0, len(source.Transport))
+	for _, transport := range source.Transport {
+		transports = append(transports, string(transport))
+	}
+
+	credential.CredentialID = source.ID
+	credential.PublicKey = source.PublicKey
+	credential.AttestationType = source.AttestationType
+	credential.Transports = strings.Join(transports, ",")
+	credential.AAGUID = source.Authenticator.AAGUID
+	credential.SignCount = source.Authenticator.SignCount
+	credential.CloneWarning = source.Authenticator.CloneWarning
+	credential.UserPresent = source.Flags.UserPresent
+	credential.UserVerified = source.Flags.UserVerified
+	credential.BackupEligible = source.Flags.BackupEligible
+	credential.BackupState = source.Flags.BackupState
+}
+
+// protocolErrorDetails returns WebAuthn protocol error including its details
+func protocolErrorDetails(err error) string {
+	var protocolErr *protocol.Error
+	if errors.As(err, &protocolErr) && protocolErr.DevInfo != "" {
+		return protocolErr.Details + ": " + protocolErr.DevInfo
+	}
+	return err.Error()
+}
@@ -0,0 +1,469 @@
+package service
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/damonleelcx/go-gin-api/entity"
+	"github.com/damonleelcx/go-gin-api/repository"
+	"github.com/damonleelcx/go-gin-api/token"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/go-webauthn/webauthn/protocol/webauthncose"
+)
+
+const testOrigin = "http://localhost:8080"
+
+// Authenticator data flags
+const (
+	flagUserPresent  = 0x01
+	flagUserVerified = 0x04
+	flagAttested     = 0x40
+)
+
+// softAuthenticator software authenticator holding one ES256 passkey
+type softAuthenticator struct {
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+	userHandle   []byte
+	signCount    uint32
+}
+
+// authenticatorResponse adjusts what the authenticator and the browser report for a ceremony
+type authenticatorResponse struct {
+	rpID      string // Relying party ID hashed into authenticator data
+	origin    string // Origin reported by the browser
+	flags     byte   // Flags other than attested credential data
+	signCount uint32 // Counter reported by the authenticator
+}
+
+// newSoftAuthenticator creates authenticator with a fresh key pair
+func newSoftAuthenticator(t *testing.T) *softAuthenticator {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate authenticator key: %v", err)
+	}
+	credentialID := make([]byte, 16)
+	if _, err := rand.Read(credentialID); err != nil {
+		t.Fatalf("generate credential ID: %v", err)
+	}
+	return &softAuthenticator{key: key, credentialID: credentialID}
+}
+
+// defaultResponse returns a valid response for the test relying party, with the next sign count
+func (a *softAuthenticator) defaultResponse() authenticatorResponse {
+	return authenticatorResponse{
+		rpID:      "localhost",
+		origin:    testOrigin,
+		flags:     flagUserPresent | flagUserVerified,
+		signCount: a.signCount + 1,
+	}
+}
+
+// clientData returns clientDataJSON the browser would send for a ceremony
+func clientData(ceremonyType, challenge, origin string) []byte {
+	data, _ := json.Marshal(map[string]interface{}{
+		"type":        ceremonyType,
+		"challenge":   challenge,
+		"origin":      origin,
+		"crossOrigin": false,
+	})
+	return data
+}
+
+// authenticatorData returns authenticator data, attestedCredential is appended when set
+func authenticatorData(response authenticatorResponse, attestedCredential []byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(response.rpID))
+	data := append([]byte{}, rpIDHash[:]...)
+	flags := response.flags
+	if attestedCredential != nil {
+		flags |= flagAttested
+	}
+	data = append(data, flags)
+	data = binary.BigEndian.AppendUint32(data, response.signCount)
+	return append(data, attestedCredential...)
+}
+
+// create answers registration options like navigator.credentials.create() with "none" attestation
+func (a *softAuthenticator) create(t *testing.T, options *WebAuthnOptionsResponse, response authenticatorResponse) json.RawMessage {
+	t.Helper()
+
+	creation, ok := options.Options.(*protocol.CredentialCreation)
+	if !ok {
+		t.Fatalf("registration options are %T", options.Options)
+	}
+	a.userHandle = creation.Response.User.ID.(protocol.URLEncodedBase64)
+
+	publicKey, err := webauthncbor.Marshal(webauthncose.EC2PublicKeyData{
+		PublicKeyData: webauthncose.PublicKeyData{
+			KeyType:   int64(webauthncose.EllipticKey),
+			Algorithm: int64(webauthncose.AlgES256),
+		},
+		Curve:  int64(webauthncose.P256),
+		XCoord: a.key.X.FillBytes(make([]byte, 32)),
+		YCoord: a.key.Y.FillBytes(make([]byte, 32)),
+	})
+	if err != nil {
+		t.Fatalf("encode public key: %v", err)
+	}
+	attested := make([]byte, 16) // AAGUID of an authenticator without attestation
+	attested = binary.BigEndian.AppendUint16(attested, uint16(len(a.credentialID)))
+	attested = append(attested, a.credentialID...)
+	attested = append(attested, publicKey...)
+
+	attestation, err := webauthncbor.Marshal(map[string]interface{}{
+		"fmt":      "none",
+		"attStmt":  map[string]interface{}{},
+		"authData": authenticatorData(response, attested),
+	})
+	if err != nil {
+		t.Fatalf("encode attestation object: %v", err)
+	}
+	a.signCount = response.signCount
+
+	return a.credential(t, map[string]interface{}{
+		"clientDataJSON":    encode(clientData("webauthn.create", creation.Response.Challenge.String(), response.origin)),
+		"attestationObject": encode(attestation),
+		"transports":        []string{"internal"},
+	})
+}
+
+// get answers login options like navigator.credentials.get()
+func (a *softAuthenticator) get(t *testing.T, options *WebAuthnOptionsResponse, response authenticatorResponse) json.RawMessage {
+	t.Helper()
+
+	assertion, ok := options.Options.(*protocol.CredentialAssertion)
+	if !ok {
+		t.Fatalf("login options are %T", options.Options)
+	}
+	data := authenticatorData(response, nil)
+	client := clientData("webauthn.get", assertion.Response.Challenge.String(), response.origin)
+
+	clientHash := sha256.Sum256(client)
+	digest := sha256.Sum256(append(append([]byte{}, data...), clientHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		t.Fatalf("sign assertion: %v", err)
+	}
+	a.signCount = response.signCount
+
+	return a.credential(t, map[string]interface{}{
+		"clientDataJSON":    encode(client),
+		"authenticatorData": encode(data),
+		"signature":         encode(signature),
+		"userHandle":        encode(a.userHandle),
+	})
+}
+
+// credential wraps authenticator response in a PublicKeyCredential
+func (a *softAuthenticator) credential(t *testing.T, response map[string]interface{}) json.RawMessage {
+	t.Helper()
+
+	credential, err := json.Marshal(map[string]interface{}{
+		"id":                      encode(a.credentialID),
+		"rawId":                   encode(a.credentialID),
+		"type":                    "public-key",
+		"authenticatorAttachment": "platform",
+		"clientExtensionResults":  map[string]interface{}{},
+		"response":                response,
+	})
+	if err != nil {
+		t.Fatalf("encode credential: %v", err)
+	}
+	return credential
+}
+
+// encode base64url encodes data without padding
+func encode(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// newTestWebAuthnService creates passkey service for the test relying party
+func newTestWebAuthnService(t *testing.T, env *testEnv) *WebAuthnService {
+	t.Helper()
+
+	webAuthnService, err := NewWebAuthnService(
+		env.auth,
+		repository.NewUserRepository(env.db),
+		repository.NewWebAuthnCredentialRepository(env.db),
+		repository.NewWebAuthnCeremonyRepository(env.db),
+		env.cfg.Auth.WebAuthn,
+	)
+	if err != nil {
+		t.Fatalf("create WebAuthn service: %v", err)
+	}
+	return webAuthnService
+}
+
+// expireCeremony moves expiration of ceremony into the past
+func expireCeremony(t *testing.T, env *testEnv, ceremonyID string) {
+	t.Helper()
+
+	if err := env.db.Model(&entity.WebAuthnCeremony{}).Where("token_hash = ?", token.Hash(ceremonyID)).
+		Update("expires_at", time.Now().Add(-time.Minute)).Error; err != nil {
+		t.Fatalf("expire ceremony: %v", err)
+	}
+}
+
+// register adds passkey of authenticator to user
+func register(t *testing.T, webAuthnService *WebAuthnService, user *entity.User, authenticator *softAuthenticator) *entity.WebAuthnCredential {
+	t.Helper()
+
+	options, err := webAuthnService.BeginRegistration(user.ID)
+	if err != nil {
+		t.Fatalf("begin registration: %v", err)
+	}
+	credential, err := webAuthnService.FinishRegistration(user.ID, 1, &WebAuthnRegisterRequest{
+		CeremonyID: options.CeremonyID,
+		Name:       "Laptop",
+		Credential: authenticator.create(t, options, authenticator.defaultResponse()),
+	}, testIP, testUserAgent)
+	if err != nil {
+		t.Fatalf("finish registration: %v", err)
+	}
+	return credential
+}
+
+func TestWebAuthnCeremonies(t *testing.T) {
+	env := newTestEnv(t)
+	webAuthnService := newTestWebAuthnService(t, env)
+	user := env.signup(t, "leo").User
+	authenticator := newSoftAuthenticator(t)
+
+	credential := register(t, webAuthnService, user, authenticator)
+	if credential.Name != "Laptop" || credential.SignCount != 1 || !credential.UserVerified {
+		t.Errorf("registered credential %q with sign count %d, user verified %v", credential.Name, credential.SignCount, credential.UserVerified)
+	}
+
+	// The same authenticator is excluded from registering again
+	options, err := webAuthnService.BeginRegistration(user.ID)
+	if err != nil {
+		t.Fatalf("begin second registration: %v", err)
+	}
+	excluded := options.Options.(*protocol.CredentialCreation).Response.CredentialExcludeList
+	if len(excluded) != 1 || string(excluded[0].CredentialID) != string(authenticator.credentialID) {
+		t.Errorf("registration excludes %v, want the registered credential", excluded)
+	}
+	if _, err := webAuthnService.FinishRegistration(user.ID, 1, &WebAuthnRegisterRequest{
+		CeremonyID: options.CeremonyID,
+		Credential: authenticator.create(t, options, authenticator.defaultResponse()),
+	}, testIP, testUserAgent); err == nil {
+		t.Error("passkey was registered twice")
+	}
+
+	// Login with username and with a discoverable credential
+	for _, username := range []string{"leo", ""} {
+		options, err := webAuthnService.BeginLogin(&WebAuthnLoginOptionsRequest{Username: username})
+		if err != nil {
+			t.Fatalf("begin login as %q: %v", username, err)
+		}
+		response, err := webAuthnService.FinishLogin(&WebAuthnLoginRequest{
+			CeremonyID: options.CeremonyID,
+			Credential: authenticator.get(t, options, authenticator.defaultResponse()),
+		}, testIP, testUserAgent)
+		if err != nil {
+			t.Fatalf("finish login as %q: %v", username, err)
+		}
+		if response.Token == "" || response.User.ID != user.ID {
+			t.Fatalf("login as %q returned %+v, want session of user %d", username, response, user.ID)
+		}
+	}
+
+	stored, err := repository.NewWebAuthnCredentialRepository(env.db).FindByUserID(user.ID)
+	if err != nil || len(stored) != 1 {
+		t.Fatalf("find credentials: %d, %v", len(stored), err)
+	}
+	if stored[0].SignCount != authenticator.signCount || stored[0].LastUsedAt == nil {
+		t.Errorf("stored sign count %d last used %v, want %d and set", stored[0].SignCount, stored[0].LastUsedAt, authenticator.signCount)
+	}
+}
+
+func TestWebAuthnRegistrationRejected(t *testing.T) {
+	tests := []struct {
+		name     string
+		response func(response *authenticatorResponse)
+		expire   bool
+		wantErr  string
+	}{
+		{name: "wrong RP ID", response: func(r *authenticatorResponse) { r.rpID = "example.com" }, wantErr: "RP Hash mismatch"},
+		{name: "wrong origin", response: func(r *authenticatorResponse) { r.origin = "https://evil.example.com" }, wantErr: "origin"},
+		{name: "user not present", response: func(r *authenticatorResponse) { r.flags = flagUserVerified }, wantErr: "credential verification failed"},
+		{name: "expired ceremony", expire: true, wantErr: "ceremony has expired"},
+	}
+
+	env := newTestEnv(t)
+	webAuthnService := newTestWebAuthnService(t, env)
+	user := env.signup(t, "leo").User
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authenticator := newSoftAuthenticator(t)
+			options, err := webAuthnService.BeginRegistration(user.ID)
+			if err != nil {
+				t.Fatalf("begin registration: %v", err)
+			}
+			response := authenticator.defaultResponse()
+			if tt.response != nil {
+				tt.response(&response)
+			}
+			if tt.expire {
+				expireCeremony(t, env, options.CeremonyID)
+			}
+
+			_, err = webAuthnService.FinishRegistration(user.ID, 1, &WebAuthnRegisterRequest{
+				CeremonyID: options.CeremonyID,
+				Credential: authenticator.create(t, options, response),
+			}, testIP, testUserAgent)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("finish registration = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	// A ceremony cannot be replayed, nor completed for another user
+	authenticator := newSoftAuthenticator(t)
+	options, err := webAuthnService.BeginRegistration(user.ID)
+	if err != nil {
+		t.Fatalf("begin registration: %v", err)
+	}
+	credential := authenticator.create(t, options, authenticator.defaultResponse())
+	mia := env.signup(t, "mia").User
+	if _, err := webAuthnService.FinishRegistration(mia.ID, 1, &WebAuthnRegisterRequest{CeremonyID: options.CeremonyID, Credential: credential}, testIP, testUserAgent); err == nil {
+		t.Error("registration ceremony was completed by another user")
+	}
+	if _, err := webAuthnService.FinishRegistration(user.ID, 1, &WebAuthnRegisterRequest{CeremonyID: options.CeremonyID, Credential: credential}, testIP, testUserAgent); err == nil {
+		t.Error("registration ceremony was replayed")
+	}
+}
+
+func TestWebAuthnLoginRejected(t *testing.T) {
+	tests := []struct {
+		name     string
+		response func(response *authenticatorResponse)
+		expire   bool
+		wantErr  string
+	}{
+		{name: "wrong RP ID", response: func(r *authenticatorResponse) { r.rpID = "example.com" }, wantErr: "RP Hash mismatch"},
+		{name: "wrong origin", response: func(r *authenticatorResponse) { r.origin = "https://evil.example.com" }, wantErr: "origin"},
+		{name: "user not verified", response: func(r *authenticatorResponse) { r.flags = flagUserPresent }, wantErr: "User verification required"},
+		{name: "expired ceremony", expire: true, wantErr: "ceremony has expired"},
+	}
+
+	env := newTestEnv(t)
+	webAuthnService := newTestWebAuthnService(t, env)
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			username := "user" + strconv.Itoa(i)
+			user := env.signup(t, username).User
+			authenticator := newSoftAuthenticator(t)
+			register(t, webAuthnService, user, authenticator)
+
+			options, err := webAuthnService.BeginLogin(&WebAuthnLoginOptionsRequest{Username: username})
+			if err != nil {
+				t.Fatalf("begin login: %v", err)
+			}
+			response := authenticator.defaultResponse()
+			if tt.response != nil {
+				tt.response(&response)
+			}
+			if tt.expire {
+				expireCeremony(t, env, options.CeremonyID)
+			}
+
+			_, err = webAuthnService.FinishLogin(&WebAuthnLoginRequest{
+				CeremonyID: options.CeremonyID,
+				Credential: authenticator.get(t, options, response),
+			}, testIP, testUserAgent)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("finish login = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWebAuthnLoginReplay(t *testing.T) {
+	env := newTestEnv(t)
+	webAuthnService := newTestWebAuthnService(t, env)
+	user := env.signup(t, "leo").User
+	authenticator := newSoftAuthenticator(t)
+	register(t, webAuthnService, user, authenticator)
+
+	options, err := webAuthnService.BeginLogin(&WebAuthnLoginOptionsRequest{Username: "leo"})
+	if err != nil {
+		t.Fatalf("begin login: %v", err)
+	}
+	req := &WebAuthnLoginRequest{CeremonyID: options.CeremonyID, Credential: authenticator.get(t, options, authenticator.defaultResponse())}
+	if _, err := webAuthnService.FinishLogin(req, testIP, testUserAgent); err != nil {
+		t.Fatalf("finish login: %v", err)
+	}
+	if _, err := webAuthnService.FinishLogin(req, testIP, testUserAgent); err == nil {
+		t.Error("login ceremony was replayed")
+	}
+
+	// A captured assertion does not answer the challenge of another ceremony
+	options, err = webAuthnService.BeginLogin(&WebAuthnLoginOptionsRequest{Username: "leo"})
+	if err != nil {
+		t.Fatalf("begin login: %v", err)
+	}
+	req.CeremonyID = options.CeremonyID
+	if _, err := webAuthnService.FinishLogin(req, testIP, testUserAgent); err == nil {
+		t.Error("assertion was accepted for another ceremony")
+	}
+}
+
+func TestWebAuthnSignCountRegression(t *testing.T) {
+	env := newTestEnv(t)
+	webAuthnService := newTestWebAuthnService(t, env)
+	user := env.signup(t, "leo").User
+	authenticator := newSoftAuthenticator(t)
+	register(t, webAuthnService, user, authenticator)
+
+	login := func(signCount uint32) error {
+		options, err := webAuthnService.BeginLogin(&WebAuthnLoginOptionsRequest{Username: "leo"})
+		if err != nil {
+			t.Fatalf("begin login: %v", err)
+		}
+		response := authenticator.defaultResponse()
+		response.signCount = signCount
+		_, err = webAuthnService.FinishLogin(&WebAuthnLoginRequest{
+			CeremonyID: options.CeremonyID,
+			Credential: authenticator.get(t, options, response),
+		}, testIP, testUserAgent)
+		return err
+	}
+
+	if err := login(5); err != nil {
+		t.Fatalf("login with sign count 5: %v", err)
+	}
+
+	// A clone of the authenticator reports a counter that did not increase
+	for _, signCount := range []uint32{5, 3} {
+		if err := login(signCount); err == nil || !strings.Contains(err.Error(), "may be cloned") {
+			t.Fatalf("login with sign count %d = %v, want clone error", signCount, err)
+		}
+	}
+
+	// The credential stays flagged, the original authenticator cannot use it either
+	stored, err := repository.NewWebAuthnCredentialRepository(env.db).FindByUserID(user.ID)
+	if err != nil || len(stored) != 1 {
+		t.Fatalf("find credentials: %d, %v", len(stored), err)
+	}
+	if !stored[0].CloneWarning || stored[0].SignCount != 5 {
+		t.Errorf("stored credential clone warning %v sign count %d, want flagged at 5", stored[0].CloneWarning, stored[0].SignCount)
+	}
+	if err := login(6); err == nil {
+		t.Error("flagged credential was accepted")
+	}
+}
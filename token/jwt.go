@@ -0,0 +1,151 @@
+package token
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/damonleelcx/go-gin-api/config"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Token use claim values, so a token issued for one purpose cannot be used for another
+const (
+	UseAccess   = "access"
+	UseIdentity = "identity"
+)
+
+// Claims access token claims
+type Claims struct {
+	jwt.RegisteredClaims
+	Use       string `json:"token_use"` // Token use: access, identity
+	Role      string `json:"role"`      // User role
+	SessionID uint   `json:"sid"`       // Session ID
+}
+
+// IdentityClaims identity assertion claims, a signed form of the token validation result
+type IdentityClaims struct {
+	Claims
+	Username string `json:"username"` // Username
+	Email    string `json:"email"`    // Email
+	Status   string `json:"status"`   // User status
+}
+
+// UserID returns user ID from subject claim
+func (c *Claims) UserID() (uint, error) {
+	id, err := strconv.ParseUint(c.Subject, 10, 64)
+	if err != nil {
+		return 0, errors.New("invalid subject claim")
+	}
+	return uint(id), nil
+}
+
+// Signer signs and verifies JWTs with keys from key manager
+type Signer struct {
+	keys     *KeyManager
+	issuer   string
+	audience string
+}
+
+// NewSigner creates a new signer instance
+func NewSigner(keys *KeyManager, cfg config.JWTConfig) *Signer {
+	return &Signer{
+		keys:     keys,
+		issuer:   cfg.Issuer,
+		audience: cfg.Audience,
+	}
+}
+
+// Sign issues signed access token for session
+func (s *Signer) Sign(userID uint, role string, sessionID uint, tokenID string, expiresAt time.Time) (string, error) {
+	claims := &Claims{
+		RegisteredClaims: s.registeredClaims(userID, expiresAt),
+		Use:              UseAccess,
+		Role:             role,
+		SessionID:        sessionID,
+	}
+	claims.ID = tokenID
+	return s.sign(claims)
+}
+
+// SignIdentity issues signed identity assertion
+func (s *Signer) SignIdentity(claims *IdentityClaims, userID uint, expiresAt time.Time) (string, error) {
+	claims.RegisteredClaims = s.registeredClaims(userID, expiresAt)
+	claims.Use = UseIdentity
+	return s.sign(claims)
+}
+
+// Verify verifies signature, algorithm, issuer, audience and expiry of access token
+func (s *Signer) Verify(tokenString string) (*Claims, error) {
+	claims := &Claims{}
+	if err := s.parse(tokenString, claims); err != nil {
+		return nil, errors.New("invalid access token: " + err.Error())
+	}
+	if claims.Use != UseAccess {
+		return nil, errors.New("invalid access token: wrong token use")
+	}
+	if claims.SessionID == 0 || claims.ID == "" {
+		return nil, errors.New("invalid access token: missing session claims")
+	}
+
+	return claims, nil
+}
+
+// registeredClaims builds standard claims
+func (s *Signer) registeredClaims(userID uint, expiresAt time.Time) jwt.RegisteredClaims {
+	now := time.Now()
+	claims := jwt.RegisteredClaims{
+		Issuer:    s.issuer,
+		Subject:   strconv.FormatUint(uint64(userID), 10),
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+		IssuedAt:  jwt.NewNumericDate(now),
+		NotBefore: jwt.NewNumericDate(now),
+	}
+	if s.audience != "" {
+		claims.Audience = jwt.ClaimStrings{s.audience}
+	}
+	return claims
+}
+
+// sign signs claims with the active key and sets its key ID header
+func (s *Signer) sign(claims jwt.Claims) (string, error) {
+	key := s.keys.SigningKey()
+	token := jwt.NewWithClaims(signingMethod(key.Algorithm), claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.Private)
+}
+
+// parse verifies token against the key named by its key ID header
+func (s *Signer) parse(tokenString string, claims jwt.Claims) error {
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{s.keys.algorithm}),
+		jwt.WithIssuer(s.issuer),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(30 * time.Second),
+	}
+	if s.audience != "" {
+		options = append(options, jwt.WithAudience(s.audience))
+	}
+
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, err := s.keys.VerificationKey(kid)
+		if err != nil {
+			return nil, err
+		}
+		return key.Public, nil
+	}, options...)
+	return err
+}
+
+// signingMethod returns jwt signing method for algorithm
+func signingMethod(algorithm string) jwt.SigningMethod {
+	switch algorithm {
+	case "RS256":
+		return jwt.SigningMethodRS256
+	case "EdDSA":
+		return jwt.SigningMethodEdDSA
+	default:
+		return jwt.SigningMethodHS256
+	}
+}
@@ -0,0 +1,495 @@
+package token
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"log"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/damonleelcx/go-gin-api/config"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JWKSMaxAge how long clients may cache the JWKS
+const JWKSMaxAge = 5 * time.Minute
+
+// keyReloadInterval how often the key directory is reloaded and rotation is checked
+const keyReloadInterval = time.Minute
+
+// activationDelay time between creating a rotated key and signing with it. The key is published
+// in the JWKS right away, so verifiers caching the JWKS, and instances picking the key up from the
+// shared key directory, know it before the first token signed with it arrives.
+const activationDelay = JWKSMaxAge + keyReloadInterval
+
+// Key signing key
+type Key struct {
+	ID          string      // Key ID, sent as "kid" header
+	Algorithm   string      // Signing algorithm: HS256, RS256, EdDSA
+	Private     interface{} // Private key, or HMAC secret for HS256
+	Public      interface{} // Public key, or HMAC secret for HS256
+	CreatedAt   time.Time   // Created at
+	ActivatesAt time.Time   // Time from which the key signs, rotated keys are only published before
+	RetiresAt   *time.Time  // Nil until a successor exists, otherwise time after which the key is removed
+}
+
+// IsActive checks if key may sign at time now
+func (k *Key) IsActive(now time.Time) bool {
+	return !now.Before(k.ActivatesAt)
+}
+
+// JWK JSON web key (public part only)
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+	N         string `json:"n,omitempty"`   // RSA modulus
+	E         string `json:"e,omitempty"`   // RSA exponent
+	Curve     string `json:"crv,omitempty"` // OKP curve
+	X         string `json:"x,omitempty"`   // OKP public key
+}
+
+// JWKS JSON web key set
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// KeyManager holds the active signing key, the next key while it is published ahead of use, and
+// retiring keys that are still accepted for verification, and rotates keys on schedule. When a
+// key directory is configured keys are persisted there as PKCS#8 PEM files, so they survive
+// restarts and can be shared by instances.
+type KeyManager struct {
+	mu               sync.RWMutex
+	algorithm        string
+	keys             []*Key // Newest first, the newest active key signs
+	dir              string
+	rotationInterval time.Duration
+	retirementPeriod time.Duration
+	lastReload       time.Time
+}
+
+// NewKeyManager creates a new key manager from JWT configuration
+func NewKeyManager(cfg config.JWTConfig) (*KeyManager, error) {
+	m := &KeyManager{
+		algorithm:        cfg.Algorithm,
+		dir:              cfg.KeyDir,
+		rotationInterval: cfg.RotationInterval.Std(),
+		retirementPeriod: cfg.RetirementPeriod.Std(),
+	}
+
+	switch {
+	case cfg.Algorithm == "HS256":
+		secret := []byte(cfg.Secret)
+		sum := sha256.Sum256(secret)
+		now := time.Now()
+		m.keys = []*Key{{
+			ID:          hex.EncodeToString(sum[:8]),
+			Algorithm:   cfg.Algorithm,
+			Private:     secret,
+			Public:      secret,
+			CreatedAt:   now,
+			ActivatesAt: now,
+		}}
+	case cfg.KeyDir != "":
+		if err := os.MkdirAll(cfg.KeyDir, 0o700); err != nil {
+			return nil, errors.New("failed to create key directory: " + err.Error())
+		}
+		if err := m.reload(); err != nil {
+			return nil, err
+		}
+		if len(m.keys) == 0 {
+			if err := m.rotate(true); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		key, err := loadPrivateKeyFile(cfg.Algorithm, cfg.PrivateKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		key.ActivatesAt = key.CreatedAt
+		m.keys = []*Key{key}
+	}
+
+	return m, nil
+}
+
+// SigningKey returns the newest active key
+func (m *KeyManager) SigningKey() *Key {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	now := time.Now()
+	for _, key := range m.keys {
+		if key.IsActive(now) {
+			return key
+		}
+	}
+	return m.keys[len(m.keys)-1]
+}
+
+// VerificationKey returns active or retiring key by ID. Unknown key IDs trigger a reload of the
+// key directory, since another instance may have rotated in the meantime.
+func (m *KeyManager) VerificationKey(kid string) (*Key, error) {
+	if key := m.find(kid); key != nil {
+		return key, nil
+	}
+
+	if m.dir != "" {
+		m.mu.RLock()
+		recentlyReloaded := time.Since(m.lastReload) < 10*time.Second
+		m.mu.RUnlock()
+		if !recentlyReloaded {
+			if err := m.reload(); err != nil {
+				log.Printf("failed to reload signing keys: %v", err)
+			}
+			if key := m.find(kid); key != nil {
+				return key, nil
+			}
+		}
+	}
+
+	return nil, errors.New("unknown signing key: " + kid)
+}
+
+// find returns non-expired key by ID
+func (m *KeyManager) find(kid string) *Key {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	now := time.Now()
+	for _, key := range m.keys {
+		if key.ID == kid && (key.RetiresAt == nil || now.Before(*key.RetiresAt)) {
+			return key
+		}
+	}
+	return nil
+}
+
+// Rotate generates the next key. It is published right away and signs once verifiers had time to
+// fetch it; the previous key keeps signing until then and retires one retirement period later.
+func (m *KeyManager) Rotate() error {
+	return m.rotate(false)
+}
+
+// rotate generates a new key, the first key of a key directory signs immediately
+func (m *KeyManager) rotate(first bool) error {
+	if m.algorithm == "HS256" {
+		return errors.New("key rotation is not supported for HS256")
+	}
+
+	key, err := generateKey(m.algorithm)
+	if err != nil {
+		return errors.New("failed to generate signing key: " + err.Error())
+	}
+	if first {
+		key.ActivatesAt = key.CreatedAt
+	}
+
+	if m.dir != "" {
+		if err := writeKeyFile(m.dir, key); err != nil {
+			return err
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.keys) > 0 && m.keys[0].RetiresAt == nil {
+		retiresAt := key.ActivatesAt.Add(m.retirementPeriod)
+		m.keys[0].RetiresAt = &retiresAt
+	}
+	m.keys = append([]*Key{key}, m.keys...)
+	m.prune()
+
+	log.Printf("rotated signing key, new key ID %s signs from %s", key.ID, key.ActivatesAt.Format(time.RFC3339))
+	return nil
+}
+
+// Run rotates keys on schedule until context is cancelled
+func (m *KeyManager) Run(ctx context.Context) {
+	if m.rotationInterval <= 0 || m.algorithm == "HS256" {
+		return
+	}
+
+	ticker := time.NewTicker(keyReloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if m.dir != "" {
+				if err := m.reload(); err != nil {
+					log.Printf("failed to reload signing keys: %v", err)
+				}
+			}
+			if time.Since(m.newestKey().CreatedAt) >= m.rotationInterval {
+				if err := m.Rotate(); err != nil {
+					log.Printf("failed to rotate signing key: %v", err)
+				}
+			}
+			m.mu.Lock()
+			m.prune()
+			m.mu.Unlock()
+		}
+	}
+}
+
+// newestKey returns the most recently created key, which may not sign yet
+func (m *KeyManager) newestKey() *Key {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.keys[0]
+}
+
+// JWKS returns public keys of next, active and retiring asymmetric keys
+func (m *KeyManager) JWKS() JWKS {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	set := JWKS{Keys: []JWK{}}
+	now := time.Now()
+	for _, key := range m.keys {
+		if key.RetiresAt != nil && now.After(*key.RetiresAt) {
+			continue
+		}
+		if jwk, ok := publicJWK(key); ok {
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+	return set
+}
+
+// prune drops keys past their retirement time, caller must hold the write lock
+func (m *KeyManager) prune() {
+	now := time.Now()
+	kept := m.keys[:0]
+	for _, key := range m.keys {
+		if key.RetiresAt != nil && now.After(*key.RetiresAt) {
+			if m.dir != "" {
+				if err := os.Remove(keyFilePath(m.dir, key)); err != nil && !os.IsNotExist(err) {
+					log.Printf("failed to remove retired key %s: %v", key.ID, err)
+				}
+			}
+			continue
+		}
+		kept = append(kept, key)
+	}
+	m.keys = kept
+}
+
+// reload reads keys from key directory. Keys sign from one activation delay after their creation,
+// the oldest key from its creation, and every older key retires one retirement period after its
+// successor starts signing.
+func (m *KeyManager) reload() error {
+	entries, err := os.ReadDir(m.dir)
+	if err != nil {
+		return errors.New("failed to read key directory: " + err.Error())
+	}
+
+	var keys []*Key
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".pem") {
+			continue
+		}
+		key, err := readKeyFile(m.algorithm, filepath.Join(m.dir, entry.Name()))
+		if err != nil {
+			log.Printf("skipping key file %s: %v", entry.Name(), err)
+			continue
+		}
+		keys = append(keys, key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.After(keys[j].CreatedAt)
+	})
+	for i := 1; i < len(keys); i++ {
+		retiresAt := keys[i-1].ActivatesAt.Add(m.retirementPeriod)
+		keys[i].RetiresAt = &retiresAt
+	}
+	if len(keys) > 0 {
+		keys[len(keys)-1].ActivatesAt = keys[len(keys)-1].CreatedAt
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lastReload = time.Now()
+	if len(keys) > 0 {
+		m.keys = keys
+		m.prune()
+	}
+	return nil
+}
+
+// generateKey generates new key for algorithm
+func generateKey(algorithm string) (*Key, error) {
+	var private crypto.Signer
+	var err error
+	switch algorithm {
+	case "RS256":
+		private, err = rsa.GenerateKey(rand.Reader, 2048)
+	case "EdDSA":
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, errors.New("unsupported signing algorithm: " + algorithm)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newKey(algorithm, private, time.Now())
+}
+
+// newKey wraps private key, key ID is the RFC 7638 thumbprint of the public key
+func newKey(algorithm string, private crypto.Signer, createdAt time.Time) (*Key, error) {
+	key := &Key{
+		Algorithm:   algorithm,
+		Private:     private,
+		Public:      private.Public(),
+		CreatedAt:   createdAt,
+		ActivatesAt: createdAt.Add(activationDelay),
+	}
+	jwk, ok := publicJWK(key)
+	if !ok {
+		return nil, errors.New("private key does not match algorithm " + algorithm)
+	}
+	key.ID = thumbprint(jwk)
+	return key, nil
+}
+
+// loadPrivateKeyFile reads PEM private key (PKCS#1 or PKCS#8) from file
+func loadPrivateKeyFile(algorithm, path string) (*Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.New("failed to read private key: " + err.Error())
+	}
+
+	var private crypto.Signer
+	switch algorithm {
+	case "RS256":
+		private, err = jwt.ParseRSAPrivateKeyFromPEM(data)
+	case "EdDSA":
+		var parsed crypto.PrivateKey
+		parsed, err = jwt.ParseEdPrivateKeyFromPEM(data)
+		if err == nil {
+			private = parsed.(crypto.Signer)
+		}
+	default:
+		return nil, errors.New("unsupported signing algorithm: " + algorithm)
+	}
+	if err != nil {
+		return nil, errors.New("failed to parse private key: " + err.Error())
+	}
+
+	return newKey(algorithm, private, time.Now())
+}
+
+// keyFilePath returns file path of key in key directory, named by creation time and key ID
+func keyFilePath(dir string, key *Key) string {
+	return filepath.Join(dir, strconv.FormatInt(key.CreatedAt.Unix(), 10)+"-"+key.ID+".pem")
+}
+
+// writeKeyFile writes private key as PKCS#8 PEM file
+func writeKeyFile(dir string, key *Key) error {
+	der, err := x509.MarshalPKCS8PrivateKey(key.Private)
+	if err != nil {
+		return errors.New("failed to encode signing key: " + err.Error())
+	}
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err := os.WriteFile(keyFilePath(dir, key), data, 0o600); err != nil {
+		return errors.New("failed to write signing key: " + err.Error())
+	}
+	return nil
+}
+
+// readKeyFile reads key written by writeKeyFile
+func readKeyFile(algorithm, path string) (*Key, error) {
+	name := filepath.Base(path)
+	createdUnix, err := strconv.ParseInt(strings.SplitN(name, "-", 2)[0], 10, 64)
+	if err != nil {
+		return nil, errors.New("invalid key file name")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("invalid PEM data")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	private, ok := parsed.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("unsupported private key type")
+	}
+
+	return newKey(algorithm, private, time.Unix(createdUnix, 0))
+}
+
+// publicJWK converts public key to JWK, returns false for symmetric keys or algorithm mismatch
+func publicJWK(key *Key) (JWK, bool) {
+	jwk := JWK{
+		Use:       "sig",
+		Algorithm: key.Algorithm,
+		KeyID:     key.ID,
+	}
+	switch public := key.Public.(type) {
+	case *rsa.PublicKey:
+		if key.Algorithm != "RS256" {
+			return JWK{}, false
+		}
+		jwk.KeyType = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+	case ed25519.PublicKey:
+		if key.Algorithm != "EdDSA" {
+			return JWK{}, false
+		}
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(public)
+	default:
+		return JWK{}, false
+	}
+	return jwk, true
+}
+
+// thumbprint computes RFC 7638 JWK thumbprint
+func thumbprint(jwk JWK) string {
+	var members interface{}
+	if jwk.KeyType == "RSA" {
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.KeyType, jwk.N}
+	} else {
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Curve, jwk.KeyType, jwk.X}
+	}
+	data, _ := json.Marshal(members)
+	sum := sha256.Sum256(data)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
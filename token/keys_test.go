@@ -0,0 +1,113 @@
+package token
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/damonleelcx/go-gin-api/config"
+)
+
+// newTestKeyManager creates key manager on a key directory holding one key created an hour ago
+func newTestKeyManager(t *testing.T) (*KeyManager, config.JWTConfig) {
+	t.Helper()
+
+	cfg := config.Default().Auth.JWT
+	cfg.Algorithm = "EdDSA"
+	cfg.KeyDir = t.TempDir()
+	if _, err := NewKeyManager(cfg); err != nil {
+		t.Fatalf("create key manager: %v", err)
+	}
+
+	// Backdate the generated key, key files are named by creation time
+	entries, err := os.ReadDir(cfg.KeyDir)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("key directory holds %d files, error %v", len(entries), err)
+	}
+	name := entries[0].Name()
+	created := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+	backdated := created + name[strings.Index(name, "-"):]
+	if err := os.Rename(filepath.Join(cfg.KeyDir, name), filepath.Join(cfg.KeyDir, backdated)); err != nil {
+		t.Fatalf("backdate key: %v", err)
+	}
+
+	keys, err := NewKeyManager(cfg)
+	if err != nil {
+		t.Fatalf("load key manager: %v", err)
+	}
+	return keys, cfg
+}
+
+// published returns key IDs in the JWKS
+func published(keys *KeyManager) map[string]bool {
+	ids := map[string]bool{}
+	for _, jwk := range keys.JWKS().Keys {
+		ids[jwk.KeyID] = true
+	}
+	return ids
+}
+
+func TestRotatePublishesNextKeyFirst(t *testing.T) {
+	keys, cfg := newTestKeyManager(t)
+	signer := NewSigner(keys, cfg)
+	previous := keys.SigningKey()
+
+	if err := keys.Rotate(); err != nil {
+		t.Fatalf("rotate: %v", err)
+	}
+	next := keys.newestKey()
+	if next == previous {
+		t.Fatal("rotate did not add a key")
+	}
+
+	// The next key is published at least one JWKS cache lifetime before it signs
+	if !published(keys)[next.ID] || !published(keys)[previous.ID] {
+		t.Fatalf("JWKS %v, want next key %s and previous key %s", published(keys), next.ID, previous.ID)
+	}
+	if keys.SigningKey() != previous {
+		t.Fatalf("signing key %s, want previous key %s until the next key activates", keys.SigningKey().ID, previous.ID)
+	}
+	if delay := next.ActivatesAt.Sub(next.CreatedAt); delay < JWKSMaxAge {
+		t.Errorf("next key activates %v after creation, want at least %v", delay, JWKSMaxAge)
+	}
+	token, err := signer.Sign(1, "user", 1, "jti", time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatalf("sign with previous key: %v", err)
+	}
+
+	// The previous key stays published for the retirement period after it stopped signing
+	if previous.RetiresAt == nil || !previous.RetiresAt.Equal(next.ActivatesAt.Add(cfg.RetirementPeriod.Std())) {
+		t.Errorf("previous key retires at %v, want %v", previous.RetiresAt, next.ActivatesAt.Add(cfg.RetirementPeriod.Std()))
+	}
+
+	// Instances sharing the key directory follow the same schedule
+	other, err := NewKeyManager(cfg)
+	if err != nil {
+		t.Fatalf("load rotated keys: %v", err)
+	}
+	if other.SigningKey().ID != previous.ID || !published(other)[next.ID] {
+		t.Errorf("other instance signs with %s and publishes %v, want %s signing and %s published", other.SigningKey().ID, published(other), previous.ID, next.ID)
+	}
+
+	// Once active the next key signs, tokens of the previous key still verify
+	next.ActivatesAt = time.Now().Add(-time.Second)
+	if keys.SigningKey() != next {
+		t.Fatalf("signing key %s, want next key %s once active", keys.SigningKey().ID, next.ID)
+	}
+	if _, err := signer.Verify(token); err != nil {
+		t.Errorf("verify token of previous key: %v", err)
+	}
+
+	// Retired keys are neither published nor accepted
+	retired := time.Now().Add(-time.Second)
+	previous.RetiresAt = &retired
+	if published(keys)[previous.ID] {
+		t.Error("retired key is still published")
+	}
+	if _, err := signer.Verify(token); err == nil {
+		t.Error("token of retired key still verifies")
+	}
+}
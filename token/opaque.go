@@ -0,0 +1,25 @@
+package token
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// Prefixes of opaque tokens, so leaked tokens can be recognized by secret scanners
+const (
+	SessionPrefix      = "ggs_"  // Session access token
+	ResetPrefix        = "ggr_"  // Password reset token
+	RefreshPrefix      = "ggrt_" // Refresh token
+	VerificationPrefix = "ggv_"  // Email verification token
+	LoginLinkPrefix    = "ggl_"  // Passwordless login link token
+	MFAPrefix          = "ggm_"  // MFA challenge token returned by signin
+	CeremonyPrefix     = "ggw_"  // WebAuthn ceremony ID
+	OAuthStatePrefix   = "ggo_"  // OAuth state parameter
+)
+
+// Hash returns hex encoded SHA-256 digest of opaque token, the form in which it is stored.
+// Opaque tokens are 256-bit random values, so an unsalted fast hash is sufficient.
+func Hash(opaque string) string {
+	sum := sha256.Sum256([]byte(opaque))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,113 @@
+package totp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret base32 of the SHA-1 seed of the RFC 6238 test vectors, "12345678901234567890"
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCodeRFC6238(t *testing.T) {
+	// RFC 6238 Appendix B lists 8 digit codes, 6 digit codes are their last 6 digits
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		at := time.Unix(tt.unix, 0)
+		got, err := Code(rfcSecret, Counter(at))
+		if err != nil {
+			t.Fatalf("Code(%d) error: %v", tt.unix, err)
+		}
+		if got != tt.want {
+			t.Errorf("Code at %d = %s, want %s", tt.unix, got, tt.want)
+		}
+
+		counter, ok := Validate(rfcSecret, tt.want, at, 0)
+		if !ok || counter != Counter(at) {
+			t.Errorf("Validate(%s) at %d = %d, %v, want counter %d", tt.want, tt.unix, counter, ok, Counter(at))
+		}
+	}
+}
+
+func TestCodeSecretFormats(t *testing.T) {
+	// Secrets are accepted in lower case and with base32 padding
+	for _, secret := range []string{strings.ToLower(rfcSecret), rfcSecret + "===="} {
+		if got, err := Code(secret, Counter(time.Unix(59, 0))); err != nil || got != "287082" {
+			t.Errorf("Code(%q) = %s, %v, want 287082", secret, got, err)
+		}
+	}
+	if _, err := Code("not base32!", 1); err == nil {
+		t.Error("Code accepted invalid secret")
+	}
+}
+
+func TestValidateSkew(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := Counter(now)
+
+	tests := []struct {
+		name   string
+		offset int64 // Time step of the code relative to now
+		skew   int
+		want   bool
+	}{
+		{name: "current step without skew", offset: 0, skew: 0, want: true},
+		{name: "previous step without skew", offset: -1, skew: 0, want: false},
+		{name: "next step without skew", offset: 1, skew: 0, want: false},
+		{name: "previous step", offset: -1, skew: 1, want: true},
+		{name: "next step", offset: 1, skew: 1, want: true},
+		{name: "two steps ago", offset: -2, skew: 1, want: false},
+		{name: "two steps ahead", offset: 2, skew: 1, want: false},
+		{name: "two steps ago with skew 2", offset: -2, skew: 2, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, err := Code(rfcSecret, current+tt.offset)
+			if err != nil {
+				t.Fatalf("Code() error: %v", err)
+			}
+			counter, ok := Validate(rfcSecret, code, now, tt.skew)
+			if ok != tt.want {
+				t.Fatalf("Validate() = %v, want %v", ok, tt.want)
+			}
+			// The matched step is returned, so a code of a later step is not replayable at an earlier one
+			if ok && counter != current+tt.offset {
+				t.Errorf("Validate() counter = %d, want %d", counter, current+tt.offset)
+			}
+		})
+	}
+}
+
+func TestValidateInput(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+
+	tests := []struct {
+		code string
+		want bool
+	}{
+		{"050471", true},
+		{" 050 471 ", true},
+		{"50471", false},
+		{"0504710", false},
+		{"050472", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if _, ok := Validate(rfcSecret, tt.code, now, 0); ok != tt.want {
+			t.Errorf("Validate(%q) = %v, want %v", tt.code, ok, tt.want)
+		}
+	}
+	if _, ok := Validate("not base32!", "050471", now, 1); ok {
+		t.Error("Validate accepted code for invalid secret")
+	}
+}
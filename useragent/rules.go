@@ -0,0 +1,75 @@
+package useragent
+
+import "regexp"
+
+// Device types
+const (
+	DeviceWeb    = "web"
+	DeviceMobile = "mobile"
+	DeviceTablet = "tablet"
+	DeviceBot    = "bot"
+	DeviceOther  = "other"
+)
+
+// Platforms
+const (
+	PlatformWindows  = "windows"
+	PlatformMacOS    = "macos"
+	PlatformLinux    = "linux"
+	PlatformIOS      = "ios"
+	PlatformAndroid  = "android"
+	PlatformChromeOS = "chromeos"
+)
+
+// rule maps user agents matching pattern to value, rules are tried in order and the first match wins
+type rule struct {
+	pattern *regexp.Regexp
+	value   string
+}
+
+// browserRule extracts browser name, the first submatch of pattern is the version
+type browserRule struct {
+	pattern *regexp.Regexp
+	name    string
+}
+
+// platformRules detect operating system. Android and ChromeOS user agents also mention Linux,
+// iOS ones mention Mac OS X, so they are checked first.
+var platformRules = []rule{
+	{regexp.MustCompile(`iPhone|iPad|iPod`), PlatformIOS},
+	{regexp.MustCompile(`Android`), PlatformAndroid},
+	{regexp.MustCompile(`CrOS`), PlatformChromeOS},
+	{regexp.MustCompile(`Windows`), PlatformWindows},
+	{regexp.MustCompile(`Macintosh|Mac OS X|Darwin`), PlatformMacOS},
+	{regexp.MustCompile(`Linux|X11|Ubuntu|Fedora`), PlatformLinux},
+}
+
+// deviceRules detect device type, user agents matching none are web browsers if they
+// start with the Mozilla token and other clients otherwise
+var deviceRules = []rule{
+	{regexp.MustCompile(`(?i)bot\b|crawler|spider|slurp|facebookexternalhit`), DeviceBot},
+	{regexp.MustCompile(`iPad|Tablet|Kindle|Silk/|PlayBook`), DeviceTablet},
+	{regexp.MustCompile(`Mobi|iPhone|iPod|Windows Phone|Android.*Mobile|BlackBerry`), DeviceMobile},
+	// Android without "Mobile" is a tablet by convention of the Android browser user agent
+	{regexp.MustCompile(`Android`), DeviceTablet},
+}
+
+// browserRules detect browser and version. Most browsers also claim to be Chrome and Safari,
+// so specific tokens come before generic ones.
+var browserRules = []browserRule{
+	{regexp.MustCompile(`Edg(?:e|A|iOS)?/([\d.]+)`), "Edge"},
+	{regexp.MustCompile(`(?:OPR|Opera)/([\d.]+)`), "Opera"},
+	{regexp.MustCompile(`SamsungBrowser/([\d.]+)`), "Samsung Internet"},
+	{regexp.MustCompile(`YaBrowser/([\d.]+)`), "Yandex Browser"},
+	{regexp.MustCompile(`Vivaldi/([\d.]+)`), "Vivaldi"},
+	{regexp.MustCompile(`(?:Firefox|FxiOS)/([\d.]+)`), "Firefox"},
+	{regexp.MustCompile(`(?:Chrome|CriOS)/([\d.]+)`), "Chrome"},
+	{regexp.MustCompile(`Version/([\d.]+).*Safari/`), "Safari"},
+	{regexp.MustCompile(`MSIE ([\d.]+)|Trident/.*rv:([\d.]+)`), "Internet Explorer"},
+	{regexp.MustCompile(`curl/([\d.]+)`), "curl"},
+	{regexp.MustCompile(`Wget/([\d.]+)`), "Wget"},
+	{regexp.MustCompile(`PostmanRuntime/([\d.]+)`), "Postman"},
+	{regexp.MustCompile(`okhttp/([\d.]+)`), "OkHttp"},
+	{regexp.MustCompile(`python-requests/([\d.]+)`), "Python Requests"},
+	{regexp.MustCompile(`Go-http-client/([\d.]+)`), "Go HTTP client"},
+}
@@ -0,0 +1,100 @@
+package useragent
+
+import (
+	"strings"
+)
+
+// maxLength longer user agents are truncated before matching
+const maxLength = 512
+
+// maxVersionLength longer versions are truncated, sessions store them in varchar(20)
+const maxVersionLength = 20
+
+// Info device information parsed from a user agent
+type Info struct {
+	Device         string // web, mobile, tablet, bot, other; empty if the user agent is empty
+	Platform       string // windows, macos, linux, ios, android, chromeos; empty if unknown
+	Browser        string // Browser or client name, empty if unknown
+	BrowserVersion string // Major and minor version of the browser
+}
+
+// Parse parses user agent header
+func Parse(userAgent string) Info {
+	userAgent = strings.TrimSpace(userAgent)
+	if userAgent == "" {
+		return Info{}
+	}
+	if len(userAgent) > maxLength {
+		userAgent = userAgent[:maxLength]
+	}
+
+	info := Info{
+		Platform: match(platformRules, userAgent),
+		Device:   match(deviceRules, userAgent),
+	}
+	info.Browser, info.BrowserVersion = matchBrowser(userAgent)
+
+	if info.Device == "" {
+		if strings.HasPrefix(userAgent, "Mozilla/") {
+			info.Device = DeviceWeb
+		} else {
+			info.Device = DeviceOther
+		}
+	}
+	return info
+}
+
+// String returns human-readable description, e.g. "Chrome 120.0 on windows"
+func (i Info) String() string {
+	description := i.Browser
+	if description != "" && i.BrowserVersion != "" {
+		description += " " + i.BrowserVersion
+	}
+	if i.Platform != "" {
+		if description == "" {
+			return i.Platform
+		}
+		description += " on " + i.Platform
+	}
+	return description
+}
+
+// match returns value of the first rule matching user agent
+func match(rules []rule, userAgent string) string {
+	for _, r := range rules {
+		if r.pattern.MatchString(userAgent) {
+			return r.value
+		}
+	}
+	return ""
+}
+
+// matchBrowser returns name and version of the first browser rule matching user agent
+func matchBrowser(userAgent string) (string, string) {
+	for _, r := range browserRules {
+		submatches := r.pattern.FindStringSubmatch(userAgent)
+		if submatches == nil {
+			continue
+		}
+		for _, version := range submatches[1:] {
+			if version != "" {
+				return r.name, shortVersion(version)
+			}
+		}
+		return r.name, ""
+	}
+	return "", ""
+}
+
+// shortVersion keeps major and minor version, at most maxVersionLength characters
+func shortVersion(version string) string {
+	parts := strings.SplitN(strings.Trim(version, "."), ".", 3)
+	if len(parts) > 2 {
+		parts = parts[:2]
+	}
+	version = strings.Join(parts, ".")
+	if len(version) > maxVersionLength {
+		version = strings.TrimRight(version[:maxVersionLength], ".")
+	}
+	return version
+}
@@ -0,0 +1,165 @@
+package useragent
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name      string
+		userAgent string
+		want      Info
+	}{
+		{
+			name:      "Chrome on Windows",
+			userAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.6478.127 Safari/537.36",
+			want:      Info{Device: DeviceWeb, Platform: PlatformWindows, Browser: "Chrome", BrowserVersion: "126.0"},
+		},
+		{
+			name:      "Edge over Chrome",
+			userAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Safari/537.36 Edg/126.0.2592.87",
+			want:      Info{Device: DeviceWeb, Platform: PlatformWindows, Browser: "Edge", BrowserVersion: "126.0"},
+		},
+		{
+			name:      "Edge on Android over Chrome",
+			userAgent: "Mozilla/5.0 (Linux; Android 10; K) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Mobile Safari/537.36 EdgA/126.0.2592.80",
+			want:      Info{Device: DeviceMobile, Platform: PlatformAndroid, Browser: "Edge", BrowserVersion: "126.0"},
+		},
+		{
+			name:      "Opera over Chrome",
+			userAgent: "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/125.0.0.0 Safari/537.36 OPR/111.0.0.0",
+			want:      Info{Device: DeviceWeb, Platform: PlatformMacOS, Browser: "Opera", BrowserVersion: "111.0"},
+		},
+		{
+			name:      "Samsung Internet over Chrome",
+			userAgent: "Mozilla/5.0 (Linux; Android 14; SM-S918B) AppleWebKit/537.36 (KHTML, like Gecko) SamsungBrowser/25.0 Chrome/121.0.0.0 Mobile Safari/537.36",
+			want:      Info{Device: DeviceMobile, Platform: PlatformAndroid, Browser: "Samsung Internet", BrowserVersion: "25.0"},
+		},
+		{
+			name:      "Firefox on Linux",
+			userAgent: "Mozilla/5.0 (X11; Ubuntu; Linux x86_64; rv:127.0) Gecko/20100101 Firefox/127.0",
+			want:      Info{Device: DeviceWeb, Platform: PlatformLinux, Browser: "Firefox", BrowserVersion: "127.0"},
+		},
+		{
+			name:      "Safari on macOS",
+			userAgent: "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Safari/605.1.15",
+			want:      Info{Device: DeviceWeb, Platform: PlatformMacOS, Browser: "Safari", BrowserVersion: "17.5"},
+		},
+		{
+			name:      "Safari on iPhone",
+			userAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Mobile/15E148 Safari/604.1",
+			want:      Info{Device: DeviceMobile, Platform: PlatformIOS, Browser: "Safari", BrowserVersion: "17.5"},
+		},
+		{
+			name:      "Chrome on iPhone",
+			userAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) CriOS/126.0.6478.54 Mobile/15E148 Safari/604.1",
+			want:      Info{Device: DeviceMobile, Platform: PlatformIOS, Browser: "Chrome", BrowserVersion: "126.0"},
+		},
+		{
+			name:      "Firefox on iPad",
+			userAgent: "Mozilla/5.0 (iPad; CPU OS 17_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) FxiOS/127.0 Mobile/15E148 Safari/605.1.15",
+			want:      Info{Device: DeviceTablet, Platform: PlatformIOS, Browser: "Firefox", BrowserVersion: "127.0"},
+		},
+		{
+			name:      "Android phone",
+			userAgent: "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.6478.71 Mobile Safari/537.36",
+			want:      Info{Device: DeviceMobile, Platform: PlatformAndroid, Browser: "Chrome", BrowserVersion: "126.0"},
+		},
+		{
+			name:      "Android tablet",
+			userAgent: "Mozilla/5.0 (Linux; Android 13; SM-X710) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.6478.71 Safari/537.36",
+			want:      Info{Device: DeviceTablet, Platform: PlatformAndroid, Browser: "Chrome", BrowserVersion: "126.0"},
+		},
+		{
+			name:      "Chrome on ChromeOS",
+			userAgent: "Mozilla/5.0 (X11; CrOS x86_64 14541.0.0) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Safari/537.36",
+			want:      Info{Device: DeviceWeb, Platform: PlatformChromeOS, Browser: "Chrome", BrowserVersion: "126.0"},
+		},
+		{
+			name:      "IE11 via Trident and rv",
+			userAgent: "Mozilla/5.0 (Windows NT 10.0; WOW64; Trident/7.0; rv:11.0) like Gecko",
+			want:      Info{Device: DeviceWeb, Platform: PlatformWindows, Browser: "Internet Explorer", BrowserVersion: "11.0"},
+		},
+		{
+			name:      "IE10 via MSIE",
+			userAgent: "Mozilla/5.0 (compatible; MSIE 10.0; Windows NT 6.2; Trident/6.0)",
+			want:      Info{Device: DeviceWeb, Platform: PlatformWindows, Browser: "Internet Explorer", BrowserVersion: "10.0"},
+		},
+		{
+			name:      "Googlebot",
+			userAgent: "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+			want:      Info{Device: DeviceBot},
+		},
+		{
+			name:      "Googlebot smartphone",
+			userAgent: "Mozilla/5.0 (Linux; Android 6.0.1; Nexus 5X Build/MMB29P) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.6478.126 Mobile Safari/537.36 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+			want:      Info{Device: DeviceBot, Platform: PlatformAndroid, Browser: "Chrome", BrowserVersion: "126.0"},
+		},
+		{
+			name:      "Bingbot",
+			userAgent: "Mozilla/5.0 (compatible; bingbot/2.0; +http://www.bing.com/bingbot.htm)",
+			want:      Info{Device: DeviceBot},
+		},
+		{
+			name:      "curl",
+			userAgent: "curl/8.5.0",
+			want:      Info{Device: DeviceOther, Browser: "curl", BrowserVersion: "8.5"},
+		},
+		{
+			name:      "Go HTTP client",
+			userAgent: "Go-http-client/1.1",
+			want:      Info{Device: DeviceOther, Browser: "Go HTTP client", BrowserVersion: "1.1"},
+		},
+		{
+			name:      "unknown client",
+			userAgent: "custom-client",
+			want:      Info{Device: DeviceOther},
+		},
+		{
+			name:      "empty",
+			userAgent: "",
+			want:      Info{},
+		},
+		{
+			name:      "whitespace",
+			userAgent: "  \t ",
+			want:      Info{},
+		},
+		{
+			name:      "version longer than the session column",
+			userAgent: "Mozilla/5.0 (Windows NT 10.0) Chrome/" + strings.Repeat("9", 30) + ".0",
+			want:      Info{Device: DeviceWeb, Platform: PlatformWindows, Browser: "Chrome", BrowserVersion: strings.Repeat("9", maxVersionLength)},
+		},
+		{
+			name:      "version cut at a separator",
+			userAgent: "Mozilla/5.0 (Windows NT 10.0) Chrome/" + strings.Repeat("9", maxVersionLength-1) + ".123",
+			want:      Info{Device: DeviceWeb, Platform: PlatformWindows, Browser: "Chrome", BrowserVersion: strings.Repeat("9", maxVersionLength-1)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Parse(tt.userAgent); got != tt.want {
+				t.Errorf("Parse() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInfoString(t *testing.T) {
+	tests := []struct {
+		info Info
+		want string
+	}{
+		{Info{Browser: "Chrome", BrowserVersion: "126.0", Platform: PlatformWindows}, "Chrome 126.0 on windows"},
+		{Info{Browser: "Chrome", Platform: PlatformWindows}, "Chrome on windows"},
+		{Info{Browser: "curl", BrowserVersion: "8.5"}, "curl 8.5"},
+		{Info{Platform: PlatformLinux}, "linux"},
+		{Info{}, ""},
+	}
+	for _, tt := range tests {
+		if got := tt.info.String(); got != tt.want {
+			t.Errorf("%+v.String() = %q, want %q", tt.info, got, tt.want)
+		}
+	}
+}